@@ -0,0 +1,485 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/di"
+)
+
+// commands are maintenance tasks which run instead of the service, e.g. `service_files migrate-blobs -dry-run`
+var commands = map[string]func(args []string) error{
+	"migrate-blobs":   migrateBlobs,
+	"rotate-keys":     rotateKeys,
+	"migrate-storage": migrateStorage,
+	"residency":       checkResidency,
+	"fingerprint":     fingerprint,
+}
+
+// runCommand runs the command and exits if it fails
+func runCommand(name string, args []string) {
+	command, ok := commands[name]
+	if !ok {
+		log.Fatalf("Unknown command: %s", name)
+	}
+	if err := command(args); err != nil {
+		log.Fatalf("Command %s failed: %s", name, err)
+	}
+}
+
+// migrateBlobs converts files stored before blobs were introduced into content-addressed blobs.
+// Converted files are skipped, so the command may be repeated after a failure.
+func migrateBlobs(args []string) error {
+	flags := flag.NewFlagSet("migrate-blobs", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only count files which would be converted")
+	batch := flags.Int("batch", 100, "number of files loaded at once")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	c := di.Container
+	var lastID uint64
+	converted, failed := 0, 0
+	for {
+		files, err := c.Repository().FindWithoutBlob(lastID, *batch)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			break
+		}
+
+		for _, file := range files {
+			lastID = file.ID
+			if *dryRun {
+				converted++
+				continue
+			}
+			if err := c.StorageService().ConvertToBlob(file); err != nil {
+				log.Printf("Can't convert file %d: %s", file.ID, err)
+				failed++
+				continue
+			}
+			converted++
+		}
+	}
+
+	if *dryRun {
+		log.Printf("%d files would be converted", converted)
+		return nil
+	}
+	log.Printf("%d files converted, %d failed", converted, failed)
+	return nil
+}
+
+// fingerprint hashes content of files which were not fingerprinted yet for duplicate detection.
+// Files which fail are left without fingerprint, so the command may be repeated.
+func fingerprint(args []string) error {
+	flags := flag.NewFlagSet("fingerprint", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only count files which would be fingerprinted")
+	batch := flags.Int("batch", 100, "number of files loaded at once")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	c := di.Container
+	var lastID uint64
+	fingerprinted, failed := 0, 0
+	for {
+		files, err := c.Repository().FindWithoutFingerprint(lastID, *batch)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			break
+		}
+
+		for _, file := range files {
+			lastID = file.ID
+			if *dryRun {
+				fingerprinted++
+				continue
+			}
+			if err := c.DuplicateService().Fingerprint(file); err != nil {
+				log.Printf("Can't fingerprint file %d: %s", file.ID, err)
+				failed++
+				continue
+			}
+			fingerprinted++
+		}
+	}
+
+	if *dryRun {
+		log.Printf("%d files would be fingerprinted", fingerprinted)
+		return nil
+	}
+	log.Printf("%d files fingerprinted, %d failed", fingerprinted, failed)
+	return nil
+}
+
+// rotateKeys wraps keys of users with the current master key and moves data keys of files encrypted
+// before user keys were introduced to keys of their owners, the content is not re-encrypted.
+// Rewrapped keys are skipped, so the command resumes where it stopped. Deprecated master keys must stay
+// in the key file and only decrypt until no keys use them, which is shown by the report.
+func rotateKeys(args []string) error {
+	flags := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	report := flags.Bool("report", false, "only list keys which use deprecated master keys")
+	batch := flags.Int("batch", 100, "number of keys loaded at once")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	keys := di.Container.KeyProvider()
+	if keys == nil {
+		return errors.New("encryption is not configured")
+	}
+	current := keys.CurrentKeyId()
+
+	if err := rewrapUserKeys(current, *batch, *report); err != nil {
+		return err
+	}
+	if err := rewrapFileKeys(*batch, *report); err != nil {
+		return err
+	}
+	return reportKeyUsage(current)
+}
+
+func rewrapUserKeys(current string, batch int, report bool) error {
+	c := di.Container
+	var lastID uint64
+	rotated, failed := 0, 0
+	for {
+		keys, err := c.UserKeyRepository().FindWithDeprecatedKey(current, lastID, batch)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		for _, key := range keys {
+			lastID = key.ID
+			if report {
+				log.Printf("Key of user %s uses deprecated master key %s", key.UserId, *key.KeyId)
+				continue
+			}
+			if err := c.KeyService().RewrapUserKey(key); err != nil {
+				log.Printf("Can't rewrap key of user %s: %s", key.UserId, err)
+				failed++
+				continue
+			}
+			rotated++
+		}
+		if !report {
+			log.Printf("%d user keys rewrapped, %d failed, last key %d", rotated, failed, lastID)
+		}
+	}
+}
+
+func rewrapFileKeys(batch int, report bool) error {
+	c := di.Container
+	var lastID uint64
+	rotated, failed := 0, 0
+	for {
+		files, err := c.Repository().FindWithoutUserKey(lastID, batch)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return nil
+		}
+
+		for _, file := range files {
+			lastID = file.ID
+			if report {
+				log.Printf("File %d of user %s uses master key %s directly", file.ID, file.UserId, *file.KeyId)
+				continue
+			}
+			err := c.StorageService().RewrapKey(file)
+			if err == database.ErrKeyShredded {
+				log.Printf("Can't rewrap key of file %d, key of user %s is shredded", file.ID, file.UserId)
+				failed++
+				continue
+			}
+			if err != nil {
+				log.Printf("Can't rewrap key of file %d: %s", file.ID, err)
+				failed++
+				continue
+			}
+			rotated++
+		}
+		if !report {
+			log.Printf("%d file keys rewrapped, %d failed, last file %d", rotated, failed, lastID)
+		}
+	}
+}
+
+func reportKeyUsage(current string) error {
+	c := di.Container
+	userKeys, err := c.UserKeyRepository().CountByKeyId()
+	if err != nil {
+		return err
+	}
+	fileKeys, err := c.Repository().CountByKeyId()
+	if err != nil {
+		return err
+	}
+
+	deprecated := int64(0)
+	for _, u := range userKeys {
+		if u.KeyId == current {
+			log.Printf("Current master key %s: %d user keys", u.KeyId, u.Total)
+			continue
+		}
+		deprecated += u.Total
+		log.Printf("Deprecated master key %s: %d user keys", u.KeyId, u.Total)
+	}
+	for _, u := range fileKeys {
+		if u.KeyId == database.UserKeyId {
+			continue
+		}
+		if u.KeyId != current {
+			deprecated += u.Total
+		}
+		log.Printf("Master key %s: %d files without user keys", u.KeyId, u.Total)
+	}
+	if deprecated == 0 {
+		log.Printf("No keys use deprecated master keys, they may be removed from the key file")
+	}
+	return nil
+}
+
+// migrateStorage moves files matching the filters into the target storage. Every file is copied, verified
+// and switched to the copy on its own, so files keep being served during the migration. Files which are
+// already in the target are skipped, so the command resumes where it stopped.
+func migrateStorage(args []string) error {
+	c := di.Container
+	flags := flag.NewFlagSet("migrate-storage", flag.ExitOnError)
+	to := flags.String("to", c.Config().DefaultTarget, "target storage from VELMIE_WALLET_FILES_STORAGE_TARGETS")
+	from := flags.String("from", "", "only files in the storage")
+	bucket := flags.String("bucket", "", "only files in the bucket")
+	user := flags.String("user", "", "only files of the user")
+	since := flags.String("since", "", "only files uploaded at or after the date, YYYY-MM-DD")
+	until := flags.String("until", "", "only files uploaded before the date, YYYY-MM-DD")
+	concurrency := flags.Int("concurrency", 4, "number of files moved at once")
+	batch := flags.Int("batch", 100, "number of files loaded at once")
+	dryRun := flags.Bool("dry-run", false, "only count files which would be moved")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return errors.New("concurrency must be positive")
+	}
+
+	target, ok := c.Config().StorageTargets[*to]
+	if !ok {
+		return errors.New("unknown storage target " + *to)
+	}
+	filter := &database.LocationFilter{
+		Storage:       *from,
+		Bucket:        *bucket,
+		UserId:        *user,
+		TargetStorage: target.Storage,
+		TargetBucket:  target.Bucket,
+	}
+	var err error
+	if filter.CreatedFrom, err = parseDate(*since); err != nil {
+		return err
+	}
+	if filter.CreatedTo, err = parseDate(*until); err != nil {
+		return err
+	}
+
+	// the container getters are not safe for concurrent use, services are resolved before workers start
+	svc := c.StorageService()
+	queue := make(chan *database.FileModel)
+	var moved, failed int64
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range queue {
+				if err := svc.Move(file, *to); err != nil {
+					log.Printf("Can't move file %d: %s", file.ID, err)
+					atomic.AddInt64(&failed, 1)
+					continue
+				}
+				atomic.AddInt64(&moved, 1)
+			}
+		}()
+	}
+
+	var lastID uint64
+	var count, size int64
+	for {
+		var files []*database.FileModel
+		if files, err = c.Repository().FindByLocation(filter, lastID, *batch); err != nil || len(files) == 0 {
+			break
+		}
+
+		for _, file := range files {
+			lastID = file.ID
+			count++
+			size += file.Size
+			if !*dryRun {
+				queue <- file
+			}
+		}
+		if !*dryRun {
+			log.Printf("%d files queued, last file %d", count, lastID)
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		log.Printf("%d files of %d bytes would be moved to %s", count, size, *to)
+		return nil
+	}
+	log.Printf("%d files moved to %s, %d failed", moved, *to, failed)
+	return nil
+}
+
+// checkResidency reports files and replicas stored outside the residency region of their owners and moves them with -fix
+func checkResidency(args []string) error {
+	c := di.Container
+	flags := flag.NewFlagSet("residency", flag.ExitOnError)
+	user := flags.String("user", "", "only files of the user")
+	fix := flags.Bool("fix", false, "move misplaced files to the target of their region")
+	concurrency := flags.Int("concurrency", 4, "number of files moved at once")
+	batch := flags.Int("batch", 100, "number of files loaded at once")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return errors.New("concurrency must be positive")
+	}
+	if !c.ResidencyService().Enabled() {
+		return errors.New("VELMIE_WALLET_FILES_RESIDENCY is not configured")
+	}
+
+	// a move without target moves the replica of the file
+	type move struct {
+		file   *database.FileModel
+		target string
+	}
+	// the container getters are not safe for concurrent use, services are resolved before workers start
+	svc := c.StorageService()
+	queue := make(chan *move)
+	var moved, failed int64
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range queue {
+				var err error
+				if m.target == "" {
+					err = svc.MoveReplica(m.file)
+				} else {
+					err = svc.Move(m.file, m.target)
+				}
+				if err != nil {
+					log.Printf("Can't move file %d: %s", m.file.ID, err)
+					atomic.AddInt64(&failed, 1)
+					continue
+				}
+				atomic.AddInt64(&moved, 1)
+			}
+		}()
+	}
+
+	// regions are resolved once per user, a failed lookup is cached as well to skip all files of the user
+	type residency struct {
+		region string
+		target string
+		err    error
+	}
+	users := make(map[string]*residency)
+	filter := &database.LocationFilter{UserId: *user}
+	var lastID uint64
+	var err error
+	var misplaced, misplacedReplicas, unresolved int
+	for {
+		var files []*database.FileModel
+		if files, err = c.Repository().FindByLocation(filter, lastID, *batch); err != nil || len(files) == 0 {
+			break
+		}
+
+		for _, file := range files {
+			lastID = file.ID
+			r, ok := users[file.UserId]
+			if !ok {
+				r = &residency{}
+				r.region, r.target, r.err = c.ResidencyService().Region(file.UserId)
+				users[file.UserId] = r
+				if r.err != nil {
+					log.Printf("Can't resolve residency of user %s: %s", file.UserId, r.err)
+				}
+			}
+			if r.err != nil {
+				unresolved++
+				continue
+			}
+			if r.region == "" {
+				continue
+			}
+
+			target := c.Config().StorageTargets[r.target]
+			if file.Storage != target.Storage || file.Bucket != target.Bucket {
+				// the replica is moved as well when the file is replicated after the move
+				misplaced++
+				log.Printf("File %d of user %s is stored in %s/%s outside region %s", file.ID, file.UserId, file.Storage, file.Bucket, r.region)
+				if *fix {
+					queue <- &move{file, r.target}
+				}
+				continue
+			}
+
+			if file.ReplicaStorage == nil {
+				continue
+			}
+			replicaTarget, ok := c.Config().StorageTargets[c.Config().Residency[r.region].ReplicaTarget]
+			if ok && *file.ReplicaStorage == replicaTarget.Storage && file.ReplicaBucket != nil && *file.ReplicaBucket == replicaTarget.Bucket {
+				continue
+			}
+			misplacedReplicas++
+			log.Printf("Replica of file %d of user %s is stored in %s outside region %s", file.ID, file.UserId, *file.ReplicaStorage, r.region)
+			if *fix {
+				queue <- &move{file, ""}
+			}
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%d files and %d replicas are stored outside their region, %d files of users with unknown residency", misplaced, misplacedReplicas, unresolved)
+	if *fix {
+		log.Printf("%d files moved, %d failed", moved, failed)
+	}
+	return nil
+}
+
+// parseDate parses YYYY-MM-DD, it returns nil for empty string
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
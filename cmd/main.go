@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/Confialink/wallet-files/internal/config"
 	"github.com/Confialink/wallet-files/internal/di"
@@ -16,6 +17,11 @@ var (
 
 // main: main function
 func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	c := di.Container
 	appConfig = c.Config()
 	ginMode := env_mods.GetMode(appConfig.Env)
@@ -28,6 +34,18 @@ func main() {
 	// Start proto buf server
 	go c.PbServer().Start()
 
+	// Start export workers
+	go c.ExportService().Start()
+
+	// Start antivirus scan workers
+	go c.ScanService().Start()
+
+	// Start fingerprint worker
+	go c.DuplicateService().Start()
+
+	// Start replication worker
+	go c.ReplicationService().Start()
+
 	// Start gin server
 	ginRouter.Run(":" + appConfig.Port)
 }
@@ -0,0 +1,102 @@
+package archive
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ManifestName is the name of the manifest entry
+const ManifestName = "manifest.json"
+
+// Entry describes a written archive entry
+type Entry struct {
+	Name     string
+	Size     int64
+	Checksum string
+}
+
+// Writer writes a zip archive into a stream keeping entry names unique
+type Writer struct {
+	zw    *zip.Writer
+	names map[string]bool
+}
+
+// NewWriter creates a zip writer on top of w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		zw:    zip.NewWriter(w),
+		names: map[string]bool{ManifestName: true},
+	}
+}
+
+// Add copies r into a new entry. If the name is already taken a suffix is added,
+// the returned entry contains the actual name, size and sha256 checksum of the content.
+func (w *Writer) Add(name string, modified time.Time, r io.Reader) (*Entry, error) {
+	name = w.uniqueName(sanitizeName(name))
+
+	dst, err := w.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(dst, hash), r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{Name: name, Size: size, Checksum: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// AddManifest writes v as indented json into the manifest entry
+func (w *Writer) AddManifest(v interface{}) error {
+	dst, err := w.zw.CreateHeader(&zip.FileHeader{
+		Name:     ManifestName,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(dst)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	return w.zw.Close()
+}
+
+// uniqueName returns the name or the name with a " (n)" suffix before the extension
+func (w *Writer) uniqueName(name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 1; w.names[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	w.names[candidate] = true
+	return candidate
+}
+
+// sanitizeName removes directories so an entry can not be extracted outside of the target folder
+func sanitizeName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
@@ -11,6 +11,15 @@ const (
 	FilesUploadPrivateResource   = "private_files_upload_private"
 	FilesUploadPublicResource    = "private_files_upload_public"
 	FilesUploadAdminOnlyResource = "private_files_upload_admin_only"
+	FilesErasureResource         = "private_files_erasure"
+	FilesRetentionResource       = "private_files_retention"
+	FilesExportResource          = "private_files_export"
+	FilesMoveResource            = "private_files_move"
+	CollectionsResource          = "private_files_collections"
+	QuarantineResource           = "private_files_quarantine"
+	DuplicatesResource           = "private_files_duplicates"
+	// ReplicationResource has no permissions of admins, so it is available for root only
+	ReplicationResource = "private_files_replication"
 
 	CreateAction   = "create"
 	UpdateAction   = "update"
@@ -18,9 +27,9 @@ const (
 	ReadListAction = "read_list"
 	DeleteAction   = "delete"
 
-	RoleRoot      = "root"
-	RoleAdmin     = "admin"
-	RoleClient    = "client"
+	RoleRoot   = "root"
+	RoleAdmin  = "admin"
+	RoleClient = "client"
 )
 
 // ServiceInterface
@@ -53,6 +62,19 @@ func NewService(acl *goAcl.ACL, permissionsService *policy.PermissionsService) *
 			FilesUploadPrivateResource: {
 				CreateAction: allowFunc,
 			},
+			FilesExportResource: {
+				CreateAction: allowFunc,
+				ReadAction:   allowFunc,
+			},
+			FilesMoveResource: {
+				UpdateAction: auth.permissionsService.CanClientDeleteFile,
+			},
+			CollectionsResource: {
+				CreateAction:   allowFunc,
+				ReadListAction: allowFunc,
+				UpdateAction:   allowFunc,
+				DeleteAction:   allowFunc,
+			},
 		},
 		RoleAdmin: {
 			FilesResource: {
@@ -69,6 +91,32 @@ func NewService(acl *goAcl.ACL, permissionsService *policy.PermissionsService) *
 			FilesUploadAdminOnlyResource: {
 				CreateAction: auth.permissionsService.CanAdminUploadFiles,
 			},
+			FilesErasureResource: {
+				DeleteAction: auth.permissionsService.CanAdminEraseFiles,
+			},
+			FilesRetentionResource: {
+				UpdateAction: auth.permissionsService.CanAdminManageRetention,
+			},
+			FilesExportResource: {
+				CreateAction: auth.permissionsService.CanAdminReadFiles,
+				ReadAction:   auth.permissionsService.CanAdminReadFiles,
+			},
+			FilesMoveResource: {
+				UpdateAction: auth.permissionsService.CanAdminDeleteFile,
+			},
+			QuarantineResource: {
+				ReadListAction: auth.permissionsService.CanAdminManageQuarantine,
+				UpdateAction:   auth.permissionsService.CanAdminManageQuarantine,
+			},
+			DuplicatesResource: {
+				ReadListAction: auth.permissionsService.CanAdminReadDuplicates,
+			},
+			CollectionsResource: {
+				CreateAction:   auth.permissionsService.CanAdminUploadFiles,
+				ReadListAction: auth.permissionsService.CanAdminReadFiles,
+				UpdateAction:   auth.permissionsService.CanAdminUploadFiles,
+				DeleteAction:   auth.permissionsService.CanAdminUploadFiles,
+			},
 		},
 	}
 	return &auth
@@ -1,9 +1,16 @@
 package config
 
 import (
+	"time"
+
 	"github.com/Confialink/wallet-pkg-env_config"
+
+	"github.com/Confialink/wallet-files/internal/inspection"
 )
 
+// DefaultCategory is the key of settings which apply to files without a category or with a category not configured explicitly
+const DefaultCategory = "default"
+
 type Config struct {
 	Env          string
 	Db           *env_config.Db
@@ -12,6 +19,122 @@ type Config struct {
 	Cors         *env_config.Cors
 	AwsConfig    AwsConfig
 	Storage      string
+	// RetentionPeriods defines how long files of a category must be kept after creation
+	RetentionPeriods map[string]time.Duration
+	Export           ExportConfig
+	// ClamdAddress is "tcp://host:port" or "unix:///path/to/clamd.sock", scanning is disabled if empty
+	ClamdAddress string
+	// ScanBlockUnscanned blocks downloads of files which are not scanned yet or failed to be scanned
+	// while scanning is enabled
+	ScanBlockUnscanned bool
+	// AllowedTypes defines allowed file extensions per category, all supported types are allowed if empty
+	AllowedTypes map[string][]string
+	Inspection   InspectionConfig
+	// StripMetadata defines per category whether metadata is removed from uploaded images and documents
+	StripMetadata map[string]bool
+	// CwebpPath is the cwebp executable which converts images into webp, conversion is disabled if it is not found
+	CwebpPath string
+	// VariantSizes and VariantQualities are the ascending max sizes and qualities of image variants which are
+	// resized or converted on request, requested values are rounded to them to bound the number of stored variants
+	VariantSizes     []int
+	VariantQualities []int
+	// WatermarkCategories are categories of files which are always watermarked when downloaded by admins
+	WatermarkCategories map[string]bool
+	// DuplicateAlertSimilarity is the similarity from 0 to 1 of uploaded files to files of other users
+	// which is logged as a duplicate event, alerts are disabled if it is zero
+	DuplicateAlertSimilarity float64
+	// Storages are backends by name which are created by the driver of their type. Built-in "s3" and "local"
+	// storages of files uploaded before storages were configurable are added unless they are configured.
+	Storages map[string]StorageConfig
+	// DefaultTarget is the target of new files which match no route and of objects which are not files
+	DefaultTarget string
+	// StorageTargets are named places where new files are stored, the "default" target is the storage
+	// Storage unless it is configured explicitly
+	StorageTargets map[string]StorageTarget
+	// StorageRoutes choose the target of new files, the first matching route wins and files matching
+	// no route go to DefaultTarget
+	StorageRoutes []StorageRoute
+	// Residency defines regions where files of users must be stored depending on their country of residence,
+	// it takes precedence over StorageRoutes and is not enforced if empty
+	Residency map[string]ResidencyRegion
+	// ReplicaTarget is the storage target where stored objects of new files are copied in background,
+	// replication is disabled if empty
+	ReplicaTarget string
+	// MasterKeyFile is the file with master keys which wrap data keys of encrypted files, encryption is disabled if empty
+	MasterKeyFile string
+}
+
+// StorageConfig is a backend of a registered driver, options which do not apply to the type must be empty
+type StorageConfig struct {
+	// Type is "s3", "azure" or "local"
+	Type string `json:"type"`
+	// Root is the directory of local storage, the working directory if empty
+	Root string `json:"root"`
+	// Prefix is the directory of new objects in the root or in buckets
+	Prefix string `json:"prefix"`
+	// Bucket is the bucket or the Azure container of targets without a bucket
+	Bucket string `json:"bucket"`
+	// Endpoint is the URL of an S3-compatible service or of the Blob service, AWS or Azure default if empty
+	Endpoint string `json:"endpoint"`
+	// Region is VELMIE_WALLET_FILES_AWS_REGION if empty, S3-compatible services usually accept any region
+	Region string `json:"region"`
+	// PathStyle addresses buckets as endpoint/bucket instead of bucket.endpoint, MinIO and Ceph require it
+	PathStyle bool `json:"pathStyle"`
+	// AccessKey and SecretKey are static S3 credentials, AWS credentials from environment are used if empty.
+	// For azure they are the account name and the base64 encoded account key.
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+	// ServerSideEncryption of s3 objects is "AES256" for AWS and none for S3-compatible endpoints if empty
+	ServerSideEncryption string `json:"serverSideEncryption"`
+}
+
+// DefaultStorageTarget is the name of DefaultTarget unless another target is chosen
+const DefaultStorageTarget = "default"
+
+// StorageTarget is a bucket of a storage with options of new objects
+type StorageTarget struct {
+	Storage string `json:"storage"`
+	// Bucket is the bucket of the storage if empty, local storage has no buckets
+	Bucket string `json:"bucket"`
+	// ACL is the canned ACL of new objects, "private" if empty
+	ACL string `json:"acl"`
+	// StorageClass is the storage class of new objects, the bucket default if empty
+	StorageClass string `json:"storageClass"`
+	// Region is the AWS region of the bucket, the region of the s3 storage if empty
+	Region string `json:"region"`
+}
+
+// ResidencyRegion requires files of users residing in the countries to be stored in the target
+type ResidencyRegion struct {
+	// Countries are ISO 3166-1 alpha-2 codes, the region without countries takes users of all other countries
+	Countries []string `json:"countries"`
+	Target    string   `json:"target"`
+	// ReplicaTarget replaces the global replica target for users of the region, files of the region
+	// are not replicated without it
+	ReplicaTarget string `json:"replicaTarget"`
+}
+
+// StorageRoute sends new files which match all non-empty conditions to the target
+type StorageRoute struct {
+	Category string `json:"category"`
+	// Visibility is "public", "private" or "admin-only"
+	Visibility string `json:"visibility"`
+	// Role is the role of the uploader, "service" for files uploaded by other services
+	Role   string `json:"role"`
+	Target string `json:"target"`
+}
+
+type InspectionConfig struct {
+	// Reject defines whether corrupt, encrypted or out of limits documents are rejected or only flagged
+	Reject bool
+	Limits inspection.Limits
+}
+
+type ExportConfig struct {
+	// StreamLimit is the max total size of files which may be exported in a single request
+	StreamLimit int64
+	// TTL defines how long an export archive is available for download
+	TTL time.Duration
 }
 
 type AwsConfig struct {
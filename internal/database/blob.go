@@ -0,0 +1,25 @@
+package database
+
+import "time"
+
+// TableName sets Blob's table name to be `blobs`
+func (BlobModel) TableName() string {
+	return "blobs"
+}
+
+// BlobModel is a stored object with content shared by all files with the same SHA-256 in a storage.
+// The object is deleted when the last file referencing it is deleted.
+type BlobModel struct {
+	ID        uint64    `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Sha256    string    `gorm:"column:sha256" json:"sha256"`
+	Storage   string    `json:"storage"`
+	Bucket    string    `json:"-"`
+	Path      string    `json:"-"`
+	Filename  string    `json:"-"`
+	Size      int64     `json:"size"`
+	RefCount  int       `json:"refCount"`
+	// UserId is the owner of the files referencing an encrypted blob, it is empty for blobs shared by all users
+	UserId string `json:"-"`
+}
@@ -0,0 +1,210 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrBlobMissing means the blob was deleted before a file could reference it
+var ErrBlobMissing = errors.New("blob is missing")
+
+// BlobRepository is repository for content addressed blobs and references of files to them.
+// Blobs of encrypted content are deduplicated per user only, so destroying the key of a user
+// never leaves their content readable with keys of other users.
+type BlobRepository struct {
+	db      *gorm.DB
+	perUser bool
+}
+
+// NewBlobRepository creates new blob repository, perUser scopes blobs by the owner of files
+func NewBlobRepository(db *gorm.DB, perUser bool) *BlobRepository {
+	return &BlobRepository{db, perUser}
+}
+
+// Find finds the blob of the content in the bucket of the storage which files of the user may reference
+func (repo *BlobRepository) Find(sha256 string, uid string, storage string, bucket string) (*BlobModel, error) {
+	var blob BlobModel
+	err := repo.db.
+		Where("sha256 = ? AND storage = ? AND bucket = ? AND user_id = ?", sha256, storage, bucket, repo.owner(uid)).
+		First(&blob).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// owner returns the owner of blobs which files of the user reference
+func (repo *BlobRepository) owner(uid string) string {
+	if repo.perUser {
+		return uid
+	}
+	return ""
+}
+
+// ShareKey gives the file access to the data key of an existing blob by the key of another file which references it
+type ShareKey func(file *FileModel, sibling *FileModel) error
+
+// CreateFile creates the file which references the blob with its content.
+// An existing blob of the content is referenced, otherwise the given new blob is created.
+// ErrBlobMissing is returned if the blob does not exist and no new blob is given.
+func (repo *BlobRepository) CreateFile(file *FileModel, sha256 string, newBlob *BlobModel, shareKey ShareKey) (*FileModel, error) {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := repo.acquire(tx, file, sha256, newBlob, shareKey); err != nil {
+			return err
+		}
+		return tx.Create(file).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// AttachFile makes an existing file reference the blob with its content, the same way as CreateFile
+func (repo *BlobRepository) AttachFile(file *FileModel, sha256 string, newBlob *BlobModel, shareKey ShareKey) (*FileModel, error) {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := repo.acquire(tx, file, sha256, newBlob, shareKey); err != nil {
+			return err
+		}
+		return saveReference(tx, file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// FindByID finds the blob by id
+func (repo *BlobRepository) FindByID(id uint64) (*BlobModel, error) {
+	var blob BlobModel
+	if err := repo.db.Where("id = ?", id).First(&blob).Error; err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// MoveFile makes the file reference the blob with its content in the bucket of the storage, the same way
+// as AttachFile, and releases the blob it referenced before, the same way as DeleteFile.
+// The file is switched atomically.
+func (repo *BlobRepository) MoveFile(
+	file *FileModel,
+	storage string,
+	bucket string,
+	newBlob *BlobModel,
+	shareKey ShareKey,
+	deleteObject func(blob *BlobModel) error,
+) (*FileModel, error) {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var source BlobModel
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", file.BlobId).First(&source).Error; err != nil {
+			return err
+		}
+
+		file.Storage, file.Bucket = storage, bucket
+		if err := repo.acquire(tx, file, source.Sha256, newBlob, shareKey); err != nil {
+			return err
+		}
+		if err := saveReference(tx, file); err != nil {
+			return err
+		}
+		return release(tx, &source, deleteObject)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// DeleteFile deletes the file and releases its blob. When the last reference goes, deleteObject is called
+// before the blob is deleted, nothing is deleted if it fails.
+func (repo *BlobRepository) DeleteFile(file *FileModel, deleteObject func(blob *BlobModel) error) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var blob BlobModel
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", file.BlobId).First(&blob).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(file).Error; err != nil {
+			return err
+		}
+		return release(tx, &blob, deleteObject)
+	})
+}
+
+// release decrements references of the locked blob, the last reference deletes the object and the blob
+func release(tx *gorm.DB, blob *BlobModel, deleteObject func(blob *BlobModel) error) error {
+	if blob.RefCount > 1 {
+		return tx.Model(blob).UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error
+	}
+	if err := deleteObject(blob); err != nil {
+		return err
+	}
+	return tx.Delete(blob).Error
+}
+
+// saveReference saves the blob reference and the data key of the file
+func saveReference(tx *gorm.DB, file *FileModel) error {
+	return tx.Model(file).Updates(map[string]interface{}{
+		"blob_id":     file.BlobId,
+		"sha256":      file.Sha256,
+		"storage":     file.Storage,
+		"bucket":      file.Bucket,
+		"path":        file.Path,
+		"target":      file.Target,
+		"wrapped_key": file.WrappedKey,
+		"key_id":      file.KeyId,
+	}).Error
+}
+
+// acquire increments references of the blob of the content in the bucket of the file storage,
+// or creates the new blob, and points the file to the blob object. The blob row is locked until
+// the transaction ends, so it can not be deleted by a concurrent release. The data key of an existing
+// blob is shared from another file, the key of the new blob must be already set to the file.
+func (repo *BlobRepository) acquire(tx *gorm.DB, file *FileModel, sha256 string, newBlob *BlobModel, shareKey ShareKey) error {
+	var blob BlobModel
+	err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("sha256 = ? AND storage = ? AND bucket = ? AND user_id = ?", sha256, file.Storage, file.Bucket, repo.owner(file.UserId)).
+		First(&blob).
+		Error
+
+	switch {
+	case err == nil:
+		if err = shareSiblingKey(tx, file, &blob, shareKey); err != nil {
+			return err
+		}
+		err = tx.Model(&blob).UpdateColumn("ref_count", gorm.Expr("ref_count + 1")).Error
+	case gorm.IsRecordNotFoundError(err) && newBlob != nil:
+		blob = *newBlob
+		blob.UserId = repo.owner(file.UserId)
+		blob.RefCount = 1
+		err = tx.Create(&blob).Error
+	case gorm.IsRecordNotFoundError(err):
+		err = ErrBlobMissing
+	}
+	if err != nil {
+		return err
+	}
+
+	file.BlobId = &blob.ID
+	file.Sha256 = &blob.Sha256
+	file.Bucket = blob.Bucket
+	file.Path = blob.Path
+	return nil
+}
+
+// shareSiblingKey shares the data key of the blob from the first file referencing it which key is not shredded
+func shareSiblingKey(tx *gorm.DB, file *FileModel, blob *BlobModel, shareKey ShareKey) error {
+	var siblings []*FileModel
+	if err := tx.Where("blob_id = ?", blob.ID).Order("id").Find(&siblings).Error; err != nil {
+		return err
+	}
+
+	err := error(ErrKeyShredded)
+	for _, sibling := range siblings {
+		if err = shareKey(file, sibling); err != ErrKeyShredded {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,25 @@
+package database
+
+import "time"
+
+// TableName sets Collection's table name to be `collections`
+func (CollectionModel) TableName() string {
+	return "collections"
+}
+
+// CollectionModel is a folder of user files, collections may be nested
+type CollectionModel struct {
+	ID        uint64    `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UserId    string    `json:"userId"`
+	ParentId  *uint64   `json:"parentId"`
+	Name      string    `json:"name"`
+}
+
+// CollectionWithStats is a collection with count and total size of files placed directly into it
+type CollectionWithStats struct {
+	CollectionModel
+	FilesCount int64 `json:"filesCount"`
+	Size       int64 `json:"size"`
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// CollectionRepository is repository for file collections
+type CollectionRepository struct {
+	db *gorm.DB
+}
+
+// NewCollectionRepository creates new collection repository
+func NewCollectionRepository(db *gorm.DB) *CollectionRepository {
+	return &CollectionRepository{db}
+}
+
+// FindByID finds collection by id
+func (repo *CollectionRepository) FindByID(id uint64) (*CollectionModel, error) {
+	var collection CollectionModel
+	if err := repo.db.Where("id = ?", id).First(&collection).Error; err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// FindByParentIDs finds children of the collections
+func (repo *CollectionRepository) FindByParentIDs(ids []uint64) ([]*CollectionModel, error) {
+	var collections []*CollectionModel
+	if err := repo.db.Where("parent_id IN (?)", ids).Find(&collections).Error; err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// FindWithStatsByUID finds all collections of the user with files count and size
+func (repo *CollectionRepository) FindWithStatsByUID(uid string) ([]*CollectionWithStats, error) {
+	var collections []*CollectionWithStats
+	err := repo.db.
+		Table("collections").
+		Select("collections.*, COUNT(files.id) AS files_count, COALESCE(SUM(files.size), 0) AS size").
+		Joins("LEFT JOIN files ON files.collection_id = collections.id").
+		Where("collections.user_id = ?", uid).
+		Group("collections.id").
+		Order("collections.name").
+		Scan(&collections).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// Create creates a new collection
+func (repo *CollectionRepository) Create(collection *CollectionModel) (*CollectionModel, error) {
+	if err := repo.db.Create(collection).Error; err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// Save updates all fields of an existing collection
+func (repo *CollectionRepository) Save(collection *CollectionModel) (*CollectionModel, error) {
+	if err := repo.db.Save(collection).Error; err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// Delete deletes an existing collection
+func (repo *CollectionRepository) Delete(collection *CollectionModel) error {
+	return repo.db.Delete(collection).Error
+}
+
+// CountByUID counts collections of the user
+func (repo *CollectionRepository) CountByUID(uid string) (int, error) {
+	var count int
+	err := repo.db.Model(&CollectionModel{}).Where("user_id = ?", uid).Count(&count).Error
+	return count, err
+}
+
+// DeleteByUID deletes all collections of the user, remaining files of the user are moved out of them
+func (repo *CollectionRepository) DeleteByUID(uid string) (int, error) {
+	var count int64
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&FileModel{}).
+			Where("user_id = ? AND collection_id IS NOT NULL", uid).
+			UpdateColumn("collection_id", gorm.Expr("NULL")).
+			Error
+		if err != nil {
+			return err
+		}
+		result := tx.Where("user_id = ?", uid).Delete(&CollectionModel{})
+		count = result.RowsAffected
+		return result.Error
+	})
+	return int(count), err
+}
@@ -0,0 +1,37 @@
+package database
+
+import "time"
+
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusDone       = "done"
+	ExportStatusFailed     = "failed"
+	ExportStatusExpired    = "expired"
+)
+
+// TableName sets Export's table name to be `exports`
+func (ExportModel) TableName() string {
+	return "exports"
+}
+
+// ExportModel is a background export of user files into an archive
+type ExportModel struct {
+	ID          uint64     `gorm:"primary_key" json:"id"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	UserId      string     `json:"userId"`
+	RequestedBy string     `json:"requestedBy"`
+	Status      string     `json:"status"`
+	Storage     string     `json:"-"`
+	Bucket      string     `json:"-"`
+	Path        string     `json:"-"`
+	Filename    string     `json:"-"`
+	Size        int64      `json:"size"`
+	FilesCount  int        `json:"filesCount"`
+	Error       string     `json:"-"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	// Watermark stamps files of watermarked categories in exports requested by admins
+	Watermark *string `json:"-"`
+	ObjectKey
+}
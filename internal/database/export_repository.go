@@ -0,0 +1,87 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ExportRepository is repository for export jobs
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+// NewExportRepository creates new export repository
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	return &ExportRepository{db}
+}
+
+// FindByID finds export by id
+func (repo *ExportRepository) FindByID(id uint64) (*ExportModel, error) {
+	var export ExportModel
+	if err := repo.db.Where("id = ?", id).First(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindByStatuses finds exports in the given statuses
+func (repo *ExportRepository) FindByStatuses(statuses []string) ([]*ExportModel, error) {
+	var exports []*ExportModel
+	if err := repo.db.Where("status IN (?)", statuses).Order("id").Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// FindExpired finds finished exports which expired before the time
+func (repo *ExportRepository) FindExpired(before time.Time) ([]*ExportModel, error) {
+	var exports []*ExportModel
+	err := repo.db.
+		Where("status = ? AND expires_at < ?", ExportStatusDone, before).
+		Find(&exports).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// FindByUID finds all exports of the user
+func (repo *ExportRepository) FindByUID(uid string) ([]*ExportModel, error) {
+	var exports []*ExportModel
+	if err := repo.db.Where("user_id = ?", uid).Order("id").Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// Exists checks if the export was not deleted
+func (repo *ExportRepository) Exists(id uint64) (bool, error) {
+	var count int
+	if err := repo.db.Model(&ExportModel{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Create creates a new export
+func (repo *ExportRepository) Create(export *ExportModel) (*ExportModel, error) {
+	if err := repo.db.Create(export).Error; err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Save updates all fields of an existing export
+func (repo *ExportRepository) Save(export *ExportModel) (*ExportModel, error) {
+	if err := repo.db.Save(export).Error; err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Delete deletes the export
+func (repo *ExportRepository) Delete(export *ExportModel) error {
+	return repo.db.Delete(export).Error
+}
@@ -1,24 +1,107 @@
 package database
 
-import "time"
+import (
+	"time"
+
+	"github.com/Confialink/wallet-files/internal/validation"
+)
+
+// Scan statuses of files, the status is empty if the file was not scanned
+const (
+	ScanStatusPending  = "pending"
+	ScanStatusClean    = "clean"
+	ScanStatusInfected = "infected"
+	ScanStatusError    = "error"
+)
+
+// Replica statuses of files, the status is empty if the file is not replicated
+const (
+	ReplicaStatusPending = "pending"
+	ReplicaStatusDone    = "done"
+	ReplicaStatusFailed  = "failed"
+)
 
 // TableName sets File's table name to be `files`
 func (FileModel) TableName() string {
 	return "files"
 }
 
+// AfterFind sanitizes the original name, names of files uploaded before sanitizing were copied as is
+func (f *FileModel) AfterFind() error {
+	f.OriginalFilename = validation.SanitizeFilename(f.OriginalFilename)
+	return nil
+}
+
 type FileModel struct {
-	ID          uint64    `gorm:"primary_key" json:"id"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	UserId      string    `json:"userId"`
-	Path        string    `json:"path"`
-	Filename    string    `json:"filename"`
-	Bucket      string    `json:"-"`
-	Storage     string    `json:"storage"`
-	ContentType string    `json:"contentType"`
-	Size        int64     `json:"size"`
-	IsAdminOnly bool      `json:"isAdminOnly"`
-	IsPrivate   bool      `json:"isPrivate"`
-	Category    *string   `json:"-"`
+	ID            uint64          `gorm:"primary_key" json:"id"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	UserId        string          `json:"userId"`
+	Path          string          `json:"path"`
+	Filename      string          `json:"filename"`
+	Bucket        string          `json:"-"`
+	Storage       string          `json:"storage"`
+	ContentType   string          `json:"contentType"`
+	Size          int64           `json:"size"`
+	IsAdminOnly   bool            `json:"isAdminOnly"`
+	IsPrivate     bool            `json:"isPrivate"`
+	Category      *string         `json:"-"`
+	LegalHold     bool            `json:"legalHold"`
+	RetainUntil   *time.Time      `json:"retainUntil"`
+	CollectionId  *uint64         `json:"collectionId"`
+	ScanStatus    string          `json:"scanStatus"`
+	ScanSignature string          `json:"scanSignature,omitempty"`
+	QuarantinedAt *time.Time      `json:"quarantinedAt"`
+	Properties    *FileProperties `gorm:"type:text" json:"properties"`
+	// ScanAttempts counts failed scans since the file was queued, failed scans are retried a few times
+	ScanAttempts int `json:"-"`
+	// OriginalFilename is the sanitized name of the uploaded file, it is never a part of object keys
+	OriginalFilename string `json:"originalFilename"`
+	// Sha256 is the hex encoded hash of the stored content, it is empty until the file is fingerprinted
+	Sha256 *string `gorm:"column:sha256" json:"sha256,omitempty"`
+	// PerceptualHash is the difference hash of an image which is stored as signed to fit into BIGINT
+	PerceptualHash *int64 `json:"-"`
+	// BlobId references the content addressed object with the content, files uploaded before blobs
+	// were introduced are stored under their own path and filename
+	BlobId *uint64 `json:"-"`
+	// WrappedKey is the data key of the encrypted content wrapped with the master key KeyId,
+	// content of files without a key is stored unencrypted
+	WrappedKey []byte  `json:"-"`
+	KeyId      *string `json:"-"`
+	// Target is the name of the storage target the file was routed to, it is empty for files uploaded before routing
+	Target string `json:"target"`
+	// ReplicaStatus tracks the copy of the stored object in the replica target, ReplicaRetryAt is the time
+	// of the next attempt of a pending replication
+	ReplicaStatus   string     `json:"replicaStatus"`
+	ReplicaAttempts int        `json:"-"`
+	ReplicaRetryAt  *time.Time `json:"-"`
+	// ReplicaStorage, ReplicaBucket and ReplicaPath locate the copy, it has the name of the stored object
+	ReplicaStorage *string `json:"-"`
+	ReplicaBucket  *string `json:"-"`
+	ReplicaPath    *string `json:"-"`
+}
+
+// ReplicaBacklog counts files which are not replicated yet
+type ReplicaBacklog struct {
+	Pending int64 `json:"pending"`
+	Failed  int64 `json:"failed"`
+	// OldestPendingAt is the upload time of the oldest pending file
+	OldestPendingAt *time.Time `json:"oldestPendingAt"`
+}
+
+// KeyUsage is the number of keys wrapped with the master key
+type KeyUsage struct {
+	KeyId string
+	Total int64
+}
+
+// IsQuarantined checks if the file must not be served
+func (f *FileModel) IsQuarantined() bool {
+	return f.ScanStatus == ScanStatusInfected
+}
+
+// IsScanPending checks if the file is not scanned yet or its scan failed,
+// files uploaded before scanning was enabled have no scan status
+func (f *FileModel) IsScanPending() bool {
+	return f.ScanStatus == ScanStatusPending || f.ScanStatus == ScanStatusError
 }
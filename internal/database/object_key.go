@@ -0,0 +1,8 @@
+package database
+
+// ObjectKey is the data key of an object derived from files of a user, e.g. a variant or an export archive.
+// It is wrapped for the user like data keys of files, objects without a key are stored unencrypted.
+type ObjectKey struct {
+	WrappedKey []byte  `json:"-"`
+	KeyId      *string `json:"-"`
+}
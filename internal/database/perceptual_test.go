@@ -0,0 +1,45 @@
+package database
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPerceptualBand(t *testing.T) {
+	hash := int64(-0x0123456789ABCDEF)
+	expected := []uint16{0xFEDC, 0xBA98, 0x7654, 0x3211}
+	for i, band := range expected {
+		if got := perceptualBand(hash, i); got != band {
+			t.Errorf("band %d: expected %04X, got %04X", i, band, got)
+		}
+	}
+}
+
+// TestPerceptualBandProbes checks that hashes within 7 bits always share a probed band
+func TestPerceptualBandProbes(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for distance := 0; distance <= 7; distance++ {
+		for n := 0; n < 1000; n++ {
+			hash := int64(random.Uint64())
+			other := hash
+			for _, bit := range random.Perm(64)[:distance] {
+				other ^= 1 << uint(bit)
+			}
+
+			if !probed(hash, other) {
+				t.Fatalf("hashes %016X and %016X within %d bits share no probed band", uint64(hash), uint64(other), distance)
+			}
+		}
+	}
+}
+
+func probed(hash, other int64) bool {
+	for i := 0; i < perceptualBands; i++ {
+		for _, probe := range perceptualBandProbes(perceptualBand(hash, i)) {
+			if probe == perceptualBand(other, i) {
+				return true
+			}
+		}
+	}
+	return false
+}
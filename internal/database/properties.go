@@ -0,0 +1,40 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// FileProperties are properties of a document read on upload
+type FileProperties struct {
+	Format    string `json:"format"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Pages     int    `json:"pages,omitempty"`
+	Encrypted bool   `json:"encrypted,omitempty"`
+	// Sanitized is true if metadata was stripped from the document
+	Sanitized bool `json:"sanitized,omitempty"`
+	// Problems found in the document if it was accepted anyway
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Value stores properties as json
+func (p FileProperties) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads properties from json
+func (p *FileProperties) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	}
+	return errors.New("unsupported file properties value")
+}
@@ -1,6 +1,10 @@
 package database
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/Confialink/wallet-pkg-list_params"
 	"github.com/jinzhu/gorm"
 )
@@ -38,6 +42,66 @@ func (repo *Repository) GetByID(id uint64) (*FileModel, error) {
 	return &file, nil
 }
 
+// FindByIDs finds files by ids, missing ids are skipped
+func (repo *Repository) FindByIDs(ids []uint64) ([]*FileModel, error) {
+	var files []*FileModel
+	if err := repo.db.Where("id IN (?)", ids).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// FindByCollectionIDs finds files placed into the collections
+func (repo *Repository) FindByCollectionIDs(ids []uint64) ([]*FileModel, error) {
+	var files []*FileModel
+	if err := repo.db.Where("collection_id IN (?)", ids).Order("id").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// FindByScanStatus finds files with the scan status
+func (repo *Repository) FindByScanStatus(status string) ([]*FileModel, error) {
+	var files []*FileModel
+	if err := repo.db.Where("scan_status = ?", status).Order("id").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// FindPageByScanStatus finds a page of files with the scan status and counts all of them,
+// files of all users are found if uid is empty
+func (repo *Repository) FindPageByScanStatus(status string, uid string, limit int, offset int) ([]*FileModel, int64, error) {
+	query := repo.db.Model(&FileModel{}).Where("scan_status = ?", status)
+	if uid != "" {
+		query = query.Where("user_id = ?", uid)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var files []*FileModel
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&files).Error; err != nil {
+		return nil, 0, err
+	}
+	return files, total, nil
+}
+
+// FindScanRetries finds files which failed to be scanned less than maxAttempts times
+func (repo *Repository) FindScanRetries(maxAttempts int, limit int) ([]*FileModel, error) {
+	var files []*FileModel
+	err := repo.db.Where("scan_status = ? AND scan_attempts < ?", ScanStatusError, maxAttempts).
+		Order("id").
+		Limit(limit).
+		Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
 // FindAdminVisibleByUID find admin visible files by user id
 func (repo *Repository) FindAdminVisibleByUID(uid string, excludeCategories []string) ([]*FileModel, error) {
 	var files []*FileModel
@@ -47,6 +111,15 @@ func (repo *Repository) FindAdminVisibleByUID(uid string, excludeCategories []st
 	return files, nil
 }
 
+// FindByUID find all files by user id
+func (repo *Repository) FindByUID(uid string) ([]*FileModel, error) {
+	var files []*FileModel
+	if err := repo.db.Where("user_id = ?", uid).Order("id").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
 // FindClientVisibleByUID find client visible files by user id
 func (repo *Repository) FindClientVisibleByUID(uid string) ([]*FileModel, error) {
 	var files []*FileModel
@@ -119,6 +192,287 @@ func (repo *Repository) Update(file *FileModel) (*FileModel, error) {
 	return file, nil
 }
 
+// UpdateRetention updates legal hold and retention date of the file
+func (repo *Repository) UpdateRetention(file *FileModel) (*FileModel, error) {
+	err := repo.db.Model(file).Updates(map[string]interface{}{
+		"legal_hold":   file.LegalHold,
+		"retain_until": file.RetainUntil,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// UpdateCollection moves the file into its collection
+func (repo *Repository) UpdateCollection(file *FileModel) (*FileModel, error) {
+	if err := repo.db.Model(file).Update("collection_id", file.CollectionId).Error; err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// UpdateScan updates scan status, attempts and quarantine date of the file
+func (repo *Repository) UpdateScan(file *FileModel) (*FileModel, error) {
+	err := repo.db.Model(file).Updates(map[string]interface{}{
+		"scan_status":    file.ScanStatus,
+		"scan_signature": file.ScanSignature,
+		"scan_attempts":  file.ScanAttempts,
+		"quarantined_at": file.QuarantinedAt,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// UpdateFingerprint updates content hashes of the file and bands of the perceptual hash
+func (repo *Repository) UpdateFingerprint(file *FileModel) (*FileModel, error) {
+	values := map[string]interface{}{
+		"sha256":          file.Sha256,
+		"perceptual_hash": file.PerceptualHash,
+	}
+	for i := 0; i < perceptualBands; i++ {
+		values[fmt.Sprintf("perceptual_band%d", i)] = nil
+		if file.PerceptualHash != nil {
+			values[fmt.Sprintf("perceptual_band%d", i)] = perceptualBand(*file.PerceptualHash, i)
+		}
+	}
+	err := repo.db.Model(file).Updates(values).Error
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// FindWithoutFingerprint finds files which content was not hashed yet in batches ordered by id
+func (repo *Repository) FindWithoutFingerprint(afterID uint64, limit int) ([]*FileModel, error) {
+	var files []*FileModel
+	err := repo.db.
+		Where("sha256 IS NULL AND id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&files).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// FindWithoutBlob finds files stored before blobs were introduced in batches ordered by id
+func (repo *Repository) FindWithoutBlob(afterID uint64, limit int) ([]*FileModel, error) {
+	var files []*FileModel
+	err := repo.db.
+		Where("blob_id IS NULL AND id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&files).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// LocationFilter selects files by location, upload time and owner, empty fields match all files
+type LocationFilter struct {
+	Storage     string
+	Bucket      string
+	UserId      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// files which are already in the target bucket of the target storage are skipped
+	TargetStorage string
+	TargetBucket  string
+}
+
+// FindByLocation finds files matching the filter in batches ordered by id
+func (repo *Repository) FindByLocation(filter *LocationFilter, afterID uint64, limit int) ([]*FileModel, error) {
+	query := repo.db.Where("id > ?", afterID)
+	if filter.Storage != "" {
+		query = query.Where("storage = ?", filter.Storage)
+	}
+	if filter.Bucket != "" {
+		query = query.Where("bucket = ?", filter.Bucket)
+	}
+	if filter.UserId != "" {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.TargetStorage != "" {
+		query = query.Where("NOT (storage = ? AND COALESCE(bucket, '') = ?)", filter.TargetStorage, filter.TargetBucket)
+	}
+
+	var files []*FileModel
+	if err := query.Order("id").Limit(limit).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// FindWithoutUserKey finds encrypted files with data keys wrapped directly with a master key in batches ordered by id
+func (repo *Repository) FindWithoutUserKey(afterID uint64, limit int) ([]*FileModel, error) {
+	var files []*FileModel
+	err := repo.db.
+		Where("wrapped_key IS NOT NULL AND key_id <> ? AND id > ?", UserKeyId, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&files).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// UpdateWrappedKey saves the data key of the file wrapped with another key.
+// Nothing is saved if the key was rewrapped concurrently since it was wrapped with oldKeyId.
+func (repo *Repository) UpdateWrappedKey(file *FileModel, oldKeyId string) error {
+	return repo.db.
+		Model(&FileModel{}).
+		Where("id = ? AND key_id = ?", file.ID, oldKeyId).
+		Updates(map[string]interface{}{
+			"wrapped_key": file.WrappedKey,
+			"key_id":      file.KeyId,
+		}).
+		Error
+}
+
+// CountByKeyId counts encrypted files per key which wraps their data keys
+func (repo *Repository) CountByKeyId() ([]*KeyUsage, error) {
+	var usage []*KeyUsage
+	err := repo.db.
+		Table("files").
+		Select("key_id, COUNT(*) AS total").
+		Where("wrapped_key IS NOT NULL").
+		Group("key_id").
+		Order("key_id").
+		Scan(&usage).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// UpdateReplica updates replication status of the file
+func (repo *Repository) UpdateReplica(file *FileModel) (*FileModel, error) {
+	err := repo.db.Model(file).Updates(map[string]interface{}{
+		"replica_status":   file.ReplicaStatus,
+		"replica_attempts": file.ReplicaAttempts,
+		"replica_retry_at": file.ReplicaRetryAt,
+		"replica_storage":  file.ReplicaStorage,
+		"replica_bucket":   file.ReplicaBucket,
+		"replica_path":     file.ReplicaPath,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// FindReplicaDue finds pending files which replication is due at the time
+func (repo *Repository) FindReplicaDue(now time.Time, limit int) ([]*FileModel, error) {
+	var files []*FileModel
+	err := repo.db.
+		Where("replica_status = ? AND replica_retry_at <= ?", ReplicaStatusPending, now).
+		Order("replica_retry_at").
+		Limit(limit).
+		Find(&files).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// CountReplicas counts files of the blob which replicas are stored in the bucket of the storage
+func (repo *Repository) CountReplicas(blobID uint64, storage, bucket string) (int, error) {
+	var count int
+	err := repo.db.Model(&FileModel{}).
+		Where("blob_id = ? AND replica_storage = ? AND replica_bucket = ?", blobID, storage, bucket).
+		Count(&count).
+		Error
+	return count, err
+}
+
+// GetReplicaBacklog counts files which are not replicated yet
+func (repo *Repository) GetReplicaBacklog() (*ReplicaBacklog, error) {
+	var backlog ReplicaBacklog
+	var rows []struct {
+		ReplicaStatus string
+		Total         int64
+		Oldest        *time.Time
+	}
+	err := repo.db.
+		Table("files").
+		Select("replica_status, COUNT(*) AS total, MIN(created_at) AS oldest").
+		Where("replica_status IN (?)", []string{ReplicaStatusPending, ReplicaStatusFailed}).
+		Group("replica_status").
+		Scan(&rows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if row.ReplicaStatus == ReplicaStatusFailed {
+			backlog.Failed = row.Total
+			continue
+		}
+		backlog.Pending = row.Total
+		backlog.OldestPendingAt = row.Oldest
+	}
+	return &backlog, nil
+}
+
+// FindBySha256 finds files of other users with identical content
+func (repo *Repository) FindBySha256(sha256 string, excludeUID string, limit int) ([]*FileModel, error) {
+	var files []*FileModel
+	err := repo.db.
+		Where("sha256 = ? AND user_id <> ?", sha256, excludeUID).
+		Order("id").
+		Limit(limit).
+		Find(&files).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// FindByPerceptualHash finds images of other users which hashes differ in at most maxDistance bits.
+// Only images with a band of the hash which differs in at most one bit are compared, so all images
+// within 7 bits are found and farther ones only if their differences are not spread over all bands.
+func (repo *Repository) FindByPerceptualHash(hash int64, maxDistance int, excludeUID string, limit int) ([]*FileModel, error) {
+	var conditions []string
+	var values []interface{}
+	for i := 0; i < perceptualBands; i++ {
+		conditions = append(conditions, fmt.Sprintf("perceptual_band%d IN (?)", i))
+		values = append(values, perceptualBandProbes(perceptualBand(hash, i)))
+	}
+
+	var files []*FileModel
+	err := repo.db.
+		Where("perceptual_hash IS NOT NULL AND user_id <> ?", excludeUID).
+		Where(strings.Join(conditions, " OR "), values...).
+		Where("BIT_COUNT(perceptual_hash ^ ?) <= ?", hash, maxDistance).
+		Order(gorm.Expr("BIT_COUNT(perceptual_hash ^ ?)", hash)).
+		Limit(limit).
+		Find(&files).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
 // Delete delete an existing user
 func (repo *Repository) Delete(file *FileModel) error {
 	if err := repo.db.Delete(file).Error; err != nil {
@@ -126,3 +480,20 @@ func (repo *Repository) Delete(file *FileModel) error {
 	}
 	return nil
 }
+
+// perceptualBands is the number of 16 bit bands of perceptual hashes
+const perceptualBands = 4
+
+// perceptualBand returns the band of the hash, the first band holds the highest bits
+func perceptualBand(hash int64, i int) uint16 {
+	return uint16(uint64(hash) >> uint(16*(perceptualBands-1-i)))
+}
+
+// perceptualBandProbes returns the band and all values which differ from it in one bit
+func perceptualBandProbes(band uint16) []uint16 {
+	probes := []uint16{band}
+	for bit := uint(0); bit < 16; bit++ {
+		probes = append(probes, band^1<<bit)
+	}
+	return probes
+}
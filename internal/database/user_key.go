@@ -0,0 +1,46 @@
+package database
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrKeyShredded means the key of the user was destroyed, so their files can't be decrypted anymore
+var ErrKeyShredded = errors.New("user key is shredded")
+
+// UserKeyId is the key id of files with data keys wrapped with the key-encryption key of the file owner
+const UserKeyId = "user"
+
+// TableName sets UserKey's table name to be `user_keys`
+func (UserKeyModel) TableName() string {
+	return "user_keys"
+}
+
+// UserKeyModel is the key-encryption key of a user which wraps data keys of the user files.
+// The key itself is wrapped with the master key KeyId, it is destroyed when the user is shredded.
+type UserKeyModel struct {
+	ID         uint64     `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	UserId     string     `json:"userId"`
+	WrappedKey []byte     `json:"-"`
+	KeyId      *string    `json:"-"`
+	ShreddedAt *time.Time `json:"shreddedAt"`
+}
+
+// TableName sets ShredEvent's table name to be `shred_events`
+func (ShredEventModel) TableName() string {
+	return "shred_events"
+}
+
+// ShredEventModel records destruction of the user key for compliance
+type ShredEventModel struct {
+	ID          uint64    `gorm:"primary_key" json:"id"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UserId      string    `json:"userId"`
+	RequestedBy string    `json:"requestedBy"`
+	// FilesCount is the number of files deleted together with the key, files which failed to be deleted
+	// are unreadable as well and are deleted when shredding is repeated
+	FilesCount  int `json:"filesCount"`
+	FailedCount int `json:"failedCount"`
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// UserKeyRepository is repository for key-encryption keys of users
+type UserKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewUserKeyRepository creates new user key repository
+func NewUserKeyRepository(db *gorm.DB) *UserKeyRepository {
+	return &UserKeyRepository{db}
+}
+
+// FindByUID finds the key of the user
+func (repo *UserKeyRepository) FindByUID(uid string) (*UserKeyModel, error) {
+	var key UserKeyModel
+	if err := repo.db.Where("user_id = ?", uid).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Create creates the key of the user, the key created concurrently is returned if it already exists
+func (repo *UserKeyRepository) Create(key *UserKeyModel) (*UserKeyModel, error) {
+	if err := repo.db.Create(key).Error; err != nil {
+		if existing, findErr := repo.FindByUID(key.UserId); findErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// FindWithDeprecatedKey finds keys of users which are wrapped with other than the current master key in batches ordered by id
+func (repo *UserKeyRepository) FindWithDeprecatedKey(currentKeyId string, afterID uint64, limit int) ([]*UserKeyModel, error) {
+	var keys []*UserKeyModel
+	err := repo.db.
+		Where("wrapped_key IS NOT NULL AND key_id <> ? AND id > ?", currentKeyId, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&keys).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// UpdateWrappedKey saves the key wrapped with another master key.
+// Nothing is saved if the key was rewrapped or shredded concurrently since it was wrapped with oldKeyId.
+func (repo *UserKeyRepository) UpdateWrappedKey(key *UserKeyModel, oldKeyId string) error {
+	return repo.db.
+		Model(&UserKeyModel{}).
+		Where("id = ? AND key_id = ?", key.ID, oldKeyId).
+		Updates(map[string]interface{}{
+			"wrapped_key": key.WrappedKey,
+			"key_id":      key.KeyId,
+		}).
+		Error
+}
+
+// CountByKeyId counts keys of users per master key which wraps them
+func (repo *UserKeyRepository) CountByKeyId() ([]*KeyUsage, error) {
+	var usage []*KeyUsage
+	err := repo.db.
+		Table("user_keys").
+		Select("key_id, COUNT(*) AS total").
+		Where("wrapped_key IS NOT NULL").
+		Group("key_id").
+		Order("key_id").
+		Scan(&usage).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// Shred destroys the key of the user and records the event. The key row is kept as shredded,
+// so no new key is created for the user.
+func (repo *UserKeyRepository) Shred(event *ShredEventModel) (*ShredEventModel, error) {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var key UserKeyModel
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Where("user_id = ?", event.UserId).First(&key).Error
+		switch {
+		case gorm.IsRecordNotFoundError(err):
+			err = tx.Create(&UserKeyModel{UserId: event.UserId, ShreddedAt: &now}).Error
+		case err == nil:
+			err = tx.Model(&key).Updates(map[string]interface{}{
+				"wrapped_key": nil,
+				"key_id":      nil,
+				"shredded_at": now,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -0,0 +1,26 @@
+package database
+
+import "time"
+
+// TableName sets Variant's table name to be `file_variants`
+func (VariantModel) TableName() string {
+	return "file_variants"
+}
+
+// VariantModel is a resized copy of an image file
+type VariantModel struct {
+	ID          uint64    `gorm:"primary_key" json:"id"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	FileId      uint64    `json:"fileId"`
+	Name        string    `json:"name"`
+	Storage     string    `json:"-"`
+	Bucket      string    `json:"-"`
+	Path        string    `json:"-"`
+	Filename    string    `json:"-"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	ObjectKey
+}
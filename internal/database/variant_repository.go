@@ -0,0 +1,46 @@
+package database
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// VariantRepository is repository for image variants
+type VariantRepository struct {
+	db *gorm.DB
+}
+
+// NewVariantRepository creates new variant repository
+func NewVariantRepository(db *gorm.DB) *VariantRepository {
+	return &VariantRepository{db}
+}
+
+// Find finds the variant of the file by name
+func (repo *VariantRepository) Find(fileId uint64, name string) (*VariantModel, error) {
+	var variant VariantModel
+	if err := repo.db.Where("file_id = ? AND name = ?", fileId, name).First(&variant).Error; err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// FindByFileID finds all variants of the file
+func (repo *VariantRepository) FindByFileID(fileId uint64) ([]*VariantModel, error) {
+	var variants []*VariantModel
+	if err := repo.db.Where("file_id = ?", fileId).Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// Create creates a new variant
+func (repo *VariantRepository) Create(variant *VariantModel) (*VariantModel, error) {
+	if err := repo.db.Create(variant).Error; err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+// Delete deletes the variant
+func (repo *VariantRepository) Delete(variant *VariantModel) error {
+	return repo.db.Delete(variant).Error
+}
@@ -1,17 +1,17 @@
 package di
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Confialink/wallet-pkg-env_config"
 	"github.com/Confialink/wallet-pkg-env_mods"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/inconshreveable/log15"
 	"github.com/jinzhu/gorm"
 	"github.com/kildevaeld/go-acl"
@@ -19,33 +19,49 @@ import (
 	"github.com/Confialink/wallet-files/internal/auth"
 	"github.com/Confialink/wallet-files/internal/config"
 	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/encryption"
+	"github.com/Confialink/wallet-files/internal/imaging"
+	"github.com/Confialink/wallet-files/internal/inspection"
 	"github.com/Confialink/wallet-files/internal/policy"
+	"github.com/Confialink/wallet-files/internal/scanner"
 	"github.com/Confialink/wallet-files/internal/service"
 	"github.com/Confialink/wallet-files/internal/storage"
+	"github.com/Confialink/wallet-files/internal/validation"
 	files "github.com/Confialink/wallet-files/rpc"
 )
 
 var Container *container
 
 type container struct {
-	appConfig           config.Config
-	dbConnection        *gorm.DB
-	repository          *database.Repository
-	authService         auth.ServiceInterface
-	acl                 *acl.ACL
-	storageS3           *storage.S3
-	storageLocal        *storage.Local
-	storageService      *service.StorageService
-	s3Uploader          *s3manager.Uploader
-	s3Downloader        *s3manager.Downloader
-	s3                  *s3.S3
-	awsCredentials      *credentials.Credentials
-	awsSession          *session.Session
-	awsConfig           *aws.Config
-	pbServer            files.PbServerInterface
-	permissionsService  *policy.PermissionsService
-	usersService        *service.Users
-	serviceLogger       log15.Logger
+	appConfig            config.Config
+	dbConnection         *gorm.DB
+	repository           *database.Repository
+	authService          auth.ServiceInterface
+	acl                  *acl.ACL
+	storages             map[string]storage.Storage
+	storageService       *service.StorageService
+	erasureService       *service.ErasureService
+	exportService        *service.ExportService
+	archiveService       *service.ArchiveService
+	collectionService    *service.CollectionService
+	scanService          *service.ScanService
+	variantService       *service.VariantService
+	combineService       *service.CombineService
+	watermarkService     *service.WatermarkService
+	duplicateService     *service.DuplicateService
+	replicationService   *service.ReplicationService
+	collectionRepository *database.CollectionRepository
+	exportRepository     *database.ExportRepository
+	variantRepository    *database.VariantRepository
+	blobRepository       *database.BlobRepository
+	userKeyRepository    *database.UserKeyRepository
+	keyProvider          encryption.KeyProvider
+	keyService           *service.KeyService
+	residencyService     *service.ResidencyService
+	pbServer             files.PbServerInterface
+	permissionsService   *policy.PermissionsService
+	usersService         *service.Users
+	serviceLogger        log15.Logger
 }
 
 func init() {
@@ -81,105 +97,288 @@ func (c *container) Repository() *database.Repository {
 	return c.repository
 }
 
+// ExportRepository creates new export repository if not exists and return
+func (c *container) ExportRepository() *database.ExportRepository {
+	if nil == c.exportRepository {
+		c.exportRepository = database.NewExportRepository(c.DbConnection())
+	}
+
+	return c.exportRepository
+}
+
+// CollectionRepository creates new collection repository if not exists and return
+func (c *container) CollectionRepository() *database.CollectionRepository {
+	if nil == c.collectionRepository {
+		c.collectionRepository = database.NewCollectionRepository(c.DbConnection())
+	}
+
+	return c.collectionRepository
+}
+
+// VariantRepository creates new variant repository if not exists and return
+func (c *container) VariantRepository() *database.VariantRepository {
+	if nil == c.variantRepository {
+		c.variantRepository = database.NewVariantRepository(c.DbConnection())
+	}
+
+	return c.variantRepository
+}
+
+// BlobRepository creates new blob repository if not exists and return
+func (c *container) BlobRepository() *database.BlobRepository {
+	if nil == c.blobRepository {
+		c.blobRepository = database.NewBlobRepository(c.DbConnection(), c.Config().MasterKeyFile != "")
+	}
+
+	return c.blobRepository
+}
+
 // StorageService creates new storage service if not exists and return
 func (c *container) StorageService() *service.StorageService {
 	if c.storageService == nil {
-		pool := map[string]storage.Storage{
-			storage.StorageS3:    c.StorageS3(),
-			storage.StorageLocal: c.StorageLocal(),
+		pool := make(map[string]storage.Storage)
+		for name := range c.Config().Storages {
+			pool[name] = c.Storage(name)
 		}
 
-		c.storageService = service.NewStorageService(pool, c.Config(), c.Repository())
+		c.storageService = service.NewStorageService(
+			pool,
+			c.Config(),
+			c.Repository(),
+			c.BlobRepository(),
+			c.KeyService(),
+			c.ResidencyService(),
+			c.ServiceLogger().New("service", "StorageService"),
+		)
 	}
 
 	return c.storageService
 }
 
-// StorageLocal creates new s3 storage service if not exists and return
-func (c *container) StorageLocal() *storage.Local {
-	if nil == c.storageLocal {
-		c.storageLocal = storage.NewLocal(
+// UserKeyRepository creates new user key repository if not exists and return
+func (c *container) UserKeyRepository() *database.UserKeyRepository {
+	if nil == c.userKeyRepository {
+		c.userKeyRepository = database.NewUserKeyRepository(c.DbConnection())
+	}
+
+	return c.userKeyRepository
+}
+
+// KeyService creates new key service if not exists and return
+func (c *container) KeyService() *service.KeyService {
+	if c.keyService == nil {
+		c.keyService = service.NewKeyService(c.UserKeyRepository(), c.KeyProvider())
+	}
+
+	return c.keyService
+}
+
+// KeyProvider creates new provider of master keys if not exists and return, it is nil if encryption is disabled
+func (c *container) KeyProvider() encryption.KeyProvider {
+	if c.keyProvider == nil && c.Config().MasterKeyFile != "" {
+		keyFile, err := encryption.NewKeyFile(c.Config().MasterKeyFile)
+		if err != nil {
+			log.Fatalf("Can't read master keys: %v", err)
+		}
+		c.keyProvider = keyFile
+	}
+
+	return c.keyProvider
+}
+
+// ErasureService creates new erasure service if not exists and return
+func (c *container) ErasureService() *service.ErasureService {
+	if c.erasureService == nil {
+		c.erasureService = service.NewErasureService(
 			c.Repository(),
+			c.CollectionRepository(),
+			c.StorageService(),
+			c.ExportService(),
+			c.KeyService(),
+			c.Config(),
+			c.ServiceLogger().New("service", "ErasureService"),
 		)
 	}
 
-	return c.storageLocal
+	return c.erasureService
 }
 
-// StorageS3 creates new s3 storage service if not exists and return
-func (c *container) StorageS3() *storage.S3 {
-	if nil == c.storageS3 {
-		c.storageS3 = storage.NewS3(
-			c.S3Uploader(),
-			c.S3Downloader(),
-			c.S3(),
-			c.Config().AwsConfig,
+// ExportService creates new export service if not exists and return
+func (c *container) ExportService() *service.ExportService {
+	if c.exportService == nil {
+		c.exportService = service.NewExportService(
 			c.Repository(),
+			c.ExportRepository(),
+			c.StorageService(),
+			c.ArchiveService(),
+			c.Config(),
+			c.ServiceLogger().New("service", "ExportService"),
 		)
 	}
 
-	return c.storageS3
+	return c.exportService
 }
 
-// S3Uploader creates new s3 uploader if not exists and return
-func (c *container) S3Uploader() *s3manager.Uploader {
-	if nil == c.s3Uploader {
-		c.s3Uploader = s3manager.NewUploader(c.AwsSession())
+// ArchiveService creates new archive service if not exists and return
+func (c *container) ArchiveService() *service.ArchiveService {
+	if c.archiveService == nil {
+		c.archiveService = service.NewArchiveService(
+			c.StorageService(),
+			c.WatermarkService(),
+			c.ServiceLogger().New("service", "ArchiveService"),
+		)
+	}
+
+	return c.archiveService
+}
+
+// CollectionService creates new collection service if not exists and return
+func (c *container) CollectionService() *service.CollectionService {
+	if c.collectionService == nil {
+		c.collectionService = service.NewCollectionService(
+			c.Repository(),
+			c.CollectionRepository(),
+			c.StorageService(),
+			c.ServiceLogger().New("service", "CollectionService"),
+		)
+	}
+
+	return c.collectionService
+}
+
+// ScanService creates new scan service if not exists and return.
+// Uploaded files are scanned only if clamd address is configured.
+func (c *container) ScanService() *service.ScanService {
+	if c.scanService == nil {
+		var fileScanner scanner.Scanner
+		if c.Config().ClamdAddress != "" {
+			clamd, err := scanner.NewClamd(c.Config().ClamdAddress, time.Minute)
+			if err != nil {
+				log.Fatalf("invalid VELMIE_WALLET_FILES_CLAMD_ADDRESS: %v", err)
+			}
+			fileScanner = clamd
+		}
+
+		c.scanService = service.NewScanService(
+			c.Repository(),
+			c.StorageService(),
+			fileScanner,
+			c.ServiceLogger().New("service", "ScanService"),
+		)
+		if c.scanService.Enabled() {
+			c.StorageService().AddUploadListener(c.scanService)
+		}
 	}
 
-	return c.s3Uploader
+	return c.scanService
 }
 
-// S3Downloader creates new s3 downloader if not exists and return
-func (c *container) S3Downloader() *s3manager.Downloader {
-	if nil == c.s3Downloader {
-		c.s3Downloader = s3manager.NewDownloader(c.AwsSession())
+// VariantService creates new variant service if not exists and return.
+// Images are converted into webp only if cwebp is installed.
+func (c *container) VariantService() *service.VariantService {
+	if c.variantService == nil {
+		logger := c.ServiceLogger().New("service", "VariantService")
+		webp, err := imaging.NewWebpEncoder(c.Config().CwebpPath, time.Minute)
+		if err != nil {
+			logger.Warn("cwebp is not found, conversion into webp is disabled", "err", err)
+		}
+
+		c.variantService = service.NewVariantService(
+			c.VariantRepository(),
+			c.StorageService(),
+			webp,
+			c.Config(),
+			logger,
+		)
+		c.StorageService().AddDeleteListener(c.variantService)
 	}
 
-	return c.s3Downloader
+	return c.variantService
 }
 
-// S3 creates new s3 instance if not exists and return
-func (c *container) S3() *s3.S3 {
-	if nil == c.s3 {
-		c.s3 = s3.New(c.AwsSession())
+// CombineService creates new combine service if not exists and return
+func (c *container) CombineService() *service.CombineService {
+	if c.combineService == nil {
+		c.combineService = service.NewCombineService(
+			c.StorageService(),
+			c.ServiceLogger().New("service", "CombineService"),
+		)
 	}
 
-	return c.s3
+	return c.combineService
 }
 
-// AwsCredentials creates new aws credentials if not exists and return
-func (c *container) AwsCredentials() *credentials.Credentials {
-	if nil == c.awsCredentials {
-		c.awsCredentials = credentials.NewEnvCredentials()
+// WatermarkService creates new watermark service if not exists and return
+func (c *container) WatermarkService() *service.WatermarkService {
+	if c.watermarkService == nil {
+		c.watermarkService = service.NewWatermarkService(c.StorageService(), c.Config())
 	}
 
-	return c.awsCredentials
+	return c.watermarkService
 }
 
-// AwsSession creates new aws session if not exists and return
-func (c *container) AwsSession() *session.Session {
-	if nil == c.awsSession {
-		c.awsSession, _ = session.NewSession(c.AwsConfig())
+// DuplicateService creates new duplicate service if not exists and return
+func (c *container) DuplicateService() *service.DuplicateService {
+	if c.duplicateService == nil {
+		c.duplicateService = service.NewDuplicateService(
+			c.Repository(),
+			c.StorageService(),
+			c.Config(),
+			c.ServiceLogger().New("service", "DuplicateService"),
+		)
+		c.StorageService().AddUploadListener(c.duplicateService)
 	}
 
-	return c.awsSession
+	return c.duplicateService
 }
 
-func (c *container) AwsConfig() *aws.Config {
-	if nil == c.awsConfig {
-		c.awsConfig = &aws.Config{
-			Region:      aws.String(c.Config().AwsConfig.Region),
-			Credentials: c.AwsCredentials(),
+// ReplicationService creates new replication service if not exists and return
+func (c *container) ReplicationService() *service.ReplicationService {
+	if c.replicationService == nil {
+		c.replicationService = service.NewReplicationService(
+			c.Repository(),
+			c.StorageService(),
+			c.ResidencyService(),
+			c.Config(),
+			c.ServiceLogger().New("service", "ReplicationService"),
+		)
+		if c.replicationService.Enabled() {
+			c.StorageService().AddUploadListener(c.replicationService)
 		}
 	}
-	return c.awsConfig
+
+	return c.replicationService
+}
+
+// Storage creates new storage of VELMIE_WALLET_FILES_STORAGES by the driver of its type if not exists and return
+func (c *container) Storage(name string) storage.Storage {
+	if nil == c.storages {
+		c.storages = make(map[string]storage.Storage)
+	}
+	if st, ok := c.storages[name]; ok {
+		return st
+	}
+
+	st, err := storage.Open(name, c.Config().Storages[name], c.Repository())
+	if err != nil {
+		log.Fatalf("Can't create storage %q: %v", name, err)
+	}
+	c.storages[name] = st
+	return st
 }
 
 // PbServer creates new proto buf server if not exists and return
 func (c *container) PbServer() files.PbServerInterface {
 	if nil == c.pbServer {
-		c.pbServer = files.NewPbServer(c.Repository(), c.Config(), c.StorageService())
+		c.pbServer = files.NewPbServer(
+			c.Repository(),
+			c.Config(),
+			c.StorageService(),
+			c.ErasureService(),
+			c.ExportService(),
+			c.CombineService(),
+			c.DuplicateService(),
+		)
 	}
 
 	return c.pbServer
@@ -202,6 +401,15 @@ func (c *container) PermissionsService() *policy.PermissionsService {
 	return c.permissionsService
 }
 
+// ResidencyService creates new residency service if not exists and return
+func (c *container) ResidencyService() *service.ResidencyService {
+	if nil == c.residencyService {
+		c.residencyService = service.NewResidencyService(c.UsersService(), c.Config())
+	}
+
+	return c.residencyService
+}
+
 func (c *container) UsersService() *service.Users {
 	if nil == c.usersService {
 		c.usersService = service.NewUsers()
@@ -233,6 +441,25 @@ func readConfig(cfg *config.Config) {
 	cfg.Env = env_config.Env("ENV", env_mods.Development)
 	cfg.Storage = os.Getenv("VELMIE_WALLET_FILES_STORAGE")
 	cfg.AwsConfig = readAwsConfig()
+	cfg.RetentionPeriods = readRetentionPeriods()
+	cfg.Export = readExportConfig()
+	cfg.ClamdAddress = os.Getenv("VELMIE_WALLET_FILES_CLAMD_ADDRESS")
+	cfg.ScanBlockUnscanned = readScanBlockUnscanned()
+	cfg.AllowedTypes = readAllowedTypes()
+	cfg.Inspection = readInspectionConfig()
+	cfg.StripMetadata = readStripMetadata()
+	cfg.CwebpPath = env_config.Env("VELMIE_WALLET_FILES_CWEBP_PATH", "cwebp")
+	cfg.VariantSizes = readPositiveInts("VELMIE_WALLET_FILES_VARIANT_SIZES", []int{480, 1024, 1600, 2400}, 4000)
+	cfg.VariantQualities = readPositiveInts("VELMIE_WALLET_FILES_VARIANT_QUALITIES", []int{60, 85}, 100)
+	cfg.WatermarkCategories = readWatermarkCategories()
+	cfg.DuplicateAlertSimilarity = readDuplicateAlertSimilarity()
+	cfg.MasterKeyFile = os.Getenv("VELMIE_WALLET_FILES_MASTER_KEY_FILE")
+	cfg.Storages = readStorages(cfg.AwsConfig)
+	cfg.StorageTargets = readStorageTargets(cfg.Storage, cfg.Storages)
+	cfg.DefaultTarget = readDefaultTarget(cfg.StorageTargets)
+	cfg.StorageRoutes = readStorageRoutes(cfg.StorageTargets)
+	cfg.Residency = readResidency(cfg.StorageTargets)
+	cfg.ReplicaTarget = readReplicaTarget(cfg.StorageTargets)
 
 	defaultConfigReader := env_config.NewReader("files")
 	cfg.Cors = defaultConfigReader.ReadCorsConfig()
@@ -245,7 +472,6 @@ func validateConfig(cfg *config.Config, logger log15.Logger) {
 	validator.ValidateDb(cfg.Db, logger)
 	validator.CriticalIfEmpty(cfg.Port, "VELMIE_WALLET_FILES_PORT", logger)
 	validator.CriticalIfEmpty(cfg.ProtoBufPort, "VELMIE_WALLET_FILES_PROTO_BUF_PORT", logger)
-	validator.CriticalIfEmpty(cfg.Storage, "VELMIE_WALLET_FILES_STORAGE", logger)
 }
 
 // readAwsConfig reads AWS configs from ENV variables
@@ -256,3 +482,368 @@ func readAwsConfig() config.AwsConfig {
 	}
 	return awsConfig
 }
+
+// readExportConfig reads export configs from ENV variables
+func readExportConfig() config.ExportConfig {
+	return config.ExportConfig{
+		StreamLimit: int64(readPositiveInt("VELMIE_WALLET_FILES_EXPORT_STREAM_LIMIT_MB", 100)) << 20,
+		TTL:         time.Duration(readPositiveInt("VELMIE_WALLET_FILES_EXPORT_TTL_HOURS", 72)) * time.Hour,
+	}
+}
+
+// readInspectionConfig reads document inspection configs from ENV variables
+func readInspectionConfig() config.InspectionConfig {
+	mode := os.Getenv("VELMIE_WALLET_FILES_INSPECTION_MODE")
+	if mode != "" && mode != "reject" && mode != "flag" {
+		log.Fatalf("invalid value %q in VELMIE_WALLET_FILES_INSPECTION_MODE", mode)
+	}
+
+	return config.InspectionConfig{
+		Reject: mode == "reject",
+		Limits: inspection.Limits{
+			MaxPages:     readPositiveInt("VELMIE_WALLET_FILES_PDF_MAX_PAGES", 0),
+			MinImageSide: readPositiveInt("VELMIE_WALLET_FILES_IMAGE_MIN_SIDE", 0),
+			MaxImageSide: readPositiveInt("VELMIE_WALLET_FILES_IMAGE_MAX_SIDE", 0),
+			// compressed streams are expanded in memory of the upload request
+			MaxDecompressedSize: int64(readPositiveInt("VELMIE_WALLET_FILES_PDF_MAX_DECOMPRESSED_MB", 50)) << 20,
+		},
+	}
+}
+
+// readScanBlockUnscanned reads whether unscanned files are blocked, they are blocked by default
+func readScanBlockUnscanned() bool {
+	value := os.Getenv("VELMIE_WALLET_FILES_SCAN_BLOCK_UNSCANNED")
+	if value == "" {
+		return true
+	}
+
+	block, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("invalid value %q in VELMIE_WALLET_FILES_SCAN_BLOCK_UNSCANNED", value)
+	}
+	return block
+}
+
+// readPositiveInt reads a positive integer from ENV variable or returns the default value
+func readPositiveInt(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i <= 0 {
+		log.Fatalf("invalid value %q in %s", value, name)
+	}
+	return i
+}
+
+// readPositiveInts reads a comma separated list of positive integers up to max from ENV variable and sorts it,
+// the default values are returned if it is empty
+func readPositiveInts(name string, defaultValues []int, max int) []int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValues
+	}
+
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || i <= 0 || i > max {
+			log.Fatalf("invalid value %q in %s", value, name)
+		}
+		values = append(values, i)
+	}
+	sort.Ints(values)
+	return values
+}
+
+// readAllowedTypes reads allowed file extensions per category
+// e.g. VELMIE_WALLET_FILES_ALLOWED_TYPES=kyc:jpg|png|pdf,default:jpg|png|pdf|docx
+func readAllowedTypes() map[string][]string {
+	allowed := make(map[string][]string)
+	value := os.Getenv("VELMIE_WALLET_FILES_ALLOWED_TYPES")
+	if value == "" {
+		return allowed
+	}
+
+	for _, rule := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(rule), ":")
+		if len(parts) != 2 || parts[1] == "" {
+			log.Fatalf("invalid allowed types %q in VELMIE_WALLET_FILES_ALLOWED_TYPES", rule)
+		}
+		for _, ext := range strings.Split(parts[1], "|") {
+			ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+			if !validation.Known(ext) {
+				log.Fatalf("unsupported file type %q in VELMIE_WALLET_FILES_ALLOWED_TYPES", ext)
+			}
+			allowed[parts[0]] = append(allowed[parts[0]], ext)
+		}
+	}
+	return allowed
+}
+
+// readStripMetadata reads per category whether metadata is stripped, it is stripped for all files by default
+// e.g. VELMIE_WALLET_FILES_STRIP_METADATA=kyc:true,default:false
+func readStripMetadata() map[string]bool {
+	strip := map[string]bool{config.DefaultCategory: true}
+	value := os.Getenv("VELMIE_WALLET_FILES_STRIP_METADATA")
+	if value == "" {
+		return strip
+	}
+
+	for _, rule := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(rule), ":")
+		if len(parts) != 2 {
+			log.Fatalf("invalid rule %q in VELMIE_WALLET_FILES_STRIP_METADATA", rule)
+		}
+		enabled, err := strconv.ParseBool(parts[1])
+		if err != nil {
+			log.Fatalf("invalid rule %q in VELMIE_WALLET_FILES_STRIP_METADATA", rule)
+		}
+		strip[parts[0]] = enabled
+	}
+	return strip
+}
+
+// readWatermarkCategories reads categories of files which are watermarked when downloaded by admins
+// e.g. VELMIE_WALLET_FILES_WATERMARK_CATEGORIES=kyc,contract
+func readWatermarkCategories() map[string]bool {
+	categories := make(map[string]bool)
+	value := os.Getenv("VELMIE_WALLET_FILES_WATERMARK_CATEGORIES")
+	if value == "" {
+		return categories
+	}
+
+	for _, category := range strings.Split(value, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			categories[category] = true
+		}
+	}
+	return categories
+}
+
+// readDuplicateAlertSimilarity reads the similarity of files of different users which is logged as a duplicate
+// e.g. VELMIE_WALLET_FILES_DUPLICATE_ALERT_SIMILARITY=0.9
+func readDuplicateAlertSimilarity() float64 {
+	value := os.Getenv("VELMIE_WALLET_FILES_DUPLICATE_ALERT_SIMILARITY")
+	if value == "" {
+		return 0
+	}
+
+	similarity, err := strconv.ParseFloat(value, 64)
+	if err != nil || similarity < 0 || similarity > 1 {
+		log.Fatalf("invalid value %q in VELMIE_WALLET_FILES_DUPLICATE_ALERT_SIMILARITY", value)
+	}
+	return similarity
+}
+
+// readRetentionPeriods reads retention periods in days per category
+// e.g. VELMIE_WALLET_FILES_RETENTION_PERIODS=kyc:1825,statement:3650
+func readRetentionPeriods() map[string]time.Duration {
+	periods := make(map[string]time.Duration)
+	value := os.Getenv("VELMIE_WALLET_FILES_RETENTION_PERIODS")
+	if value == "" {
+		return periods
+	}
+
+	for _, rule := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(rule), ":")
+		if len(parts) != 2 {
+			log.Fatalf("invalid retention period %q in VELMIE_WALLET_FILES_RETENTION_PERIODS", rule)
+		}
+		days, err := strconv.Atoi(parts[1])
+		if err != nil || days < 0 {
+			log.Fatalf("invalid retention period %q in VELMIE_WALLET_FILES_RETENTION_PERIODS", rule)
+		}
+		periods[parts[0]] = time.Duration(days) * 24 * time.Hour
+	}
+	return periods
+}
+
+func readStorages(awsConfig config.AwsConfig) map[string]config.StorageConfig {
+	storages := make(map[string]config.StorageConfig)
+	if value := os.Getenv("VELMIE_WALLET_FILES_STORAGES"); value != "" {
+		if err := json.Unmarshal([]byte(value), &storages); err != nil {
+			log.Fatalf("invalid value in VELMIE_WALLET_FILES_STORAGES: %v", err)
+		}
+	}
+	// files uploaded before storages were configurable reference these storages
+	if _, ok := storages[storage.StorageS3]; !ok {
+		storages[storage.StorageS3] = config.StorageConfig{Type: storage.StorageS3, Bucket: awsConfig.S3Bucket}
+	}
+	if _, ok := storages[storage.StorageLocal]; !ok {
+		storages[storage.StorageLocal] = config.StorageConfig{Type: storage.StorageLocal, Prefix: storage.StorageDir}
+	}
+
+	for name, cfg := range storages {
+		if !storage.Registered(cfg.Type) {
+			log.Fatalf("unknown type %q of storage %q in VELMIE_WALLET_FILES_STORAGES", cfg.Type, name)
+		}
+		switch cfg.Type {
+		case storage.StorageS3:
+			if cfg.Root != "" {
+				log.Fatalf("s3 storage %q in VELMIE_WALLET_FILES_STORAGES can't have root", name)
+			}
+			if cfg.Region == "" {
+				cfg.Region = awsConfig.Region
+			}
+			if cfg.ServerSideEncryption == "" && cfg.Endpoint == "" {
+				cfg.ServerSideEncryption = "AES256"
+			}
+		case storage.StorageAzure:
+			if cfg.AccessKey == "" || cfg.SecretKey == "" {
+				log.Fatalf("azure storage %q in VELMIE_WALLET_FILES_STORAGES must have account name and key", name)
+			}
+			if cfg.Root != "" || cfg.Region != "" || cfg.PathStyle || cfg.ServerSideEncryption != "" {
+				log.Fatalf("azure storage %q in VELMIE_WALLET_FILES_STORAGES can't have root, region, path style or server side encryption", name)
+			}
+		case storage.StorageLocal:
+			if cfg.Bucket != "" || cfg.Endpoint != "" || cfg.Region != "" || cfg.PathStyle ||
+				cfg.AccessKey != "" || cfg.SecretKey != "" || cfg.ServerSideEncryption != "" {
+				log.Fatalf("local storage %q in VELMIE_WALLET_FILES_STORAGES can only have root and prefix", name)
+			}
+		}
+		storages[name] = cfg
+	}
+	return storages
+}
+
+// readStorageTargets reads targets and adds storages of s3 targets in other regions
+func readStorageTargets(defaultStorage string, storages map[string]config.StorageConfig) map[string]config.StorageTarget {
+	targets := make(map[string]config.StorageTarget)
+	if value := os.Getenv("VELMIE_WALLET_FILES_STORAGE_TARGETS"); value != "" {
+		if err := json.Unmarshal([]byte(value), &targets); err != nil {
+			log.Fatalf("invalid value in VELMIE_WALLET_FILES_STORAGE_TARGETS: %v", err)
+		}
+	}
+	if _, ok := targets[config.DefaultStorageTarget]; !ok && defaultStorage != "" {
+		targets[config.DefaultStorageTarget] = config.StorageTarget{Storage: defaultStorage}
+	}
+
+	buckets := make(map[string]string)
+	for name, target := range targets {
+		cfg, ok := storages[target.Storage]
+		if !ok {
+			log.Fatalf("unknown storage %q of target %q in VELMIE_WALLET_FILES_STORAGE_TARGETS", target.Storage, name)
+		}
+
+		switch cfg.Type {
+		case storage.StorageS3:
+			if target.Region != "" && target.Region != cfg.Region {
+				if target.Bucket == "" {
+					log.Fatalf("s3 storage target %q in VELMIE_WALLET_FILES_STORAGE_TARGETS of region %q must have a bucket", name, target.Region)
+				}
+				// buckets of other regions are accessed by a storage with a session of the region
+				regional := storage.Regional(target.Storage, target.Region)
+				if _, ok := storages[regional]; !ok {
+					cfg.Region, cfg.Bucket = target.Region, ""
+					storages[regional] = cfg
+				}
+				target.Storage = regional
+			}
+		case storage.StorageLocal:
+			if target.Bucket != "" || target.ACL != "" || target.StorageClass != "" || target.Region != "" {
+				log.Fatalf("local storage target %q in VELMIE_WALLET_FILES_STORAGE_TARGETS can't have bucket, acl, storage class or region", name)
+			}
+		default:
+			if target.Region != "" {
+				log.Fatalf("target %q in VELMIE_WALLET_FILES_STORAGE_TARGETS can't have region, it is configured per storage", name)
+			}
+			if cfg.Type == storage.StorageAzure && target.ACL != "" {
+				log.Fatalf("azure target %q in VELMIE_WALLET_FILES_STORAGE_TARGETS can't have acl, access is configured per container", name)
+			}
+		}
+		if target.Bucket == "" {
+			target.Bucket = cfg.Bucket
+		}
+		targets[name] = target
+
+		// content is deduplicated per bucket, so options of objects in a bucket must be the same
+		bucket := target.Storage + "/" + target.Bucket
+		if other, ok := buckets[bucket]; ok {
+			log.Fatalf("targets %q and %q in VELMIE_WALLET_FILES_STORAGE_TARGETS use the same bucket", other, name)
+		}
+		buckets[bucket] = name
+	}
+	return targets
+}
+
+func readDefaultTarget(targets map[string]config.StorageTarget) string {
+	name := env_config.Env("VELMIE_WALLET_FILES_DEFAULT_TARGET", config.DefaultStorageTarget)
+	if _, ok := targets[name]; !ok {
+		log.Fatalf("default target %q is not configured, set VELMIE_WALLET_FILES_STORAGE or configure the target in VELMIE_WALLET_FILES_STORAGE_TARGETS", name)
+	}
+	return name
+}
+
+func readStorageRoutes(targets map[string]config.StorageTarget) []config.StorageRoute {
+	var routes []config.StorageRoute
+	value := os.Getenv("VELMIE_WALLET_FILES_STORAGE_ROUTES")
+	if value == "" {
+		return routes
+	}
+
+	if err := json.Unmarshal([]byte(value), &routes); err != nil {
+		log.Fatalf("invalid value in VELMIE_WALLET_FILES_STORAGE_ROUTES: %v", err)
+	}
+	for _, route := range routes {
+		if _, ok := targets[route.Target]; !ok {
+			log.Fatalf("unknown target %q in VELMIE_WALLET_FILES_STORAGE_ROUTES", route.Target)
+		}
+		switch route.Visibility {
+		case "", service.VisibilityPublic, service.VisibilityPrivate, service.VisibilityAdminOnly:
+		default:
+			log.Fatalf("invalid visibility %q in VELMIE_WALLET_FILES_STORAGE_ROUTES", route.Visibility)
+		}
+	}
+	return routes
+}
+
+func readReplicaTarget(targets map[string]config.StorageTarget) string {
+	name := os.Getenv("VELMIE_WALLET_FILES_REPLICA_TARGET")
+	if name == "" {
+		return ""
+	}
+	if _, ok := targets[name]; !ok {
+		log.Fatalf("unknown target %q in VELMIE_WALLET_FILES_REPLICA_TARGET", name)
+	}
+	return name
+}
+
+func readResidency(targets map[string]config.StorageTarget) map[string]config.ResidencyRegion {
+	residency := make(map[string]config.ResidencyRegion)
+	value := os.Getenv("VELMIE_WALLET_FILES_RESIDENCY")
+	if value == "" {
+		return residency
+	}
+
+	if err := json.Unmarshal([]byte(value), &residency); err != nil {
+		log.Fatalf("invalid value in VELMIE_WALLET_FILES_RESIDENCY: %v", err)
+	}
+	countries := make(map[string]string)
+	for name, region := range residency {
+		if _, ok := targets[region.Target]; !ok {
+			log.Fatalf("unknown target %q of region %q in VELMIE_WALLET_FILES_RESIDENCY", region.Target, name)
+		}
+		if region.ReplicaTarget != "" {
+			replica, ok := targets[region.ReplicaTarget]
+			if !ok {
+				log.Fatalf("unknown replica target %q of region %q in VELMIE_WALLET_FILES_RESIDENCY", region.ReplicaTarget, name)
+			}
+			if target := targets[region.Target]; replica.Storage == target.Storage && replica.Bucket == target.Bucket {
+				log.Fatalf("replica target of region %q in VELMIE_WALLET_FILES_RESIDENCY uses the bucket of its target", name)
+			}
+		}
+		if len(region.Countries) == 0 {
+			region.Countries = []string{""}
+		}
+		for _, country := range region.Countries {
+			if other, ok := countries[country]; ok {
+				log.Fatalf("regions %q and %q in VELMIE_WALLET_FILES_RESIDENCY overlap", other, name)
+			}
+			countries[country] = name
+		}
+	}
+	return residency
+}
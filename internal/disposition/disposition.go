@@ -0,0 +1,37 @@
+package disposition
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attachment returns Content-Disposition header of a download named filename. The quoted filename is an ASCII
+// fallback for old clients, filename* carries the name in UTF-8 as described in RFC 5987 and RFC 6266.
+func Attachment(filename string) string {
+	var fallback, encoded strings.Builder
+	for _, r := range filename {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' {
+			fallback.WriteByte('_')
+		} else {
+			fallback.WriteRune(r)
+		}
+	}
+	for _, c := range []byte(filename) {
+		if isAttrChar(c) {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+
+	return `attachment; filename="` + fallback.String() + `"; filename*=UTF-8''` + encoded.String()
+}
+
+// isAttrChar checks if the byte may be left unescaped in an extended parameter value
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
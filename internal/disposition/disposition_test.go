@@ -0,0 +1,28 @@
+package disposition
+
+import "testing"
+
+func TestAttachment(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		expected string
+	}{
+		{"plain", "report.pdf", `attachment; filename="report.pdf"; filename*=UTF-8''report.pdf`},
+		{"space", "my report.pdf", `attachment; filename="my report.pdf"; filename*=UTF-8''my%20report.pdf`},
+		{"quote", `a"b.txt`, `attachment; filename="a_b.txt"; filename*=UTF-8''a%22b.txt`},
+		{"backslash", `a\b.txt`, `attachment; filename="a_b.txt"; filename*=UTF-8''a%5Cb.txt`},
+		{"line break", "a\r\nb.txt", `attachment; filename="a__b.txt"; filename*=UTF-8''a%0D%0Ab.txt`},
+		{"semicolon", "a;b.txt", `attachment; filename="a;b.txt"; filename*=UTF-8''a%3Bb.txt`},
+		{"unicode", "фото.jpg", `attachment; filename="____.jpg"; filename*=UTF-8''%D1%84%D0%BE%D1%82%D0%BE.jpg`},
+		{"invalid utf-8", "a\xffb", `attachment; filename="a_b"; filename*=UTF-8''a%FFb`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := Attachment(tt.filename); actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
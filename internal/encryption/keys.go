@@ -0,0 +1,146 @@
+package encryption
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrUnknownKey means the data key was wrapped with a master key the provider does not have
+var ErrUnknownKey = errors.New("unknown master key")
+
+// KeyProvider wraps data keys with master keys. Master keys never leave the provider,
+// so it may be backed by a local key file as well as by a KMS or Vault.
+type KeyProvider interface {
+	// CurrentKeyId returns id of the master key which wraps new data keys
+	CurrentKeyId() string
+	// Wrap encrypts the data key with the current master key and returns the id of the master key
+	Wrap(dataKey []byte) (wrapped []byte, keyId string, err error)
+	// Unwrap decrypts the data key with the master key it was wrapped with
+	Unwrap(wrapped []byte, keyId string) ([]byte, error)
+}
+
+// KeyFile keeps master keys in a local file. Every line of the file is "<id>:<base64 encoded 32 byte key>",
+// empty lines and lines starting with # are ignored. The last key is current, older keys only unwrap data keys.
+type KeyFile struct {
+	keys    map[string]cipher.AEAD
+	current string
+}
+
+// NewKeyFile reads master keys from the file
+func NewKeyFile(path string) (*KeyFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	kf := &KeyFile{keys: make(map[string]cipher.AEAD)}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid master key on line %d", line)
+		}
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil || len(key) != KeySize {
+			return nil, fmt.Errorf("invalid master key on line %d", line)
+		}
+		if _, ok := kf.keys[parts[0]]; ok {
+			return nil, fmt.Errorf("duplicate master key id %q", parts[0])
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if kf.keys[parts[0]], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+		kf.current = parts[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if kf.current == "" {
+		return nil, errors.New("no master keys in the key file")
+	}
+
+	return kf, nil
+}
+
+// CurrentKeyId returns id of the last key in the file
+func (kf *KeyFile) CurrentKeyId() string {
+	return kf.current
+}
+
+// Wrap encrypts the data key with the current master key, the id of the key is authenticated as well
+func (kf *KeyFile) Wrap(dataKey []byte) ([]byte, string, error) {
+	aead := kf.keys[kf.current]
+	n := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, n); err != nil {
+		return nil, "", err
+	}
+
+	return aead.Seal(n, n, dataKey, []byte(kf.current)), kf.current, nil
+}
+
+// Unwrap decrypts the data key with the master key
+func (kf *KeyFile) Unwrap(wrapped []byte, keyId string) ([]byte, error) {
+	aead, ok := kf.keys[keyId]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrCorrupt
+	}
+
+	dataKey, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(keyId))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return dataKey, nil
+}
+
+// WrapKey encrypts the data key with the key-encryption key, aad is authenticated with it
+func WrapKey(kek []byte, dataKey []byte, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, n); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(n, n, dataKey, aad), nil
+}
+
+// UnwrapKey decrypts the data key wrapped with WrapKey
+func UnwrapKey(kek []byte, wrapped []byte, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrCorrupt
+	}
+
+	dataKey, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return dataKey, nil
+}
@@ -0,0 +1,123 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newKeyFile(t *testing.T, content string) (*KeyFile, error) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keys")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return NewKeyFile(path)
+}
+
+func TestNewKeyFileMalformed(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, KeySize))
+	short := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, KeySize-1))
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"empty", ""},
+		{"comments only", "# no keys\n\n"},
+		{"no separator", key + "\n"},
+		{"empty id", ":" + key + "\n"},
+		{"invalid base64", "k1:not base64!\n"},
+		{"short key", "k1:" + short + "\n"},
+		{"duplicate id", "k1:" + key + "\nk1:" + key + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newKeyFile(t, tt.content); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestKeyFileWrap(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, KeySize))
+	k2 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, KeySize))
+	kf, err := newKeyFile(t, "# keys\nk1:"+k1+"\n\nk2:"+k2+"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kf.CurrentKeyId() != "k2" {
+		t.Fatalf("expected current key k2, got %s", kf.CurrentKeyId())
+	}
+
+	dataKey, _ := GenerateKey()
+	wrapped, keyId, err := kf.Wrap(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyId != "k2" {
+		t.Fatalf("expected key k2, got %s", keyId)
+	}
+	unwrapped, err := kf.Unwrap(wrapped, keyId)
+	if err != nil || !bytes.Equal(unwrapped, dataKey) {
+		t.Fatalf("expected the data key, got %x, %v", unwrapped, err)
+	}
+
+	if _, err = kf.Unwrap(wrapped, "k3"); err != ErrUnknownKey {
+		t.Errorf("unknown key: expected ErrUnknownKey, got %v", err)
+	}
+	if _, err = kf.Unwrap(wrapped, "k1"); err != ErrCorrupt {
+		t.Errorf("other key: expected ErrCorrupt, got %v", err)
+	}
+	if _, err = kf.Unwrap(wrapped[:5], "k2"); err != ErrCorrupt {
+		t.Errorf("short: expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestUnwrapKeyMalformed(t *testing.T) {
+	kek, _ := GenerateKey()
+	dataKey, _ := GenerateKey()
+	wrapped, err := WrapKey(kek, dataKey, []byte("uid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modified := append([]byte{}, wrapped...)
+	modified[len(modified)-1] ^= 1
+	otherKek, _ := GenerateKey()
+
+	tests := []struct {
+		name    string
+		kek     []byte
+		wrapped []byte
+		aad     string
+	}{
+		{"empty", kek, nil, "uid"},
+		{"shorter than nonce", kek, wrapped[:4], "uid"},
+		{"modified", kek, modified, "uid"},
+		{"other aad", kek, wrapped, "other"},
+		{"other key", otherKek, wrapped, "uid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnwrapKey(tt.kek, tt.wrapped, []byte(tt.aad)); err != ErrCorrupt {
+				t.Errorf("expected ErrCorrupt, got %v", err)
+			}
+		})
+	}
+
+	if out, err := UnwrapKey(kek, wrapped, []byte("uid")); err != nil || !bytes.Equal(out, dataKey) {
+		t.Errorf("expected the data key, got %x, %v", out, err)
+	}
+}
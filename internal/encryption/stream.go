@@ -0,0 +1,239 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// KeySize is the size of AES-256 data keys
+const KeySize = 32
+
+// ChunkSize is the size of plaintext chunks which are sealed separately, so content can be decrypted while it is read
+const ChunkSize = 64 << 10
+
+// noncePrefixSize is the size of the random part of chunk nonces, the rest is the chunk counter and the last chunk flag
+const noncePrefixSize = 7
+
+// magic starts encrypted content and identifies the format version
+var magic = []byte("VWE1")
+
+// ErrCorrupt means the encrypted content was modified, truncated or the key is wrong
+var ErrCorrupt = errors.New("encrypted content is corrupt")
+
+// GenerateKey returns a random data key
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt encrypts the content with the data key
+func Encrypt(b []byte, key []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(b); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt decrypts the content encrypted with the data key
+func Decrypt(b []byte, key []byte) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(b), key)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err = io.Copy(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writer seals the content in chunks with AES-GCM. Every chunk nonce contains the chunk counter and
+// whether the chunk is the last one, so chunks can't be reordered, dropped or truncated unnoticed.
+type writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	buf     []byte
+	counter uint32
+	closed  bool
+}
+
+// NewWriter returns a writer which encrypts the content with the data key, it must be closed to write the last chunk
+func NewWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(append([]byte{}, magic...), prefix...)); err != nil {
+		return nil, err
+	}
+
+	return &writer{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, ChunkSize)}, nil
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed encryption writer")
+	}
+
+	n := len(p)
+	for len(p) > 0 {
+		free := ChunkSize - len(w.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buf = append(w.buf, p[:free]...)
+		p = p[free:]
+
+		// full chunks are sealed as soon as they fill up, Close seals the remainder as the last chunk
+		// which is shorter than a full chunk and may be empty
+		if len(w.buf) == ChunkSize {
+			if err := w.seal(false); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close writes the last chunk which may be empty
+func (w *writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+func (w *writer) seal(last bool) error {
+	if w.counter == math.MaxUint32 {
+		return errors.New("content is too large to encrypt")
+	}
+
+	sealed := w.aead.Seal(nil, nonce(w.prefix, w.counter, last), w.buf, nil)
+	w.counter++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+type reader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	sealed  []byte
+	plain   []byte
+	counter uint32
+	done    bool
+}
+
+// NewReader returns a reader which decrypts the content encrypted with the data key.
+// ErrCorrupt is returned by Read if the content was modified or truncated.
+func NewReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(magic)+noncePrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrCorrupt
+		}
+		return nil, err
+	}
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return nil, ErrCorrupt
+	}
+
+	return &reader{
+		r:      r,
+		aead:   aead,
+		prefix: header[len(magic):],
+		sealed: make([]byte, ChunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// open decrypts the next chunk, a chunk shorter than the full size is the last one
+func (r *reader) open() error {
+	n, err := io.ReadFull(r.r, r.sealed)
+	last := false
+	switch err {
+	case nil:
+	case io.ErrUnexpectedEOF:
+		last = true
+	case io.EOF:
+		// the content ends before the last chunk
+		return ErrCorrupt
+	default:
+		return err
+	}
+
+	plain, err := r.aead.Open(r.sealed[:0], nonce(r.prefix, r.counter, last), r.sealed[:n], nil)
+	if err != nil {
+		return ErrCorrupt
+	}
+	r.counter++
+	r.plain = plain
+	r.done = last
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("invalid data key size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(prefix []byte, counter uint32, last bool) []byte {
+	n := make([]byte, 12)
+	copy(n, prefix)
+	binary.BigEndian.PutUint32(n[noncePrefixSize:], counter)
+	if last {
+		n[11] = 1
+	}
+	return n
+}
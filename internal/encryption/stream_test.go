@@ -0,0 +1,101 @@
+package encryption
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 2 * ChunkSize, 3*ChunkSize + 7} {
+		plain := bytes.Repeat([]byte{byte(size)}, size)
+		sealed, err := Encrypt(plain, key)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		out, err := Decrypt(sealed, key)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(out, plain) {
+			t.Errorf("size %d: decrypted content differs", size)
+		}
+	}
+}
+
+func TestDecryptMalformed(t *testing.T) {
+	key, _ := GenerateKey()
+	otherKey, _ := GenerateKey()
+	sealed, err := Encrypt(bytes.Repeat([]byte("a"), 2*ChunkSize+100), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := len(magic) + noncePrefixSize
+	chunk := ChunkSize + 16
+
+	flip := func(i int) []byte {
+		b := append([]byte{}, sealed...)
+		b[i] ^= 1
+		return b
+	}
+	swapped := append([]byte{}, sealed[:header]...)
+	swapped = append(swapped, sealed[header+chunk:header+2*chunk]...)
+	swapped = append(swapped, sealed[header:header+chunk]...)
+	swapped = append(swapped, sealed[header+2*chunk:]...)
+
+	tests := []struct {
+		name string
+		b    []byte
+		key  []byte
+	}{
+		{"empty", nil, key},
+		{"short header", sealed[:header-1], key},
+		{"header only", sealed[:header], key},
+		{"wrong magic", flip(0), key},
+		{"modified nonce prefix", flip(len(magic)), key},
+		{"modified first chunk", flip(header + 10), key},
+		{"modified last chunk", flip(len(sealed) - 1), key},
+		{"truncated at chunk boundary", sealed[:header+2*chunk], key},
+		{"truncated in the last chunk", sealed[:len(sealed)-1], key},
+		{"truncated in a full chunk", sealed[:header+chunk+10], key},
+		{"swapped chunks", swapped, key},
+		{"appended data", append(append([]byte{}, sealed...), 0), key},
+		{"wrong key", sealed, otherKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decrypt(tt.b, tt.key); err != ErrCorrupt {
+				t.Errorf("expected ErrCorrupt, got %v", err)
+			}
+		})
+	}
+}
+
+func TestInvalidKeySize(t *testing.T) {
+	if _, err := Encrypt([]byte("a"), make([]byte, 16)); err == nil {
+		t.Error("expected error for short key")
+	}
+	if _, err := NewReader(bytes.NewReader(nil), make([]byte, 33)); err == nil {
+		t.Error("expected error for long key")
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	key, _ := GenerateKey()
+	w, err := NewWriter(ioutil.Discard, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte("a")); err == nil {
+		t.Error("expected error on write after close")
+	}
+}
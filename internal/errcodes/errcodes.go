@@ -12,11 +12,47 @@ const (
 	FileNotFound                     = "FILE_NOT_FOUND"
 	CodeNotEnoughSpaceInFilesStorage = "NOT_ENOUGH_SPACE_IN_FILES_STORAGE"
 	CodeFileTooLarge                 = "FILE_TOO_LARGE"
+	ExportNotFound                   = "EXPORT_NOT_FOUND"
+	ExportNotReady                   = "EXPORT_NOT_READY"
+	ExportTooLarge                   = "EXPORT_TOO_LARGE"
+	CollectionNotFound               = "COLLECTION_NOT_FOUND"
+	InvalidCollectionParent          = "INVALID_COLLECTION_PARENT"
+	FileQuarantined                  = "FILE_QUARANTINED"
+	ScanDisabled                     = "SCAN_DISABLED"
+	UnsupportedFileType              = "UNSUPPORTED_FILE_TYPE"
+	FileTypeMismatch                 = "FILE_TYPE_MISMATCH"
+	CorruptFile                      = "CORRUPT_FILE"
+	EncryptedFile                    = "ENCRYPTED_FILE"
+	FileLimitsExceeded               = "FILE_LIMITS_EXCEEDED"
+	UnsupportedVariant               = "UNSUPPORTED_VARIANT"
+	InvalidCombineFiles              = "INVALID_COMBINE_FILES"
+	WatermarkUnsupported             = "WATERMARK_UNSUPPORTED"
+	FileRetained                     = "FILE_RETAINED"
+	FileNotScanned                   = "FILE_NOT_SCANNED"
+	FileShredded                     = "FILE_SHREDDED"
 )
 
 var StatusCodes = map[string]int{
-	Forbidden:    http.StatusForbidden,
-	FileNotFound: http.StatusNotFound,
+	Forbidden:               http.StatusForbidden,
+	FileNotFound:            http.StatusNotFound,
+	ExportNotFound:          http.StatusNotFound,
+	ExportNotReady:          http.StatusConflict,
+	ExportTooLarge:          http.StatusRequestEntityTooLarge,
+	CollectionNotFound:      http.StatusNotFound,
+	InvalidCollectionParent: http.StatusBadRequest,
+	FileQuarantined:         http.StatusLocked,
+	ScanDisabled:            http.StatusConflict,
+	UnsupportedFileType:     http.StatusUnsupportedMediaType,
+	FileTypeMismatch:        http.StatusUnsupportedMediaType,
+	CorruptFile:             http.StatusUnprocessableEntity,
+	EncryptedFile:           http.StatusUnprocessableEntity,
+	FileLimitsExceeded:      http.StatusUnprocessableEntity,
+	UnsupportedVariant:      http.StatusBadRequest,
+	InvalidCombineFiles:     http.StatusBadRequest,
+	WatermarkUnsupported:    http.StatusUnsupportedMediaType,
+	FileRetained:            http.StatusConflict,
+	FileNotScanned:          http.StatusLocked,
+	FileShredded:            http.StatusGone,
 }
 
 func AddError(c *gin.Context, code string) {
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Confialink/wallet-files/internal/auth"
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/errcodes"
+	"github.com/Confialink/wallet-files/internal/service"
+	errors "github.com/Confialink/wallet-pkg-errors"
+	"github.com/gin-gonic/gin"
+)
+
+// collectionForm is a request body to create or update a collection
+type collectionForm struct {
+	Name     string  `json:"name" binding:"required,max=255"`
+	ParentId *uint64 `json:"parentId"`
+}
+
+// GetCollectionsHandler returns all collections of the user with files count and size
+func (h *Handler) GetCollectionsHandler(c *gin.Context) {
+	uid := c.Params.ByName("uid")
+
+	collections, err := h.collectionService.List(uid)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't retrieve collections"}
+		privateError.AddLogPair("error", err.Error())
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewResponse().SetData(collections))
+}
+
+// CreateCollectionHandler creates a new collection of the user
+func (h *Handler) CreateCollectionHandler(c *gin.Context) {
+	uid := c.Params.ByName("uid")
+
+	var form collectionForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "invalid request body",
+			Details:    err.Error(),
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+
+	collection, err := h.collectionService.Create(uid, form.Name, form.ParentId)
+	if err != nil {
+		h.addCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewResponse().SetData(collection))
+}
+
+// UpdateCollectionHandler renames the collection or moves it into another parent
+func (h *Handler) UpdateCollectionHandler(c *gin.Context) {
+	collection := h.getRequestedCollection(c)
+	if collection == nil {
+		return
+	}
+
+	var form collectionForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "invalid request body",
+			Details:    err.Error(),
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+
+	res, err := h.collectionService.Update(collection, form.Name, form.ParentId)
+	if err != nil {
+		h.addCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewResponse().SetData(res))
+}
+
+// DeleteCollectionHandler deletes the collection with nested collections and files.
+// Nothing is deleted if the current user is not allowed to delete any of the files.
+func (h *Handler) DeleteCollectionHandler(c *gin.Context) {
+	currentUser := h.mustGetCurrentUser(c)
+	collection := h.getRequestedCollection(c)
+	if collection == nil {
+		return
+	}
+
+	tree, err := h.collectionService.Tree(collection)
+	if err != nil {
+		h.addCollectionError(c, err)
+		return
+	}
+
+	forbidden, retained := make([]uint64, 0), make([]uint64, 0)
+	now := time.Now()
+	for _, file := range tree.Files {
+		if !h.authService.Can(currentUser, auth.DeleteAction, auth.FilesResource, file) {
+			forbidden = append(forbidden, file.ID)
+		} else if h.storageService.RetainReason(file, now) != "" {
+			retained = append(retained, file.ID)
+		}
+	}
+	if len(forbidden) > 0 {
+		errcodes.AddErrorMeta(c, errcodes.Forbidden, gin.H{"fileIds": forbidden})
+		return
+	}
+	if len(retained) > 0 {
+		errcodes.AddErrorMeta(c, errcodes.FileRetained, gin.H{"fileIds": retained})
+		return
+	}
+
+	if err := h.collectionService.DeleteTree(tree); err != nil {
+		privateError := errors.PrivateError{Message: "can't delete collection"}
+		privateError.AddLogPair("error", err.Error())
+		privateError.AddLogPair("id", collection.ID)
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// MoveFileHandler moves the file into a collection of its owner
+func (h *Handler) MoveFileHandler(c *gin.Context) {
+	file := h.getRequestedFile(c)
+	if file == nil {
+		logger := h.logger.New("action", "MoveFileHandler")
+		logger.Error("not found", "id", h.getIdParam(c))
+		errcodes.AddError(c, errcodes.FileNotFound)
+		return
+	}
+
+	var form struct {
+		CollectionId *uint64 `json:"collectionId"`
+	}
+	if err := c.ShouldBindJSON(&form); err != nil {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "invalid request body",
+			Details:    err.Error(),
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+
+	res, err := h.collectionService.MoveFile(file, form.CollectionId)
+	if err != nil {
+		h.addCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewResponse().SetData(res))
+}
+
+// getRequestedCollection returns collection of the requested user or adds an error
+func (h *Handler) getRequestedCollection(c *gin.Context) *database.CollectionModel {
+	id, err := strconv.ParseUint(c.Params.ByName("collectionId"), 10, 64)
+	if err != nil {
+		errcodes.AddError(c, errcodes.CollectionNotFound)
+		return nil
+	}
+
+	collection, err := h.collectionService.Find(c.Params.ByName("uid"), id)
+	if err != nil {
+		errcodes.AddError(c, errcodes.CollectionNotFound)
+		return nil
+	}
+	return collection
+}
+
+func (h *Handler) addCollectionError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrCollectionNotFound:
+		errcodes.AddError(c, errcodes.CollectionNotFound)
+	case service.ErrCollectionInvalidParent:
+		errcodes.AddError(c, errcodes.InvalidCollectionParent)
+	default:
+		privateError := errors.PrivateError{Message: "can't process collection"}
+		privateError.AddLogPair("error", err.Error())
+		errors.AddErrors(c, &privateError)
+	}
+}
@@ -16,6 +16,16 @@ func (*factory) FilesHandlerFactory() *Handler {
 		c.Repository(),
 		c.AuthService(),
 		c.StorageService(),
+		c.ErasureService(),
+		c.ExportService(),
+		c.ArchiveService(),
+		c.CollectionService(),
+		c.ScanService(),
+		c.VariantService(),
+		c.CombineService(),
+		c.WatermarkService(),
+		c.DuplicateService(),
+		c.ReplicationService(),
 		c.UsersService(),
 		c.ServiceLogger(),
 	)
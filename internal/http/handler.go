@@ -2,14 +2,19 @@ package http
 
 import (
 	"bytes"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	list_params "github.com/Confialink/wallet-pkg-list_params"
 
 	"github.com/Confialink/wallet-files/internal/auth"
 	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/disposition"
 	"github.com/Confialink/wallet-files/internal/errcodes"
 	"github.com/Confialink/wallet-files/internal/service"
 	errors "github.com/Confialink/wallet-pkg-errors"
@@ -18,11 +23,46 @@ import (
 	"github.com/inconshreveable/log15"
 )
 
+const maxFilesPerUpload = 10
+
+// maxQuarantinedPageSize limits the page of quarantined files
+const maxQuarantinedPageSize = 100
+
+const (
+	// variantFormatOriginal requests the original file without conversion
+	variantFormatOriginal = "original"
+	maxVariantSize        = 4000
+)
+
+const (
+	BulkDeleteStatusDeleted   = "deleted"
+	BulkDeleteStatusNotFound  = "not_found"
+	BulkDeleteStatusForbidden = "forbidden"
+	BulkDeleteStatusFailed    = "failed"
+	BulkDeleteStatusRetained  = "retained"
+)
+
+// BulkDeleteResult is the outcome of a single file deletion
+type BulkDeleteResult struct {
+	ID     uint64 `json:"id"`
+	Status string `json:"status"`
+}
+
 // Handler
 type Handler struct {
 	repo                *database.Repository
 	authService         auth.ServiceInterface
 	storageService      *service.StorageService
+	erasureService      *service.ErasureService
+	exportService       *service.ExportService
+	archiveService      *service.ArchiveService
+	collectionService   *service.CollectionService
+	scanService         *service.ScanService
+	variantService      *service.VariantService
+	combineService      *service.CombineService
+	watermarkService    *service.WatermarkService
+	duplicateService    *service.DuplicateService
+	replicationService  *service.ReplicationService
 	userService         *service.Users
 	logger              log15.Logger
 }
@@ -32,6 +72,16 @@ func NewHandler(
 	repo *database.Repository,
 	authService auth.ServiceInterface,
 	storageService *service.StorageService,
+	erasureService *service.ErasureService,
+	exportService *service.ExportService,
+	archiveService *service.ArchiveService,
+	collectionService *service.CollectionService,
+	scanService *service.ScanService,
+	variantService *service.VariantService,
+	combineService *service.CombineService,
+	watermarkService *service.WatermarkService,
+	duplicateService *service.DuplicateService,
+	replicationService *service.ReplicationService,
 	userService *service.Users,
 	logger log15.Logger,
 ) *Handler {
@@ -39,6 +89,16 @@ func NewHandler(
 		repo,
 		authService,
 		storageService,
+		erasureService,
+		exportService,
+		archiveService,
+		collectionService,
+		scanService,
+		variantService,
+		combineService,
+		watermarkService,
+		duplicateService,
+		replicationService,
 		userService,
 		logger,
 	}
@@ -79,16 +139,243 @@ func (h *Handler) GetBinary(c *gin.Context) {
 		return
 	}
 
-	b := h.storageService.Download(file)
+	if file.IsQuarantined() {
+		logger.Error("file is quarantined", "id", id)
+		errcodes.AddError(c, errcodes.FileQuarantined)
+		return
+	}
+	if h.storageService.Unscanned(file) {
+		logger.Error("file is not scanned", "id", id, "scanStatus", file.ScanStatus)
+		errcodes.AddError(c, errcodes.FileNotScanned)
+		return
+	}
+
+	// watermarked downloads are not resized or converted
+	if c.Query("watermark") == "true" || h.watermarkForced(file, currentUser) {
+		h.getWatermarked(c, file, currentUser)
+		return
+	}
+
+	variant, ok := h.requestedVariant(c, file, currentUser)
+	if !ok {
+		return
+	}
+	if variant != nil && h.getVariant(c, file, variant) {
+		return
+	}
+
+	b, err := h.storageService.Download(file)
+	if err != nil {
+		addContentError(c, file, "can't download file", err)
+		return
+	}
 	r := bytes.NewReader(b)
 
 	extraHeaders := map[string]string{
-		"Content-Disposition": `attachment; filename="` + file.Filename + `"`,
+		"Content-Disposition": disposition.Attachment(file.OriginalFilename),
 	}
 
 	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, r, extraHeaders)
 }
 
+// requestedVariant builds the variant from "variant", "maxSize", "format" and "quality" query parameters.
+// Without format the image is converted into webp if Accept header allows it, plain downloads included.
+// nil is returned if the original is requested, false is returned if parameters are invalid.
+// Custom sizes, conversions and qualities are available for authenticated users only.
+func (h *Handler) requestedVariant(c *gin.Context, file *database.FileModel, user *userpb.User) (*service.Variant, bool) {
+	name := c.Query("variant")
+	format := strings.ToLower(c.Query("format"))
+	if format == "jpg" {
+		format = service.VariantFormatJpeg
+	}
+
+	quality, ok := h.variantParam(c, "quality", 100)
+	if !ok {
+		return nil, false
+	}
+	maxSize, ok := h.variantParam(c, "maxSize", maxVariantSize)
+	if !ok {
+		return nil, false
+	}
+
+	if format == variantFormatOriginal {
+		return nil, true
+	}
+	if name == "" && format == "" && maxSize == 0 && quality == 0 {
+		// plain downloads of images are converted for anonymous users too, it is a single variant per file
+		if !h.variantService.Supported(file) {
+			return nil, true
+		}
+		if format = h.acceptedFormat(c); format == "" {
+			return nil, true
+		}
+		return &service.Variant{Name: variantFormatOriginal, Format: format}, true
+	}
+
+	variant := &service.Variant{Name: variantFormatOriginal}
+	switch {
+	case name != "" && maxSize > 0:
+		addVariantError(c, "variant and maxSize can not be combined")
+		return nil, false
+	case name != "":
+		predefined := service.FindVariant(name)
+		if predefined == nil {
+			addVariantError(c, service.ErrVariantUnknown.Error())
+			return nil, false
+		}
+		*variant = *predefined
+	case maxSize > 0:
+		// sizes are rounded to limit the number of stored variants
+		variant.Size = h.variantService.RoundSize(maxSize)
+		variant.Name = "s" + strconv.Itoa(variant.Size)
+	}
+
+	// only predefined variants are generated for anonymous users, they are requested by public pages
+	if user == nil && (name == "" || quality > 0) {
+		errcodes.AddError(c, errcodes.Forbidden)
+		return nil, false
+	}
+
+	if format == "" {
+		format = h.acceptedFormat(c)
+	}
+	// the quality applies to the converted image only
+	if quality > 0 && format == "" {
+		addVariantError(c, "quality requires format")
+		return nil, false
+	}
+
+	if format != "" && !h.variantService.FormatSupported(format) {
+		if format != service.VariantFormatWebp {
+			addVariantError(c, "unsupported format "+format)
+			return nil, false
+		}
+		// webp encoder is not installed, the variant is served in the default format
+		format = ""
+	}
+	variant.Format = format
+
+	if quality > 0 {
+		variant.Quality = h.variantService.RoundQuality(quality)
+	}
+	return variant, true
+}
+
+// acceptedFormat returns webp if the client accepts it and the encoder is installed, otherwise the default
+// format of variants is used
+func (h *Handler) acceptedFormat(c *gin.Context) string {
+	c.Header("Vary", "Accept")
+	if strings.Contains(c.GetHeader("Accept"), "image/webp") && h.variantService.FormatSupported(service.VariantFormatWebp) {
+		return service.VariantFormatWebp
+	}
+	return ""
+}
+
+// variantParam reads an optional positive integer query parameter, zero is returned if it is empty
+func (h *Handler) variantParam(c *gin.Context, name string, max int) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i <= 0 || i > max {
+		addVariantError(c, fmt.Sprintf("%s must be from 1 to %d", name, max))
+		return 0, false
+	}
+	return i, true
+}
+
+// getVariant writes the resized variant of the image file. Images which can not be resized
+// are served in original size, false is returned for them.
+func (h *Handler) getVariant(c *gin.Context, file *database.FileModel, v *service.Variant) bool {
+	content, variant, err := h.variantService.Open(file, v)
+	switch {
+	case err == nil:
+	case err == service.ErrVariantUnsupported && strings.HasPrefix(file.ContentType, "image/"):
+		return false
+	case err == service.ErrVariantUnknown || err == service.ErrVariantUnsupported:
+		addVariantError(c, err.Error())
+		return true
+	default:
+		addContentError(c, file, "can't open file variant", err)
+		return true
+	}
+	defer content.Close()
+
+	extraHeaders := map[string]string{
+		"Content-Disposition": disposition.Attachment(variant.Filename),
+	}
+
+	c.DataFromReader(http.StatusOK, variant.Size, variant.ContentType, content, extraHeaders)
+	return true
+}
+
+// watermarkForced checks if downloads of the file by the user must be watermarked because of its category
+func (h *Handler) watermarkForced(file *database.FileModel, user *userpb.User) bool {
+	return forcedWatermark(user) != "" && h.watermarkService.Forced(file)
+}
+
+// forcedWatermark returns the watermark of files of watermarked categories in archives downloaded by the user,
+// it is empty for users whose downloads are not watermarked
+func forcedWatermark(user *userpb.User) string {
+	if user == nil || (user.RoleName != auth.RoleRoot && user.RoleName != auth.RoleAdmin) {
+		return ""
+	}
+	return service.WatermarkText(user.Email, user.UID)
+}
+
+// getWatermarked sends the file stamped with the current user and time, it is generated for each download
+func (h *Handler) getWatermarked(c *gin.Context, file *database.FileModel, user *userpb.User) {
+	if user == nil {
+		errcodes.AddError(c, errcodes.Forbidden)
+		return
+	}
+	if !h.watermarkService.Supported(file) {
+		errcodes.AddError(c, errcodes.WatermarkUnsupported)
+		return
+	}
+
+	b, contentType, err := h.watermarkService.Watermark(file, service.WatermarkText(user.Email, user.UID), time.Now())
+	if err == service.ErrWatermarkUnsupported {
+		errcodes.AddError(c, errcodes.WatermarkUnsupported)
+		return
+	}
+	if err != nil {
+		addContentError(c, file, "can't watermark file", err)
+		return
+	}
+
+	extraHeaders := map[string]string{
+		"Content-Disposition": disposition.Attachment(file.OriginalFilename),
+		"Cache-Control":       "no-store",
+	}
+
+	c.DataFromReader(http.StatusOK, int64(len(b)), contentType, bytes.NewReader(b), extraHeaders)
+}
+
+// addContentError reports a failure to read content of the file, files of users whose key is shredded are gone
+func addContentError(c *gin.Context, file *database.FileModel, message string, err error) {
+	if err == service.ErrKeyShredded {
+		errcodes.AddError(c, errcodes.FileShredded)
+		return
+	}
+
+	privateError := errors.PrivateError{Message: message}
+	privateError.AddLogPair("error", err.Error())
+	privateError.AddLogPair("id", file.ID)
+	errors.AddErrors(c, &privateError)
+}
+
+func addVariantError(c *gin.Context, details string) {
+	errors.AddErrors(c, &errors.PublicError{
+		Title:      "Variant is not available",
+		Details:    details,
+		Code:       errcodes.UnsupportedVariant,
+		HttpStatus: errcodes.StatusCodes[errcodes.UnsupportedVariant],
+	})
+}
+
 // GetHandler returns file by id
 func (h *Handler) GetHandler(c *gin.Context) {
 	file := h.getRequestedFile(c)
@@ -102,34 +389,35 @@ func (h *Handler) GetHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, NewResponse().SetData(file))
 }
 
-// CreatePublicHandler creates new public file
+// CreatePublicHandler creates new public files
 func (h *Handler) CreatePublicHandler(c *gin.Context) {
-	uid := c.Params.ByName("uid")
-	file, header, err := c.Request.FormFile("file")
-
-	if nil != err {
-		privateError := errors.PrivateError{Message: "can't read file"}
-		privateError.AddLogPair("error", err.Error())
-		errors.AddErrors(c, &privateError)
-		return
-	}
-
-	res, tErr := h.storageService.Upload(file, header, uid, false, false, nil)
-
-	if nil != tErr {
-		errors.AddErrors(c, tErr)
-		return
-	}
+	h.upload(c, c.Params.ByName("uid"), false, false)
+}
 
-	c.JSON(http.StatusOK, NewResponse().SetData(res))
+// CreatePrivateHandler creates new private files
+func (h *Handler) CreatePrivateHandler(c *gin.Context) {
+	h.upload(c, c.Params.ByName("uid"), false, true)
+}
 
+// CreatePrivateLimitedHandler creates new private files for the new user
+func (h *Handler) CreatePrivateLimitedHandler(c *gin.Context) {
+	currentUser := h.mustGetCurrentUser(c)
+	h.upload(c, currentUser.UID, false, true)
 }
 
-// CreatePrivateHandler creates new private file
-func (h *Handler) CreatePrivateHandler(c *gin.Context) {
-	uid := c.Params.ByName("uid")
-	file, header, err := c.Request.FormFile("file")
+// CreateAdminOnlyHandler creates new private files visible for admin only
+func (h *Handler) CreateAdminOnlyHandler(c *gin.Context) {
+	h.upload(c, c.Params.ByName("uid"), true, true)
+}
 
+// upload uploads the only "file" part of the request and returns the file. Several "file" parts and "files" parts
+// are uploaded independently and the result of each upload is returned whatever the number of files is.
+func (h *Handler) upload(c *gin.Context, uid string, isAdminOnly bool, isPrivate bool) {
+	currentUser := h.mustGetCurrentUser(c)
+	form, err := c.MultipartForm()
+	if nil == err && len(form.File["file"]) == 0 && len(form.File["files"]) == 0 {
+		err = http.ErrMissingFile
+	}
 	if nil != err {
 		privateError := errors.PrivateError{Message: "can't read file"}
 		privateError.AddLogPair("error", err.Error())
@@ -137,22 +425,14 @@ func (h *Handler) CreatePrivateHandler(c *gin.Context) {
 		return
 	}
 
-	res, tErr := h.storageService.Upload(file, header, uid, false, true, nil)
-
-	if nil != tErr {
-		errors.AddErrors(c, tErr)
+	headers := form.File["file"]
+	if len(headers) > 1 || len(form.File["files"]) > 0 {
+		headers = append(headers, form.File["files"]...)
+		h.uploadMany(c, headers, uid, isAdminOnly, isPrivate, currentUser.RoleName)
 		return
 	}
 
-	c.JSON(http.StatusOK, NewResponse().SetData(res))
-
-}
-
-// CreatePrivateLimitedHandler creates new private file for the new user
-func (h *Handler) CreatePrivateLimitedHandler(c *gin.Context) {
-	currentUser := h.mustGetCurrentUser(c)
-
-	file, header, err := c.Request.FormFile("file")
+	file, err := headers[0].Open()
 	if nil != err {
 		privateError := errors.PrivateError{Message: "can't read file"}
 		privateError.AddLogPair("error", err.Error())
@@ -160,7 +440,7 @@ func (h *Handler) CreatePrivateLimitedHandler(c *gin.Context) {
 		return
 	}
 
-	res, tErr := h.storageService.Upload(file, header, currentUser.UID, false, true, nil)
+	res, tErr := h.storageService.Upload(file, headers[0], uid, isAdminOnly, isPrivate, currentUser.RoleName, nil)
 	if nil != tErr {
 		errors.AddErrors(c, tErr)
 		return
@@ -169,27 +449,31 @@ func (h *Handler) CreatePrivateLimitedHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, NewResponse().SetData(res))
 }
 
-// CreateAdminOnlyHandler creates new private file visible for admin only
-func (h *Handler) CreateAdminOnlyHandler(c *gin.Context) {
-	uid := c.Params.ByName("uid")
-	file, header, err := c.Request.FormFile("file")
-
-	if nil != err {
-		privateError := errors.PrivateError{Message: "can't read file"}
-		privateError.AddLogPair("error", err.Error())
-		errors.AddErrors(c, &privateError)
+// uploadMany uploads the files independently and returns the result of each upload
+func (h *Handler) uploadMany(
+	c *gin.Context,
+	headers []*multipart.FileHeader,
+	uid string,
+	isAdminOnly bool,
+	isPrivate bool,
+	uploaderRole string,
+) {
+	if len(headers) > maxFilesPerUpload {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "too many files",
+			Details:    fmt.Sprintf("at most %d files may be uploaded at once", maxFilesPerUpload),
+			HttpStatus: http.StatusBadRequest,
+		})
 		return
 	}
 
-	res, tErr := h.storageService.Upload(file, header, uid, true, true, nil)
-
+	results, tErr := h.storageService.UploadMany(headers, uid, isAdminOnly, isPrivate, uploaderRole, nil)
 	if nil != tErr {
 		errors.AddErrors(c, tErr)
 		return
 	}
 
-	c.JSON(http.StatusOK, NewResponse().SetData(res))
-
+	c.JSON(http.StatusOK, NewResponse().SetData(results))
 }
 
 // DeleteHandler deletes an existing file
@@ -203,6 +487,10 @@ func (h *Handler) DeleteHandler(c *gin.Context) {
 	}
 
 	err := h.storageService.Delete(file)
+	if err == service.ErrFileRetained {
+		errcodes.AddError(c, errcodes.FileRetained)
+		return
+	}
 
 	if nil != err {
 		privateError := errors.PrivateError{Message: "can't delete a file"}
@@ -215,6 +503,138 @@ func (h *Handler) DeleteHandler(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// BulkDeleteHandler deletes several files, every file is checked and deleted independently
+func (h *Handler) BulkDeleteHandler(c *gin.Context) {
+	currentUser := h.mustGetCurrentUser(c)
+	logger := h.logger.New("action", "BulkDeleteHandler")
+
+	var form struct {
+		Ids []uint64 `json:"ids" binding:"required,min=1,max=100"`
+	}
+	if err := c.ShouldBindJSON(&form); err != nil {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "invalid request body",
+			Details:    err.Error(),
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+
+	found, err := h.repo.FindByIDs(form.Ids)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't retrieve files"}
+		privateError.AddLogPair("error", err.Error())
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	byID := make(map[uint64]*database.FileModel, len(found))
+	for _, file := range found {
+		byID[file.ID] = file
+	}
+
+	results := make([]*BulkDeleteResult, 0, len(form.Ids))
+	seen := make(map[uint64]bool, len(form.Ids))
+	for _, id := range form.Ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		result := &BulkDeleteResult{ID: id, Status: BulkDeleteStatusDeleted}
+		results = append(results, result)
+
+		file, ok := byID[id]
+		if !ok {
+			result.Status = BulkDeleteStatusNotFound
+			continue
+		}
+
+		if !h.authService.Can(currentUser, auth.DeleteAction, auth.FilesResource, file) {
+			result.Status = BulkDeleteStatusForbidden
+			continue
+		}
+
+		err := h.storageService.Delete(file)
+		if err == service.ErrFileRetained {
+			result.Status = BulkDeleteStatusRetained
+		} else if err != nil {
+			logger.Error("can't delete a file", "id", id, "err", err)
+			result.Status = BulkDeleteStatusFailed
+		}
+	}
+
+	c.JSON(http.StatusOK, NewResponse().SetData(results))
+}
+
+// CombineImagesHandler combines images of the user into a single pdf document
+func (h *Handler) CombineImagesHandler(c *gin.Context) {
+	currentUser := h.mustGetCurrentUser(c)
+	uid := c.Params.ByName("uid")
+
+	var form struct {
+		FileIds       []uint64 `json:"fileIds" binding:"required,min=1,max=50"`
+		FileName      string   `json:"fileName" binding:"required,max=200"`
+		Category      *string  `json:"category" binding:"omitempty,max=64"`
+		DeleteSources bool     `json:"deleteSources"`
+	}
+	if err := c.ShouldBindJSON(&form); err != nil {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "invalid request body",
+			Details:    err.Error(),
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+
+	found, err := h.repo.FindByIDs(form.FileIds)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't retrieve files"}
+		privateError.AddLogPair("error", err.Error())
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	byID := make(map[uint64]*database.FileModel, len(found))
+	for _, file := range found {
+		byID[file.ID] = file
+	}
+
+	// pages follow the requested order
+	files := make([]*database.FileModel, 0, len(form.FileIds))
+	notFound := make([]uint64, 0)
+	forbidden := make([]uint64, 0)
+	for _, id := range form.FileIds {
+		file, ok := byID[id]
+		if !ok {
+			notFound = append(notFound, id)
+			continue
+		}
+		if !h.authService.Can(currentUser, auth.ReadAction, auth.FilesResource, file) ||
+			(form.DeleteSources && !h.authService.Can(currentUser, auth.DeleteAction, auth.FilesResource, file)) {
+			forbidden = append(forbidden, id)
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(notFound) > 0 {
+		errcodes.AddErrorMeta(c, errcodes.FileNotFound, gin.H{"fileIds": notFound})
+		return
+	}
+	if len(forbidden) > 0 {
+		errcodes.AddErrorMeta(c, errcodes.Forbidden, gin.H{"fileIds": forbidden})
+		return
+	}
+
+	document, tErr := h.combineService.Combine(uid, files, form.FileName, form.Category, form.DeleteSources, currentUser.RoleName)
+	if tErr != nil {
+		errors.AddErrors(c, tErr)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewResponse().SetData(document))
+}
+
 // GetUserFilesHandler returns list of files
 func (h *Handler) GetUserFilesHandler(c *gin.Context) {
 	uid := c.Params.ByName("uid")
@@ -222,6 +642,17 @@ func (h *Handler) GetUserFilesHandler(c *gin.Context) {
 
 	params := h.getListParamsByRoleName(currentUser.RoleName, c.Request.URL.RawQuery)
 	params.AddFilter("user_id", []string{uid})
+	if collectionId := c.Query("collectionId"); collectionId != "" {
+		if _, err := strconv.ParseUint(collectionId, 10, 64); err != nil {
+			errors.AddErrors(c, &errors.PublicError{
+				Title:      "collectionId param must be an integer",
+				Details:    err.Error(),
+				HttpStatus: http.StatusBadRequest,
+			})
+			return
+		}
+		params.AddFilter("collection_id", []string{collectionId})
+	}
 	files, err := h.repo.GetList(params)
 	if nil != err {
 		privateError := errors.PrivateError{Message: "can't retrieve files"}
@@ -259,6 +690,7 @@ func (h *Handler) CreateProfileImageHandler(c *gin.Context) {
 		currentUser.UID,
 		false,
 		false,
+		currentUser.RoleName,
 		regexp.MustCompile(`(image)/([a-z-.]{2,})`),
 	)
 	if nil != tErr {
@@ -282,6 +714,14 @@ func (h *Handler) CreateProfileImageHandler(c *gin.Context) {
 		return
 	}
 
+	// avatars are rendered often, so their variants are generated beforehand.
+	// Images which can not be resized are served in original size.
+	if h.variantService.Supported(res) {
+		if _, err := h.variantService.Generate(res); err != nil {
+			h.logger.Error("can't generate profile image variants", "id", res.ID, "err", err)
+		}
+	}
+
 	if currentUser.ProfileImageID != 0 {
 		currentImage, err := h.repo.GetByID(currentUser.ProfileImageID)
 		if nil != err {
@@ -291,8 +731,9 @@ func (h *Handler) CreateProfileImageHandler(c *gin.Context) {
 			return
 		}
 
+		// a held image stays stored, it is not the profile image anymore
 		err = h.storageService.Delete(currentImage)
-		if nil != err {
+		if nil != err && err != service.ErrFileRetained {
 			privateError := errors.PrivateError{Message: "can't delete current profile image"}
 			privateError.AddLogPair("error", err.Error())
 			errors.AddErrors(c, &privateError)
@@ -303,6 +744,331 @@ func (h *Handler) CreateProfileImageHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, NewResponse().SetData(res))
 }
 
+// EraseUserFilesHandler erases all files of the user except protected ones
+func (h *Handler) EraseUserFilesHandler(c *gin.Context) {
+	uid := c.Params.ByName("uid")
+
+	var form struct {
+		ExcludeCategories []string `json:"excludeCategories"`
+		DryRun            bool     `json:"dryRun"`
+	}
+	if err := c.ShouldBindJSON(&form); err != nil {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "invalid request body",
+			Details:    err.Error(),
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+
+	report, err := h.erasureService.EraseUserFiles(uid, form.ExcludeCategories, form.DryRun)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't erase user files"}
+		privateError.AddLogPair("error", err.Error())
+		privateError.AddLogPair("uid", uid)
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewResponse().SetData(report))
+}
+
+// UpdateRetentionHandler sets legal hold and retention date of the file
+func (h *Handler) UpdateRetentionHandler(c *gin.Context) {
+	file := h.getRequestedFile(c)
+	if file == nil {
+		logger := h.logger.New("action", "UpdateRetentionHandler")
+		logger.Error("not found", "id", h.getIdParam(c))
+		errcodes.AddError(c, errcodes.FileNotFound)
+		return
+	}
+
+	var form struct {
+		LegalHold   bool       `json:"legalHold"`
+		RetainUntil *time.Time `json:"retainUntil"`
+	}
+	if err := c.ShouldBindJSON(&form); err != nil {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "invalid request body",
+			Details:    err.Error(),
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+
+	file.LegalHold = form.LegalHold
+	file.RetainUntil = form.RetainUntil
+	res, err := h.repo.UpdateRetention(file)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't update file retention"}
+		privateError.AddLogPair("error", err.Error())
+		privateError.AddLogPair("id", file.ID)
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewResponse().SetData(res))
+}
+
+// ExportUserFilesHandler streams a zip archive with all files visible for the user
+func (h *Handler) ExportUserFilesHandler(c *gin.Context) {
+	uid := c.Params.ByName("uid")
+	currentUser := h.mustGetCurrentUser(c)
+	logger := h.logger.New("action", "ExportUserFilesHandler", "uid", uid)
+
+	files, err := h.exportService.UserFiles(uid)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't retrieve files"}
+		privateError.AddLogPair("error", err.Error())
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	if !h.exportService.CanStream(files) {
+		errcodes.AddError(c, errcodes.ExportTooLarge)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", disposition.Attachment("files-"+uid+".zip"))
+	c.Status(http.StatusOK)
+
+	// headers are already sent, so errors can only be logged
+	if _, err := h.exportService.WriteArchive(c.Writer, uid, files, forcedWatermark(currentUser)); err != nil {
+		logger.Error("can't write export archive", "err", err)
+	}
+}
+
+// CreateExportHandler starts a background export of the user files
+func (h *Handler) CreateExportHandler(c *gin.Context) {
+	uid := c.Params.ByName("uid")
+	currentUser := h.mustGetCurrentUser(c)
+
+	export, err := h.exportService.CreateExport(uid, currentUser.UID, forcedWatermark(currentUser))
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't create export"}
+		privateError.AddLogPair("error", err.Error())
+		privateError.AddLogPair("uid", uid)
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, NewResponse().SetData(export))
+}
+
+// GetExportHandler returns the export status
+func (h *Handler) GetExportHandler(c *gin.Context) {
+	export := h.getRequestedExport(c)
+	if export == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, NewResponse().SetData(export))
+}
+
+// DownloadExportHandler streams the archive of a finished export
+func (h *Handler) DownloadExportHandler(c *gin.Context) {
+	export := h.getRequestedExport(c)
+	if export == nil {
+		return
+	}
+
+	content, err := h.exportService.OpenArchive(export)
+	if err != nil {
+		logger := h.logger.New("action", "DownloadExportHandler")
+		logger.Error("export is not available", "id", export.ID, "status", export.Status, "err", err)
+		errcodes.AddError(c, errcodes.ExportNotReady)
+		return
+	}
+	defer content.Close()
+
+	extraHeaders := map[string]string{
+		"Content-Disposition": disposition.Attachment("files-" + export.UserId + ".zip"),
+	}
+
+	c.DataFromReader(http.StatusOK, export.Size, "application/zip", content, extraHeaders)
+}
+
+// ArchiveHandler streams a zip archive with the requested files.
+// Files which are not found or not readable by the current user are listed in the manifest.
+func (h *Handler) ArchiveHandler(c *gin.Context) {
+	currentUser := h.mustGetCurrentUser(c)
+	logger := h.logger.New("action", "ArchiveHandler")
+
+	var form struct {
+		Ids []uint64 `json:"ids" binding:"required,min=1,max=100"`
+	}
+	if err := c.ShouldBindJSON(&form); err != nil {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "invalid request body",
+			Details:    err.Error(),
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+
+	found, err := h.repo.FindByIDs(form.Ids)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't retrieve files"}
+		privateError.AddLogPair("error", err.Error())
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	byID := make(map[uint64]*database.FileModel, len(found))
+	for _, file := range found {
+		byID[file.ID] = file
+	}
+
+	manifest := &service.ArchiveManifest{Inaccessible: []uint64{}}
+	files := make([]*database.FileModel, 0, len(found))
+	seen := make(map[uint64]bool, len(form.Ids))
+	for _, id := range form.Ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		file, ok := byID[id]
+		if !ok || !h.authService.Can(currentUser, auth.ReadAction, auth.FilesResource, file) {
+			manifest.Inaccessible = append(manifest.Inaccessible, id)
+			continue
+		}
+		files = append(files, file)
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", disposition.Attachment("files.zip"))
+	c.Status(http.StatusOK)
+
+	// headers are already sent, so errors can only be logged
+	if err := h.archiveService.WriteArchive(c.Writer, manifest, files, forcedWatermark(currentUser)); err != nil {
+		logger.Error("can't write archive", "err", err)
+	}
+}
+
+// GetQuarantinedHandler returns quarantined files page by page, "limit" and "offset" query parameters select
+// the page and "userId" selects files of a single user
+func (h *Handler) GetQuarantinedHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "15"))
+	if err != nil || limit < 1 || limit > maxQuarantinedPageSize {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      fmt.Sprintf("limit param must be an integer from 1 to %d", maxQuarantinedPageSize),
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		errors.AddErrors(c, &errors.PublicError{
+			Title:      "offset param must be a non-negative integer",
+			HttpStatus: http.StatusBadRequest,
+		})
+		return
+	}
+
+	files, total, err := h.scanService.Quarantined(c.Query("userId"), limit, offset)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't retrieve quarantined files"}
+		privateError.AddLogPair("error", err.Error())
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	res, err := NewResponseWithListAndLinks(files, c, total)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't create response list"}
+		privateError.AddLogPair("error", err.Error())
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// GetReplicationBacklogHandler returns the number of files which are not copied into the replica target yet
+func (h *Handler) GetReplicationBacklogHandler(c *gin.Context) {
+	backlog, err := h.replicationService.Backlog()
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't count replication backlog"}
+		privateError.AddLogPair("error", err.Error())
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewResponse().SetData(backlog))
+}
+
+// GetDuplicatesHandler returns files of other users with identical or near-identical content
+func (h *Handler) GetDuplicatesHandler(c *gin.Context) {
+	file := h.getRequestedFile(c)
+	if file == nil {
+		logger := h.logger.New("action", "GetDuplicatesHandler")
+		logger.Error("not found", "id", h.getIdParam(c))
+		errcodes.AddError(c, errcodes.FileNotFound)
+		return
+	}
+
+	duplicates, err := h.duplicateService.Find(file)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't find duplicates"}
+		privateError.AddLogPair("error", err.Error())
+		privateError.AddLogPair("id", file.ID)
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewResponse().SetData(duplicates))
+}
+
+// RescanHandler queues the file for another scan
+func (h *Handler) RescanHandler(c *gin.Context) {
+	file := h.getRequestedFile(c)
+	if file == nil {
+		logger := h.logger.New("action", "RescanHandler")
+		logger.Error("not found", "id", h.getIdParam(c))
+		errcodes.AddError(c, errcodes.FileNotFound)
+		return
+	}
+
+	if err := h.scanService.Enqueue(file); err != nil {
+		if err == service.ErrScanDisabled {
+			errcodes.AddError(c, errcodes.ScanDisabled)
+			return
+		}
+		privateError := errors.PrivateError{Message: "can't queue file for scanning"}
+		privateError.AddLogPair("error", err.Error())
+		privateError.AddLogPair("id", file.ID)
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, NewResponse().SetData(file))
+}
+
+// ReleaseHandler releases a quarantined file, e.g. after a false positive
+func (h *Handler) ReleaseHandler(c *gin.Context) {
+	file := h.getRequestedFile(c)
+	if file == nil {
+		logger := h.logger.New("action", "ReleaseHandler")
+		logger.Error("not found", "id", h.getIdParam(c))
+		errcodes.AddError(c, errcodes.FileNotFound)
+		return
+	}
+
+	res, err := h.scanService.Release(file)
+	if err != nil {
+		privateError := errors.PrivateError{Message: "can't release file"}
+		privateError.AddLogPair("error", err.Error())
+		privateError.AddLogPair("id", file.ID)
+		errors.AddErrors(c, &privateError)
+		return
+	}
+
+	h.logger.Info("quarantined file is released", "id", file.ID, "by", h.mustGetCurrentUser(c).UID)
+	c.JSON(http.StatusOK, NewResponse().SetData(res))
+}
+
 // NotFoundHandler returns 404 NotFound
 func (h *Handler) NotFoundHandler(c *gin.Context) {
 	c.JSON(http.StatusNotFound, gin.H{"code": "PAGE_NOT_FOUND", "file": "Page not found"})
@@ -355,6 +1121,22 @@ func (h *Handler) getRequestedFile(c *gin.Context) *database.FileModel {
 	return file.(*database.FileModel)
 }
 
+// getRequestedExport returns export of the requested user or adds an error
+func (h *Handler) getRequestedExport(c *gin.Context) *database.ExportModel {
+	id, err := strconv.ParseUint(c.Params.ByName("exportId"), 10, 64)
+	if err != nil {
+		errcodes.AddError(c, errcodes.ExportNotFound)
+		return nil
+	}
+
+	export, err := h.exportService.FindExport(id)
+	if err != nil || export.UserId != c.Params.ByName("uid") {
+		errcodes.AddError(c, errcodes.ExportNotFound)
+		return nil
+	}
+	return export
+}
+
 func (h *Handler) getListParamsByRoleName(roleName string, query string) *list_params.ListParams {
 	params := getListParams(query)
 	if roleName != auth.RoleRoot && roleName != auth.RoleAdmin {
@@ -158,6 +158,31 @@ func (s *PermissionChecker) CanWithFile(action string) func(*gin.Context) {
 	}
 }
 
+// check dynamic permission to the requested file treated as the given resource
+func (s *PermissionChecker) CanWithFileResource(action string, resourceName string) func(*gin.Context) {
+	return func(c *gin.Context) {
+		user, exist := c.Get("_user")
+		if !exist {
+			errcodes.AddError(c, errcodes.Forbidden)
+			c.Abort()
+			return
+		}
+
+		file, exist := c.Get("_requested_file")
+		if !exist {
+			errcodes.AddError(c, errcodes.Forbidden)
+			c.Abort()
+			return
+		}
+
+		if !s.authService.Can(user.(*userpb.User), action, resourceName, file) {
+			errcodes.AddError(c, errcodes.Forbidden)
+			c.Abort()
+			return
+		}
+	}
+}
+
 // check dynamic permission to user's files
 func (s *PermissionChecker) CanWithUser(action string, resourceName string) func(*gin.Context) {
 	return func(c *gin.Context) {
@@ -18,6 +18,7 @@ var showListOutputFields = []interface{}{
 	"Size",
 	"IsAdminOnly",
 	"IsPrivate",
+	"CollectionId",
 }
 
 func getListParams(query string) *list_params.ListParams {
@@ -0,0 +1,35 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math/bits"
+)
+
+// DHash computes the 64 bit difference hash of the image. The image is reduced to 9x8 gray pixels
+// and every bit tells if a pixel is brighter than its right neighbour, so the hash survives
+// resizing, recompression and small color changes.
+func DHash(src image.Image) uint64 {
+	small := toRGBA(Resize(Flatten(src, color.White), 9, 8))
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luminance(small, x, y) > luminance(small, x+1, y) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// Distance returns the number of different bits of two hashes
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func luminance(img *image.RGBA, x, y int) int {
+	i := img.PixOffset(x, y)
+	return 299*int(img.Pix[i]) + 587*int(img.Pix[i+1]) + 114*int(img.Pix[i+2])
+}
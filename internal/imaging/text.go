@@ -0,0 +1,82 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// RenderText renders a single line of ascii text into a mask, other characters are rendered as '?'
+func RenderText(text string) *image.Alpha {
+	runes := []rune(text)
+	mask := image.NewAlpha(image.Rect(0, 0, len(runes)*glyphWidth, glyphHeight))
+	for i, r := range runes {
+		if r < 0x20 || r > 0x7E {
+			r = '?'
+		}
+		glyph := glyphs[r-0x20]
+		for y, row := range glyph {
+			for x := 0; x < glyphWidth; x++ {
+				if row&(1<<(glyphWidth-1-x)) != 0 {
+					mask.SetAlpha(i*glyphWidth+x, y, color.Alpha{A: 0xFF})
+				}
+			}
+		}
+	}
+	return mask
+}
+
+// Watermark returns a copy of the image with the text lines tiled over it in semi-transparent color.
+// Text is scaled to the image size, so it stays readable on large photos.
+func Watermark(src image.Image, lines []string, c color.Color) image.Image {
+	dst := toRGBA(src)
+	if dst == src {
+		dst = image.NewRGBA(dst.Rect)
+		draw.Draw(dst, dst.Rect, src, image.Point{}, draw.Src)
+	}
+
+	masks := make([]*image.Alpha, len(lines))
+	blockWidth := 0
+	for i, line := range lines {
+		masks[i] = RenderText(line)
+		if w := masks[i].Rect.Dx(); w > blockWidth {
+			blockWidth = w
+		}
+	}
+	if blockWidth == 0 {
+		return dst
+	}
+
+	// the block takes about a half of the image width
+	width, height := dst.Rect.Dx(), dst.Rect.Dy()
+	scale := max(width/2/blockWidth, 1)
+	lineHeight := (glyphHeight + 2) * scale
+	blockHeight := lineHeight * len(lines)
+	stepX := blockWidth*scale + blockWidth*scale/2
+	stepY := blockHeight * 3
+
+	fill := image.NewUniform(c)
+	for row, y := 0, blockHeight; y < height; row, y = row+1, y+stepY {
+		// odd rows are shifted, so the text can not be cut out by a single strip
+		x0 := -(row % 2) * stepX / 2
+		for x := x0; x < width; x += stepX {
+			for i, mask := range masks {
+				drawMask(dst, mask, image.Pt(x, y+i*lineHeight), scale, fill)
+			}
+		}
+	}
+	return dst
+}
+
+// drawMask draws every pixel of the mask as a square of scale pixels
+func drawMask(dst draw.Image, mask *image.Alpha, at image.Point, scale int, fill image.Image) {
+	for y := 0; y < mask.Rect.Dy(); y++ {
+		for x := 0; x < mask.Rect.Dx(); x++ {
+			if mask.AlphaAt(x, y).A == 0 {
+				continue
+			}
+			r := image.Rect(at.X+x*scale, at.Y+y*scale, at.X+(x+1)*scale, at.Y+(y+1)*scale)
+			draw.Draw(dst, r, fill, image.Point{}, draw.Over)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// WebpEncoder encodes images into webp with the cwebp tool, the standard library has no webp encoder
+type WebpEncoder struct {
+	path    string
+	timeout time.Duration
+}
+
+// NewWebpEncoder creates an encoder of the cwebp executable, which is looked up in PATH if path has no slashes
+func NewWebpEncoder(path string, timeout time.Duration) (*WebpEncoder, error) {
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &WebpEncoder{path: resolved, timeout: timeout}, nil
+}
+
+// Encode writes the image in webp format with the quality from 1 to 100
+func (e *WebpEncoder) Encode(w io.Writer, img image.Image, quality int) error {
+	dir, err := ioutil.TempDir("", "webp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// png is lossless, so the quality is defined by webp compression only
+	input := dir + "/input.png"
+	output := dir + "/output.webp"
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(input, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.path, "-quiet", "-q", strconv.Itoa(quality), "-o", output, "--", input)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cwebp failed: %v: %s", err, out)
+	}
+
+	f, err := os.Open(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
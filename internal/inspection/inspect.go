@@ -0,0 +1,142 @@
+package inspection
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/pdf"
+)
+
+// Problems found in a document
+const (
+	ProblemCorrupt       = "corrupt"
+	ProblemEncrypted     = "encrypted"
+	ProblemTooManyPages  = "too_many_pages"
+	ProblemImageTooSmall = "image_too_small"
+	ProblemImageTooLarge = "image_too_large"
+	// ProblemDecompressionLimit means compressed streams of the document expand beyond the limit
+	ProblemDecompressionLimit = "decompression_limit"
+)
+
+// maxDecodePixels limits images which are fully decoded to find truncation
+const maxDecodePixels = 50e6
+
+var errCorruptWebp = errors.New("webp is corrupt")
+
+// Limits of documents, zero means no limit
+type Limits struct {
+	MaxPages     int
+	MinImageSide int
+	MaxImageSide int
+	// MaxDecompressedSize limits the total size of compressed streams which are expanded to inspect a document
+	MaxDecompressedSize int64
+}
+
+// Inspect reads properties of a document of the format detected by validation.
+// Problems are listed in the properties, the caller decides whether to reject the document.
+func Inspect(b []byte, format string, limits Limits) *database.FileProperties {
+	props := &database.FileProperties{Format: format}
+
+	switch format {
+	case "pdf":
+		inspectPdf(b, props, limits)
+	case "jpeg", "png", "gif":
+		inspectImage(b, props, limits)
+	case "webp":
+		if width, height, err := webpSize(b); err != nil {
+			props.Problems = append(props.Problems, ProblemCorrupt)
+		} else {
+			props.Width, props.Height = width, height
+			checkImageSize(props, limits)
+		}
+	}
+
+	return props
+}
+
+func inspectPdf(b []byte, props *database.FileProperties, limits Limits) {
+	info, err := pdf.Inspect(b, limits.MaxDecompressedSize)
+	if err == pdf.ErrDecompressionLimit {
+		props.Problems = append(props.Problems, ProblemDecompressionLimit)
+		return
+	}
+	if err != nil {
+		props.Problems = append(props.Problems, ProblemCorrupt)
+		return
+	}
+
+	props.Pages = info.Pages
+	props.Encrypted = info.Encrypted
+	if info.Encrypted {
+		props.Problems = append(props.Problems, ProblemEncrypted)
+	}
+	if limits.MaxPages > 0 && info.Pages > limits.MaxPages {
+		props.Problems = append(props.Problems, ProblemTooManyPages)
+	}
+}
+
+func inspectImage(b []byte, props *database.FileProperties, limits Limits) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		props.Problems = append(props.Problems, ProblemCorrupt)
+		return
+	}
+	props.Width, props.Height = cfg.Width, cfg.Height
+
+	// do not decode huge images, they may be decompression bombs
+	if !checkImageSize(props, limits) || cfg.Width*cfg.Height > maxDecodePixels {
+		return
+	}
+
+	// a truncated image has a valid header but can not be decoded
+	if _, _, err := image.Decode(bytes.NewReader(b)); err != nil {
+		props.Problems = append(props.Problems, ProblemCorrupt)
+	}
+}
+
+// checkImageSize adds a problem and returns false if the image dimensions are out of limits
+func checkImageSize(props *database.FileProperties, limits Limits) bool {
+	if limits.MaxImageSide > 0 && (props.Width > limits.MaxImageSide || props.Height > limits.MaxImageSide) {
+		props.Problems = append(props.Problems, ProblemImageTooLarge)
+		return false
+	}
+	if limits.MinImageSide > 0 && (props.Width < limits.MinImageSide || props.Height < limits.MinImageSide) {
+		props.Problems = append(props.Problems, ProblemImageTooSmall)
+		return false
+	}
+	return true
+}
+
+// webpSize reads dimensions from the first chunk of a webp image
+func webpSize(b []byte) (int, int, error) {
+	if len(b) < 30 {
+		return 0, 0, errCorruptWebp
+	}
+
+	switch string(b[12:16]) {
+	case "VP8 ":
+		if !bytes.Equal(b[23:26], []byte{0x9d, 0x01, 0x2a}) {
+			return 0, 0, errCorruptWebp
+		}
+		width := int(binary.LittleEndian.Uint16(b[26:28]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(b[28:30]) & 0x3fff)
+		return width, height, nil
+	case "VP8L":
+		if b[20] != 0x2f {
+			return 0, 0, errCorruptWebp
+		}
+		bits := binary.LittleEndian.Uint32(b[21:25])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, nil
+	case "VP8X":
+		width := int(b[24]) | int(b[25])<<8 | int(b[26])<<16
+		height := int(b[27]) | int(b[28])<<8 | int(b[29])<<16
+		return width + 1, height + 1, nil
+	}
+	return 0, 0, errCorruptWebp
+}
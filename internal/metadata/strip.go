@@ -0,0 +1,232 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/Confialink/wallet-files/internal/imaging"
+	"github.com/Confialink/wallet-files/internal/pdf"
+)
+
+var (
+	// ErrUnsupportedFormat means metadata of the format can not be stripped
+	ErrUnsupportedFormat = errors.New("metadata stripping is not supported for the format")
+	// ErrMalformed means the structure of the file can not be parsed
+	ErrMalformed = errors.New("file structure is malformed")
+)
+
+// Supported checks if metadata of the format can be stripped
+func Supported(format string) bool {
+	switch format {
+	case "jpeg", "png", "webp", "pdf":
+		return true
+	}
+	return false
+}
+
+// Strip removes metadata from the file of the format detected by validation:
+// EXIF, XMP and IPTC from images and the information dictionary from pdf documents.
+// JPEG orientation is kept, otherwise photos would be displayed rotated.
+func Strip(b []byte, format string) ([]byte, error) {
+	switch format {
+	case "jpeg":
+		return stripJpeg(b)
+	case "png":
+		return stripPng(b)
+	case "webp":
+		return stripWebp(b)
+	case "pdf":
+		out, ok := pdf.ClearInfo(b)
+		if !ok {
+			return nil, ErrMalformed
+		}
+		return out, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+func stripJpeg(b []byte) ([]byte, error) {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(b))
+	out = append(out, b[:2]...)
+
+	// orientation is written after JFIF segment which must be the first one
+	var exif []byte
+	if o := imaging.Orientation(b); o != 1 {
+		exif = orientationSegment(o)
+	}
+
+	i := 2
+	for i < len(b) {
+		if b[i] != 0xFF {
+			return nil, ErrMalformed
+		}
+		// markers may be preceded by fill bytes
+		for i+1 < len(b) && b[i+1] == 0xFF {
+			i++
+		}
+		if i+1 >= len(b) {
+			return nil, ErrMalformed
+		}
+
+		marker := b[i+1]
+		if marker == 0xD9 {
+			// anything after the end of image is dropped
+			return append(out, 0xFF, 0xD9), nil
+		}
+		if i+4 > len(b) {
+			return nil, ErrMalformed
+		}
+		length := int(binary.BigEndian.Uint16(b[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(b) {
+			return nil, ErrMalformed
+		}
+
+		if keepJpegSegment(marker, b[i+4:end]) {
+			if exif != nil && marker != 0xE0 {
+				out = append(out, exif...)
+				exif = nil
+			}
+			out = append(out, b[i:end]...)
+		}
+		i = end
+
+		if marker == 0xDA {
+			// entropy coded data ends at the first marker which is not a restart marker
+			start := i
+			for i+1 < len(b) && !(b[i] == 0xFF && b[i+1] != 0x00 && (b[i+1] < 0xD0 || b[i+1] > 0xD7)) {
+				i++
+			}
+			if i+1 >= len(b) {
+				return nil, ErrMalformed
+			}
+			out = append(out, b[start:i]...)
+		}
+	}
+	return nil, ErrMalformed
+}
+
+// keepJpegSegment drops comments and application segments except JFIF, color profiles and Adobe color transform
+func keepJpegSegment(marker byte, data []byte) bool {
+	switch {
+	case marker == 0xFE:
+		return false
+	case marker == 0xE0:
+		return true
+	case marker == 0xE2:
+		return bytes.HasPrefix(data, []byte("ICC_PROFILE\x00"))
+	case marker == 0xEE:
+		return bytes.HasPrefix(data, []byte("Adobe"))
+	case marker >= 0xE1 && marker <= 0xEF:
+		return false
+	}
+	return true
+}
+
+// orientationSegment builds an APP1 segment with EXIF which contains only the orientation tag
+func orientationSegment(orientation int) []byte {
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08,
+		// a single entry: tag 0x0112, type SHORT, count 1
+		0x00, 0x01, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01,
+		0x00, byte(orientation), 0x00, 0x00,
+		// no next IFD
+		0x00, 0x00, 0x00, 0x00,
+	}
+	data := append([]byte("Exif\x00\x00"), tiff...)
+
+	segment := []byte{0xFF, 0xE1, 0x00, 0x00}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(data)+2))
+	return append(segment, data...)
+}
+
+// pngMetadataChunks are text, EXIF and modification time chunks
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+func stripPng(b []byte) ([]byte, error) {
+	const signatureLen = 8
+	if len(b) < signatureLen {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(b))
+	out = append(out, b[:signatureLen]...)
+
+	for i := signatureLen; i+8 <= len(b); {
+		length := int(binary.BigEndian.Uint32(b[i : i+4]))
+		chunkType := string(b[i+4 : i+8])
+		end := i + 12 + length
+		if length < 0 || end > len(b) {
+			return nil, ErrMalformed
+		}
+
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, b[i:end]...)
+		}
+		if chunkType == "IEND" {
+			return out, nil
+		}
+		i = end
+	}
+	return nil, ErrMalformed
+}
+
+// VP8X flags of metadata chunks
+const (
+	webpFlagXmp  = 0x04
+	webpFlagExif = 0x08
+)
+
+func stripWebp(b []byte) ([]byte, error) {
+	const headerLen = 12
+	if len(b) < headerLen {
+		return nil, ErrMalformed
+	}
+	riffEnd := 8 + int(binary.LittleEndian.Uint32(b[4:8]))
+	if riffEnd > len(b) {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(b))
+	out = append(out, b[:headerLen]...)
+
+	for i := headerLen; i < riffEnd; {
+		if i+8 > riffEnd {
+			return nil, ErrMalformed
+		}
+		fourcc := string(b[i : i+4])
+		size := int(binary.LittleEndian.Uint32(b[i+4 : i+8]))
+		// chunks are padded to even size
+		end := i + 8 + size + size%2
+		if size < 0 || end > riffEnd {
+			return nil, ErrMalformed
+		}
+
+		switch fourcc {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, b[i:end]...)
+			if size > 0 {
+				chunk[8] &^= webpFlagXmp | webpFlagExif
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, b[i:end]...)
+		}
+		i = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,138 @@
+package metadata
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStripJpegMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", []byte{}},
+		{"no start of image", []byte{0x00, 0x00, 0xFF, 0xD9}},
+		{"zero segment length", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x00, 0xFF, 0xD9}},
+		{"segment length of one", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xD9}},
+		{"segment past the end", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x10, 0x00}},
+		{"truncated length", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00}},
+		{"no marker", []byte{0xFF, 0xD8, 0x00, 0x00}},
+		{"only fill bytes", []byte{0xFF, 0xD8, 0xFF, 0xFF, 0xFF}},
+		{"unterminated scan", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02, 0x01, 0x02}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Strip(tt.b, "jpeg"); err != ErrMalformed {
+				t.Errorf("expected ErrMalformed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStripJpeg(t *testing.T) {
+	jfif := []byte{0xFF, 0xE0, 0x00, 0x07, 'J', 'F', 'I', 'F', 0x00}
+	comment := []byte{0xFF, 0xFE, 0x00, 0x05, 'a', 'b', 'c'}
+	xmp := []byte{0xFF, 0xE1, 0x00, 0x06, 'h', 't', 't', 'p'}
+	scan := []byte{0xFF, 0xDA, 0x00, 0x02, 0x01, 0xFF, 0x00, 0xFF, 0xD0, 0x02}
+
+	in := join([]byte{0xFF, 0xD8}, jfif, comment, xmp, scan, []byte{0xFF, 0xD9}, []byte("trailer"))
+	expected := join([]byte{0xFF, 0xD8}, jfif, scan, []byte{0xFF, 0xD9})
+
+	out, err := Strip(in, "jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected % X, got % X", expected, out)
+	}
+}
+
+func TestStripPngMalformed(t *testing.T) {
+	signature := []byte{0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A}
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"short", signature[:4]},
+		{"no end", signature},
+		{"chunk past the end", join(signature, []byte{0xFF, 0xFF, 0xFF, 0xFF, 'I', 'D', 'A', 'T'})},
+		{"truncated chunk", join(signature, []byte{0x00, 0x00, 0x00, 0x04, 'I', 'D', 'A', 'T', 0x00})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Strip(tt.b, "png"); err != ErrMalformed {
+				t.Errorf("expected ErrMalformed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStripPng(t *testing.T) {
+	signature := []byte{0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A}
+	ihdr := chunk("IHDR", make([]byte, 13))
+	text := chunk("tEXt", []byte("Author\x00me"))
+	iend := chunk("IEND", nil)
+
+	out, err := Strip(join(signature, ihdr, text, iend), "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := join(signature, ihdr, iend); !bytes.Equal(out, expected) {
+		t.Errorf("expected % X, got % X", expected, out)
+	}
+}
+
+func TestStripWebpMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"short", []byte("RIFF")},
+		{"riff past the end", []byte("RIFF\xFF\x00\x00\x00WEBP")},
+		{"truncated chunk header", []byte("RIFF\x08\x00\x00\x00WEBPVP8 ")},
+		{"chunk past the riff", []byte("RIFF\x0C\x00\x00\x00WEBPVP8 \xFF\x00\x00\x00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Strip(tt.b, "webp"); err != ErrMalformed {
+				t.Errorf("expected ErrMalformed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStripWebp(t *testing.T) {
+	vp8x := []byte("VP8X\x0A\x00\x00\x00\x0C\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	vp8 := []byte("VP8 \x02\x00\x00\x00\x01\x02")
+	exif := []byte("EXIF\x03\x00\x00\x00abc\x00")
+	in := riff(vp8x, vp8, exif)
+
+	out, err := Strip(in, "webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleared := append([]byte{}, vp8x...)
+	cleared[8] = 0x00
+	if expected := riff(cleared, vp8); !bytes.Equal(out, expected) {
+		t.Errorf("expected % X, got % X", expected, out)
+	}
+}
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func chunk(chunkType string, data []byte) []byte {
+	length := len(data)
+	// the checksum is not verified
+	return join([]byte{byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)}, []byte(chunkType), data, make([]byte, 4))
+}
+
+func riff(chunks ...[]byte) []byte {
+	body := join(append([][]byte{[]byte("WEBP")}, chunks...)...)
+	size := len(body)
+	return join([]byte("RIFF"), []byte{byte(size), byte(size >> 8), byte(size >> 16), byte(size >> 24)}, body)
+}
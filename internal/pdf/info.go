@@ -0,0 +1,219 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// ErrCorrupt means the document is truncated or is not a pdf
+	ErrCorrupt = errors.New("pdf is corrupt")
+	// ErrDecompressionLimit means compressed streams of the document expand beyond the limit
+	ErrDecompressionLimit = errors.New("pdf streams exceed the decompression limit")
+)
+
+// eofSearchLen is the size of the tail where %%EOF marker must be found
+const eofSearchLen = 2048
+
+var (
+	headerRe  = regexp.MustCompile(`^%PDF-(\d\.\d)`)
+	encryptRe = regexp.MustCompile(`/Encrypt\s*(\d+\s+\d+\s+R|<<)`)
+	pagesRe   = regexp.MustCompile(`/Type\s*/Pages\b`)
+	pageRe    = regexp.MustCompile(`/Type\s*/Page\b`)
+	countRe   = regexp.MustCompile(`/Count\s+(\d+)`)
+	objStmRe  = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+)
+
+// Info is basic information about a pdf document
+type Info struct {
+	Version   string
+	Pages     int
+	Encrypted bool
+}
+
+// Inspect reads version, page count and encryption flag of the document.
+// Page count is not available for encrypted documents which use object streams.
+// Object streams are decompressed up to maxDecompressed bytes in total, zero means no limit.
+func Inspect(b []byte, maxDecompressed int64) (*Info, error) {
+	header := headerRe.FindSubmatch(b)
+	if header == nil {
+		return nil, ErrCorrupt
+	}
+
+	tail := b
+	if len(tail) > eofSearchLen {
+		tail = tail[len(tail)-eofSearchLen:]
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return nil, ErrCorrupt
+	}
+
+	info := &Info{
+		Version:   string(header[1]),
+		Encrypted: encryptRe.Match(b),
+	}
+
+	// page tree objects may be placed into compressed object streams
+	sources := [][]byte{b}
+	if !info.Encrypted {
+		streams, err := objectStreams(b, maxDecompressed)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, streams...)
+	}
+
+	info.Pages = pageTreeCount(sources)
+	if info.Pages == 0 {
+		for _, src := range sources {
+			info.Pages += len(pageRe.FindAllIndex(src, -1))
+		}
+	}
+
+	if info.Pages == 0 && !info.Encrypted {
+		return nil, ErrCorrupt
+	}
+	return info, nil
+}
+
+// pageTreeCount returns the largest /Count of page tree nodes, which is the count of the root node
+func pageTreeCount(sources [][]byte) int {
+	max := 0
+	for _, src := range sources {
+		for _, loc := range pagesRe.FindAllIndex(src, -1) {
+			dict := enclosingDict(src, loc[0])
+			if dict == nil {
+				continue
+			}
+			for _, m := range countRe.FindAllSubmatch(topLevel(dict), -1) {
+				if n, err := strconv.Atoi(string(m[1])); err == nil && n > max {
+					max = n
+				}
+			}
+		}
+	}
+	return max
+}
+
+// objectStreams returns decompressed content of all object streams,
+// ErrDecompressionLimit is returned if the content exceeds the limit
+func objectStreams(b []byte, limit int64) ([][]byte, error) {
+	var result [][]byte
+	var total int64
+	for _, loc := range objStmRe.FindAllIndex(b, -1) {
+		dict := enclosingDict(b, loc[0])
+		if dict == nil || !bytes.Contains(dict, []byte("/FlateDecode")) {
+			continue
+		}
+
+		start := loc[0] + bytes.Index(b[loc[0]:], []byte(">>"))
+		data := streamData(b, start)
+		if data == nil {
+			continue
+		}
+
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			continue
+		}
+		var content []byte
+		if limit > 0 {
+			// a byte over the rest of the limit tells that the content does not fit
+			content, err = ioutil.ReadAll(io.LimitReader(r, limit-total+1))
+			if total += int64(len(content)); total > limit {
+				return nil, ErrDecompressionLimit
+			}
+		} else {
+			content, err = ioutil.ReadAll(r)
+		}
+		if err == nil {
+			result = append(result, content)
+		}
+	}
+	return result, nil
+}
+
+// streamData returns raw data of a stream which follows the dictionary ending after pos
+func streamData(b []byte, pos int) []byte {
+	i := bytes.Index(b[pos:], []byte("stream"))
+	if i < 0 {
+		return nil
+	}
+	start := pos + i + len("stream")
+	if start < len(b) && b[start] == '\r' {
+		start++
+	}
+	if start < len(b) && b[start] == '\n' {
+		start++
+	}
+
+	end := bytes.Index(b[start:], []byte("endstream"))
+	if end < 0 {
+		return nil
+	}
+	return b[start : start+end]
+}
+
+// enclosingDict returns the innermost dictionary which contains pos
+func enclosingDict(b []byte, pos int) []byte {
+	depth := 0
+	start := -1
+	for i := pos; i > 0; i-- {
+		if b[i-1] == '>' && b[i] == '>' {
+			depth++
+			i--
+		} else if b[i-1] == '<' && b[i] == '<' {
+			if depth == 0 {
+				start = i - 1
+				break
+			}
+			depth--
+			i--
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+
+	depth = 0
+	for i := start; i < len(b)-1; i++ {
+		if b[i] == '<' && b[i+1] == '<' {
+			depth++
+			i++
+		} else if b[i] == '>' && b[i+1] == '>' {
+			depth--
+			i++
+			if depth == 0 {
+				return b[start : i+1]
+			}
+		}
+	}
+	return nil
+}
+
+// topLevel removes nested dictionaries from the dictionary content
+func topLevel(dict []byte) []byte {
+	var result []byte
+	depth := 0
+	for i := 0; i < len(dict); i++ {
+		if i+1 < len(dict) && dict[i] == '<' && dict[i+1] == '<' {
+			depth++
+			i++
+			continue
+		}
+		if i+1 < len(dict) && dict[i] == '>' && dict[i+1] == '>' {
+			depth--
+			i++
+			continue
+		}
+		if depth == 1 {
+			result = append(result, dict[i])
+		}
+	}
+	return result
+}
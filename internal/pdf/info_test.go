@@ -0,0 +1,69 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+)
+
+// document builds a minimal pdf with the page tree inside a compressed object stream
+func document(objects []byte) []byte {
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	_, _ = w.Write(objects)
+	_ = w.Close()
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n")
+	fmt.Fprintf(&b, "1 0 obj\n<< /Type /ObjStm /N 1 /First 0 /Filter /FlateDecode /Length %d >>\nstream\n", compressed.Len())
+	b.Write(compressed.Bytes())
+	b.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return b.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	b := document([]byte("<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>"))
+
+	info, err := Inspect(b, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "1.5" || info.Pages != 2 || info.Encrypted {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestInspectDecompressionLimit(t *testing.T) {
+	objects := append([]byte("<< /Type /Pages /Count 1 >>"), bytes.Repeat([]byte{' '}, 1<<20)...)
+	b := document(objects)
+
+	if _, err := Inspect(b, 1<<10); err != ErrDecompressionLimit {
+		t.Errorf("expected ErrDecompressionLimit, got %v", err)
+	}
+	if _, err := Inspect(b, 0); err != nil {
+		t.Errorf("expected no limit, got %v", err)
+	}
+}
+
+func TestInspectMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", []byte{}},
+		{"no header", []byte("1 0 obj\n<< /Type /Page >>\nendobj\n%%EOF")},
+		{"no end of file", []byte("%PDF-1.4\n1 0 obj\n<< /Type /Page >>\nendobj\n")},
+		{"no pages", []byte("%PDF-1.4\n%%EOF")},
+		{"unterminated stream", []byte("%PDF-1.5\n<< /Type /ObjStm /Filter /FlateDecode >>\nstream\nxx%%EOF")},
+		{"unclosed dictionary", []byte("%PDF-1.5\n<< /Type /Pages /Count 3\n%%EOF")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Inspect(tt.b, 1<<20); err != ErrCorrupt {
+				t.Errorf("expected ErrCorrupt, got %v", err)
+			}
+		})
+	}
+}
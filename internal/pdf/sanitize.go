@@ -0,0 +1,127 @@
+package pdf
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var (
+	infoRefRe  = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	metadataRe = regexp.MustCompile(`/Type\s*/Metadata\b`)
+)
+
+// ClearInfo blanks the document information dictionary and uncompressed xmp metadata streams.
+// Content is overwritten with spaces in place, so offsets of the cross-reference table stay valid.
+// false is returned if the information dictionary is placed into a compressed object stream.
+func ClearInfo(b []byte) ([]byte, bool) {
+	out := make([]byte, len(b))
+	copy(out, b)
+
+	cleared := true
+	refs := make(map[string]bool)
+	for _, m := range infoRefRe.FindAllSubmatch(b, -1) {
+		ref := string(m[1]) + " " + string(m[2])
+		if refs[ref] {
+			continue
+		}
+		refs[ref] = true
+		if !blankObject(out, m[1], m[2]) {
+			cleared = false
+		}
+	}
+
+	// metadata of encrypted documents is usually encrypted as well and can't be told apart
+	if !encryptRe.Match(b) {
+		for _, loc := range metadataRe.FindAllIndex(b, -1) {
+			dict := enclosingDict(b, loc[0])
+			if dict == nil || bytes.Contains(dict, []byte("/Filter")) {
+				continue
+			}
+			start := loc[0] + bytes.Index(b[loc[0]:], []byte(">>"))
+			if data := streamData(b, start); data != nil {
+				offset := bytes.Index(b[start:], data) + start
+				blank(out[offset : offset+len(data)])
+			}
+		}
+	}
+
+	return out, cleared
+}
+
+// blankObject blanks content of every definition of the dictionary object,
+// documents with incremental updates may define the object several times
+func blankObject(b []byte, num, gen []byte) bool {
+	re := regexp.MustCompile(`(?:^|[^0-9])` + string(num) + `\s+` + string(gen) + `\s+obj\b`)
+	found := false
+	for _, loc := range re.FindAllIndex(b, -1) {
+		i := loc[1]
+		for i < len(b) && isSpace(b[i]) {
+			i++
+		}
+		if i+1 >= len(b) || b[i] != '<' || b[i+1] != '<' {
+			continue
+		}
+		end := dictEnd(b, i)
+		if end < 0 {
+			continue
+		}
+		blank(b[i+2 : end])
+		found = true
+	}
+	return found
+}
+
+// dictEnd returns the position of ">>" closing the dictionary started at pos, literal strings are skipped
+func dictEnd(b []byte, pos int) int {
+	depth := 0
+	for i := pos; i < len(b)-1; i++ {
+		switch {
+		case b[i] == '(':
+			i = stringEnd(b, i)
+			if i < 0 {
+				return -1
+			}
+		case b[i] == '<' && b[i+1] == '<':
+			depth++
+			i++
+		case b[i] == '>' && b[i+1] == '>':
+			depth--
+			if depth == 0 {
+				return i
+			}
+			i++
+		}
+	}
+	return -1
+}
+
+// stringEnd returns the position of ")" closing the literal string started at pos
+func stringEnd(b []byte, pos int) int {
+	depth := 0
+	for i := pos; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func blank(b []byte) {
+	for i := range b {
+		if b[i] != '\r' && b[i] != '\n' {
+			b[i] = ' '
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
@@ -0,0 +1,99 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestClearInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       string
+		cleared bool
+		removed []string
+		kept    []string
+	}{
+		{
+			name:    "info dictionary",
+			b:       "%PDF-1.4\n5 0 obj\n<< /Author (John Doe) /Creator (Scanner) >>\nendobj\ntrailer\n<< /Info 5 0 R >>\n%%EOF",
+			cleared: true,
+			removed: []string{"John Doe", "Scanner"},
+			kept:    []string{"/Info 5 0 R"},
+		},
+		{
+			name: "incremental update",
+			b: "%PDF-1.4\n5 0 obj\n<< /Author (First) >>\nendobj\ntrailer\n<< /Info 5 0 R >>\n%%EOF\n" +
+				"5 0 obj\n<< /Author (Second) >>\nendobj\ntrailer\n<< /Info 5 0 R /Prev 9 >>\n%%EOF",
+			cleared: true,
+			removed: []string{"First", "Second"},
+		},
+		{
+			name:    "delimiters in strings",
+			b:       "%PDF-1.4\n5 0 obj\n<< /Title (a >> b \\) << c) /Author (Doe) >>\nendobj\ntrailer\n<< /Info 5 0 R >>\n%%EOF",
+			cleared: true,
+			removed: []string{"Doe", "/Title"},
+		},
+		{
+			name:    "other object numbers",
+			b:       "%PDF-1.4\n15 0 obj\n<< /Author (Keep) >>\nendobj\n5 0 obj\n<< /Author (Drop) >>\nendobj\ntrailer\n<< /Info 5 0 R >>\n%%EOF",
+			cleared: true,
+			removed: []string{"Drop"},
+			kept:    []string{"Keep"},
+		},
+		{
+			name:    "info in object stream",
+			b:       "%PDF-1.5\n3 0 obj\n<< /Type /ObjStm /N 1 /First 4 /Filter /FlateDecode >>\nendobj\ntrailer\n<< /Info 5 0 R >>\n%%EOF",
+			cleared: false,
+		},
+		{
+			name:    "unclosed info dictionary",
+			b:       "%PDF-1.4\n5 0 obj\n<< /Author (Doe\ntrailer\n<< /Info 5 0 R >>\n%%EOF",
+			cleared: false,
+			kept:    []string{"Doe"},
+		},
+		{
+			name:    "uncompressed xmp",
+			b:       "%PDF-1.4\n7 0 obj\n<< /Type /Metadata /Subtype /XML /Length 20 >>\nstream\n<x:creator>Doe</x>\nendstream\nendobj\n%%EOF",
+			cleared: true,
+			removed: []string{"Doe"},
+		},
+		{
+			name:    "compressed xmp",
+			b:       "%PDF-1.4\n7 0 obj\n<< /Type /Metadata /Filter /FlateDecode /Length 3 >>\nstream\nDoe\nendstream\nendobj\n%%EOF",
+			cleared: true,
+			kept:    []string{"Doe"},
+		},
+		{
+			name:    "encrypted xmp",
+			b:       "%PDF-1.4\n7 0 obj\n<< /Type /Metadata /Length 3 >>\nstream\nDoe\nendstream\nendobj\ntrailer\n<< /Encrypt 8 0 R >>\n%%EOF",
+			cleared: true,
+			kept:    []string{"Doe"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := []byte(tt.b)
+			out, cleared := ClearInfo(b)
+			if cleared != tt.cleared {
+				t.Errorf("expected cleared %v, got %v", tt.cleared, cleared)
+			}
+			if len(out) != len(b) || bytes.Count(out, []byte("\n")) != bytes.Count(b, []byte("\n")) {
+				t.Errorf("offsets changed: %q", out)
+			}
+			if string(b) != tt.b {
+				t.Error("input modified")
+			}
+			for _, s := range tt.removed {
+				if bytes.Contains(out, []byte(s)) {
+					t.Errorf("%q not removed: %q", s, out)
+				}
+			}
+			for _, s := range tt.kept {
+				if !bytes.Contains(out, []byte(s)) {
+					t.Errorf("%q removed: %q", s, out)
+				}
+			}
+		})
+	}
+}
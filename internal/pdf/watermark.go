@@ -0,0 +1,317 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/Confialink/wallet-files/internal/imaging"
+)
+
+var (
+	// ErrEncrypted means content can not be added to the document without its password
+	ErrEncrypted = errors.New("pdf is encrypted")
+	// ErrUnsupported means the document structure can not be updated, e.g. pages are in object streams
+	ErrUnsupported = errors.New("pdf structure is not supported")
+)
+
+// watermarkLineHeight is the height of a text line in font pixels
+const watermarkLineHeight = 15
+
+var (
+	objRe       = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	startxrefRe = regexp.MustCompile(`startxref\s+(\d+)`)
+	mediaBoxRe  = regexp.MustCompile(`/MediaBox\s*\[\s*([-+\d.]+)\s+([-+\d.]+)\s+([-+\d.]+)\s+([-+\d.]+)\s*\]`)
+	contentsRe  = regexp.MustCompile(`/Contents\s*(\[[^\]]*\]|\d+\s+\d+\s+R)`)
+	sizeRe      = regexp.MustCompile(`/Size\s+(\d+)`)
+	rootRe      = regexp.MustCompile(`/Root\s+\d+\s+\d+\s+R`)
+	idRe        = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+)
+
+type pageObject struct {
+	num  int
+	gen  int
+	dict []byte
+}
+
+type trailer struct {
+	size   int
+	root   []byte
+	id     []byte
+	prev   int
+	stream bool
+}
+
+// Watermark appends an incremental update which draws the text lines diagonally over every page.
+// The original objects are not changed, pages are redefined to draw the watermark after their content.
+// The watermark deters casual sharing only: the original document is a prefix of the result, so it is
+// restored by truncating the output after the original %%EOF marker.
+// Object streams are decompressed up to maxDecompressed bytes as Inspect does.
+func Watermark(b []byte, lines []string, maxDecompressed int64) ([]byte, error) {
+	if encryptRe.Match(b) {
+		return nil, ErrEncrypted
+	}
+	info, err := Inspect(b, maxDecompressed)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, defaultBox := findPages(b)
+	if len(pages) == 0 || len(pages) < info.Pages {
+		return nil, ErrUnsupported
+	}
+	t, err := readTrailer(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Write(b)
+	if b[len(b)-1] != '\n' {
+		out.WriteByte('\n')
+	}
+
+	offsets := make(map[int]int)
+	gens := make(map[int]int)
+	next := t.size
+	writeObject := func(num, gen int, body []byte) {
+		offsets[num] = out.Len()
+		gens[num] = gen
+		fmt.Fprintf(&out, "%d %d obj\n", num, gen)
+		out.Write(body)
+		out.WriteString("\nendobj\n")
+	}
+	writeStream := func(num int, content []byte) {
+		writeObject(num, 0, []byte(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content)))
+	}
+
+	// the original content is isolated, so its graphics state does not affect the watermark
+	saveRef := next
+	next++
+	writeStream(saveRef, []byte("q"))
+
+	for _, page := range pages {
+		box := defaultBox
+		if m := mediaBoxRe.FindSubmatch(page.dict); m != nil {
+			box = parseBox(m)
+		}
+
+		markRef := next
+		next++
+		writeStream(markRef, append([]byte("Q\n"), watermarkContent(box, lines)...))
+		writeObject(page.num, page.gen, replaceContents(page.dict, saveRef, markRef))
+	}
+
+	if t.stream {
+		writeXrefStream(&out, t, offsets, gens, next)
+	} else {
+		writeXrefTable(&out, t, offsets, gens, next)
+	}
+	return out.Bytes(), nil
+}
+
+// findPages returns the latest definitions of uncompressed page objects ordered by number,
+// and the media box inherited from the page tree
+func findPages(b []byte) ([]*pageObject, [4]float64) {
+	defaultBox := [4]float64{0, 0, pageWidth, pageHeight}
+	byNum := make(map[int]*pageObject)
+
+	for _, m := range objRe.FindAllSubmatchIndex(b, -1) {
+		i := m[1]
+		for i < len(b) && isSpace(b[i]) {
+			i++
+		}
+		if i+1 >= len(b) || b[i] != '<' || b[i+1] != '<' {
+			continue
+		}
+		end := dictEnd(b, i)
+		if end < 0 {
+			continue
+		}
+		dict := b[i : end+2]
+		top := topLevel(dict)
+
+		num, _ := strconv.Atoi(string(b[m[2]:m[3]]))
+		gen, _ := strconv.Atoi(string(b[m[4]:m[5]]))
+		switch {
+		case pageRe.Match(top):
+			byNum[num] = &pageObject{num: num, gen: gen, dict: dict}
+		case pagesRe.Match(top):
+			if box := mediaBoxRe.FindSubmatch(dict); box != nil {
+				defaultBox = parseBox(box)
+			}
+		}
+	}
+
+	pages := make([]*pageObject, 0, len(byNum))
+	for _, page := range byNum {
+		pages = append(pages, page)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].num < pages[j].num })
+	return pages, defaultBox
+}
+
+// readTrailer reads the trailer of the latest cross-reference section,
+// which is either a classic table or a cross-reference stream
+func readTrailer(b []byte) (*trailer, error) {
+	matches := startxrefRe.FindAllSubmatch(b, -1)
+	if matches == nil {
+		return nil, ErrUnsupported
+	}
+	prev, err := strconv.Atoi(string(matches[len(matches)-1][1]))
+	if err != nil || prev >= len(b) {
+		return nil, ErrUnsupported
+	}
+
+	t := &trailer{prev: prev}
+	start := -1
+	if bytes.HasPrefix(b[prev:], []byte("xref")) {
+		if i := bytes.Index(b[prev:], []byte("trailer")); i >= 0 {
+			start = prev + i + bytes.Index(b[prev+i:], []byte("<<"))
+		}
+	} else if loc := objRe.FindIndex(b[prev:]); loc != nil && loc[0] == 0 {
+		t.stream = true
+		start = prev + loc[1] + bytes.Index(b[prev+loc[1]:], []byte("<<"))
+	}
+	if start < prev {
+		return nil, ErrUnsupported
+	}
+	end := dictEnd(b, start)
+	if end < 0 {
+		return nil, ErrUnsupported
+	}
+	dict := b[start : end+2]
+
+	size := sizeRe.FindSubmatch(dict)
+	t.root = rootRe.Find(dict)
+	if size == nil || t.root == nil {
+		return nil, ErrUnsupported
+	}
+	t.size, _ = strconv.Atoi(string(size[1]))
+	if id := idRe.Find(dict); id != nil {
+		t.id = append([]byte(" "), id...)
+	}
+	return t, nil
+}
+
+// replaceContents makes the page draw the saved state, the original content and the watermark
+func replaceContents(dict []byte, saveRef, markRef int) []byte {
+	m := contentsRe.FindSubmatchIndex(dict)
+	if m == nil {
+		// a page without content gets only the watermark
+		return append(append([]byte{}, dict[:len(dict)-2]...),
+			[]byte(fmt.Sprintf(" /Contents [%d 0 R %d 0 R] >>", saveRef, markRef))...)
+	}
+
+	original := bytes.Trim(dict[m[2]:m[3]], "[]")
+	contents := fmt.Sprintf("/Contents [%d 0 R %s %d 0 R]", saveRef, original, markRef)
+
+	result := append([]byte{}, dict[:m[0]]...)
+	result = append(result, contents...)
+	return append(result, dict[m[1]:]...)
+}
+
+// watermarkContent draws the text lines diagonally across the center of the box in light gray.
+// Glyph pixels are drawn as rectangles, so the page needs no font resources.
+func watermarkContent(box [4]float64, lines []string) []byte {
+	blockWidth := 0
+	rendered := make([][]byte, len(lines))
+	var content bytes.Buffer
+	for i, line := range lines {
+		mask := imaging.RenderText(line)
+		if mask.Rect.Dx() > blockWidth {
+			blockWidth = mask.Rect.Dx()
+		}
+
+		// horizontal runs of pixels are merged into single rectangles
+		var rects bytes.Buffer
+		for y := 0; y < mask.Rect.Dy(); y++ {
+			for x := 0; x < mask.Rect.Dx(); {
+				if mask.AlphaAt(x, y).A == 0 {
+					x++
+					continue
+				}
+				start := x
+				for x < mask.Rect.Dx() && mask.AlphaAt(x, y).A != 0 {
+					x++
+				}
+				fmt.Fprintf(&rects, "%d %d %d 1 re\n", start, -(i*watermarkLineHeight + y + 1), x-start)
+			}
+		}
+		rendered[i] = rects.Bytes()
+	}
+	if blockWidth == 0 {
+		return nil
+	}
+
+	width, height := box[2]-box[0], box[3]-box[1]
+	diagonal := math.Hypot(width, height)
+	angle := math.Atan2(height, width)
+	blockHeight := float64(len(lines) * watermarkLineHeight)
+
+	// the text takes 60% of the diagonal, but glyphs are not higher than a tenth of the page
+	scale := math.Min(diagonal*0.6/float64(blockWidth), height/10/watermarkLineHeight)
+
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	fmt.Fprintf(&content, "q 0.7 g\n%.4f %.4f %.4f %.4f %.2f %.2f cm\n", cos, sin, -sin, cos, box[0]+width/2, box[1]+height/2)
+	fmt.Fprintf(&content, "%.4f 0 0 %.4f 0 0 cm\n1 0 0 1 %.1f %.1f cm\n", scale, scale, -float64(blockWidth)/2, blockHeight/2)
+	for _, rects := range rendered {
+		content.Write(rects)
+	}
+	content.WriteString("f Q")
+	return content.Bytes()
+}
+
+func writeXrefTable(out *bytes.Buffer, t *trailer, offsets, gens map[int]int, size int) {
+	xref := out.Len()
+	out.WriteString("xref\n")
+	for _, num := range sortedKeys(offsets) {
+		fmt.Fprintf(out, "%d 1\n%010d %05d n \n", num, offsets[num], gens[num])
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d %s /Prev %d%s >>\nstartxref\n%d\n%%%%EOF\n", size, t.root, t.prev, t.id, xref)
+}
+
+// writeXrefStream writes an uncompressed cross-reference stream, which must follow a stream section
+func writeXrefStream(out *bytes.Buffer, t *trailer, offsets, gens map[int]int, next int) {
+	num := next
+	xref := out.Len()
+	offsets[num] = xref
+	gens[num] = 0
+
+	var index, data bytes.Buffer
+	for _, n := range sortedKeys(offsets) {
+		fmt.Fprintf(&index, "%d 1 ", n)
+		row := make([]byte, 7)
+		row[0] = 1
+		binary.BigEndian.PutUint32(row[1:5], uint32(offsets[n]))
+		binary.BigEndian.PutUint16(row[5:7], uint16(gens[n]))
+		data.Write(row)
+	}
+
+	fmt.Fprintf(out, "%d 0 obj\n<< /Type /XRef /Size %d %s /Prev %d%s /W [1 4 2] /Index [%s] /Length %d >>\nstream\n",
+		num, next+1, t.root, t.prev, t.id, bytes.TrimSpace(index.Bytes()), data.Len())
+	out.Write(data.Bytes())
+	fmt.Fprintf(out, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", xref)
+}
+
+func parseBox(m [][]byte) [4]float64 {
+	var box [4]float64
+	for i := range box {
+		box[i], _ = strconv.ParseFloat(string(m[i+1]), 64)
+	}
+	return box
+}
+
+func sortedKeys(m map[int]int) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
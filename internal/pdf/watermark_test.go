@@ -0,0 +1,80 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWatermark(t *testing.T) {
+	tests := []struct {
+		name  string
+		sizes [][2]int
+	}{
+		{"single page", [][2]int{{600, 800}}},
+		{"several pages", [][2]int{{600, 800}, {800, 600}, {10, 10}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := jpegPages(t, tt.sizes...)
+			out, err := Watermark(b, []string{"John Doe", "2026-10-19"}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.HasPrefix(out, b) {
+				t.Error("original document is not kept")
+			}
+			checkXref(t, out, 1)
+			if !bytes.Contains(out, []byte("/Prev ")) {
+				t.Error("update does not reference the previous section")
+			}
+			info, err := Inspect(out, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Pages != len(tt.sizes) {
+				t.Errorf("expected %d pages, got %d", len(tt.sizes), info.Pages)
+			}
+			if n := strings.Count(string(out[len(b):]), " re\n"); n == 0 {
+				t.Error("watermark is not drawn")
+			}
+
+			pages, _ := findPages(out)
+			for _, page := range pages {
+				if !contentsRe.Match(page.dict) || bytes.Count(page.dict, []byte(" 0 R")) < 3 {
+					t.Errorf("page %d does not draw the watermark: %s", page.num, page.dict)
+				}
+			}
+		})
+	}
+}
+
+func TestWatermarkMalformed(t *testing.T) {
+	valid := jpegPages(t, [2]int{10, 10})
+	xref := bytes.LastIndex(valid, []byte("startxref"))
+
+	tests := []struct {
+		name     string
+		b        []byte
+		expected error
+	}{
+		{"empty", []byte{}, ErrCorrupt},
+		{"not a pdf", []byte("hello"), ErrCorrupt},
+		{"encrypted", append(append([]byte{}, valid[:xref]...), []byte("trailer << /Encrypt 9 0 R >>\n%%EOF")...), ErrEncrypted},
+		{"pages in object stream", document([]byte("<< /Type /Pages /Kids [3 0 R] /Count 1 >> << /Type /Page >>")), ErrUnsupported},
+		{"no startxref", append(append([]byte{}, valid[:xref]...), []byte("%%EOF")...), ErrUnsupported},
+		{"startxref out of range", append(append([]byte{}, valid[:xref]...), []byte("startxref\n99999999\n%%EOF")...), ErrUnsupported},
+		{"startxref to content", append(append([]byte{}, valid[:xref]...), []byte("startxref\n0\n%%EOF")...), ErrUnsupported},
+		{"trailer without root", bytes.Replace(valid, []byte("/Root"), []byte("/Rook"), 1), ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Watermark(tt.b, []string{"John Doe"}, 1<<20); err != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package pdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// A4 page size and margins in points
+const (
+	pageWidth   = 595.28
+	pageHeight  = 841.89
+	pageMargin  = 36.0
+	catalogRef  = 1
+	pageTreeRef = 2
+)
+
+// Writer writes a pdf document with a page per image
+type Writer struct {
+	w       *bufio.Writer
+	offset  int64
+	offsets []int64
+	pages   []int
+	err     error
+}
+
+// NewWriter creates a writer of a document and writes the header
+func NewWriter(w io.Writer) *Writer {
+	pw := &Writer{w: bufio.NewWriter(w)}
+	// the binary comment marks the file as binary for transfer tools
+	pw.printf("%%PDF-1.4\n%%\xE2\xE3\xCF\xD3\n")
+	// catalog and page tree are written on close, their numbers are reserved
+	pw.offsets = make([]int64, pageTreeRef)
+	return pw
+}
+
+// AddJpegPage adds an A4 page with the jpeg image of the size in pixels and 3 color components.
+// Page orientation follows the image and the image is scaled to fit the page keeping aspect ratio.
+func (pw *Writer) AddJpegPage(jpeg []byte, width, height int) error {
+	pageW, pageH := pageWidth, pageHeight
+	if width > height {
+		pageW, pageH = pageH, pageW
+	}
+
+	scale := (pageW - 2*pageMargin) / float64(width)
+	if s := (pageH - 2*pageMargin) / float64(height); s < scale {
+		scale = s
+	}
+	w := float64(width) * scale
+	h := float64(height) * scale
+	x := (pageW - w) / 2
+	y := (pageH - h) / 2
+
+	imageRef := pw.beginObject()
+	pw.printf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB"+
+		" /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n", width, height, len(jpeg))
+	pw.write(jpeg)
+	pw.printf("\nendstream\nendobj\n")
+
+	content := fmt.Sprintf("q %.2f 0 0 %.2f %.2f %.2f cm /Im0 Do Q", w, h, x, y)
+	contentRef := pw.beginObject()
+	pw.printf("<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+
+	pageRef := pw.beginObject()
+	pw.printf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f]"+
+		" /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+		pageTreeRef, pageW, pageH, imageRef, contentRef)
+	pw.pages = append(pw.pages, pageRef)
+
+	return pw.err
+}
+
+// Close writes the page tree, the cross-reference table and the trailer
+func (pw *Writer) Close() error {
+	pw.offsets[catalogRef-1] = pw.offset
+	pw.printf("%d 0 obj\n<< /Type /Catalog /Pages %d 0 R >>\nendobj\n", catalogRef, pageTreeRef)
+
+	pw.offsets[pageTreeRef-1] = pw.offset
+	pw.printf("%d 0 obj\n<< /Type /Pages /Kids [", pageTreeRef)
+	for _, ref := range pw.pages {
+		pw.printf("%d 0 R ", ref)
+	}
+	pw.printf("] /Count %d >>\nendobj\n", len(pw.pages))
+
+	xref := pw.offset
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)+1)
+	for _, offset := range pw.offsets {
+		pw.printf("%010d 00000 n \n", offset)
+	}
+	pw.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets)+1, catalogRef, xref)
+
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
+
+// beginObject starts a new object and returns its number
+func (pw *Writer) beginObject() int {
+	pw.offsets = append(pw.offsets, pw.offset)
+	ref := len(pw.offsets)
+	pw.printf("%d 0 obj\n", ref)
+	return ref
+}
+
+func (pw *Writer) printf(format string, args ...interface{}) {
+	pw.write([]byte(fmt.Sprintf(format, args...)))
+}
+
+func (pw *Writer) write(b []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	pw.err = err
+}
@@ -0,0 +1,91 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var xrefEntryRe = regexp.MustCompile(`(\d{10}) (\d{5}) n`)
+
+// checkXref checks that entries of the cross-reference table of the section point to objects
+func checkXref(t *testing.T, b []byte, section int) {
+	xref, err := strconv.Atoi(string(startxrefRe.FindAllSubmatch(b, -1)[section][1]))
+	if err != nil || !bytes.HasPrefix(b[xref:], []byte("xref")) {
+		t.Fatalf("startxref does not point to a table")
+	}
+	table := b[xref : xref+bytes.Index(b[xref:], []byte("trailer"))]
+	for _, m := range xrefEntryRe.FindAllSubmatch(table, -1) {
+		offset, _ := strconv.Atoi(string(m[1]))
+		if loc := objRe.FindIndex(b[offset:]); loc == nil || loc[0] != 0 {
+			t.Errorf("entry %s does not point to an object", m[0])
+		}
+	}
+}
+
+func jpegPages(t *testing.T, sizes ...[2]int) []byte {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	for _, size := range sizes {
+		if err := w.AddJpegPage([]byte("jpeg data"), size[0], size[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		sizes    [][2]int
+		mediaBox string
+	}{
+		{"no pages", nil, ""},
+		{"portrait", [][2]int{{600, 800}}, "/MediaBox [0 0 595.28 841.89]"},
+		{"landscape", [][2]int{{800, 600}}, "/MediaBox [0 0 841.89 595.28]"},
+		{"several pages", [][2]int{{100, 100}, {1, 4000}, {4000, 1}}, "/MediaBox [0 0 595.28 841.89]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := jpegPages(t, tt.sizes...)
+			checkXref(t, b, 0)
+			if !bytes.Contains(b, []byte(tt.mediaBox)) {
+				t.Errorf("expected %s", tt.mediaBox)
+			}
+
+			if len(tt.sizes) == 0 {
+				return
+			}
+			info, err := Inspect(b, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Pages != len(tt.sizes) {
+				t.Errorf("expected %d pages, got %d", len(tt.sizes), info.Pages)
+			}
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestWriterError(t *testing.T) {
+	w := NewWriter(failingWriter{})
+	// the header is buffered, a large image flushes it
+	if err := w.AddJpegPage(make([]byte, 1<<16), 10, 10); err == nil {
+		t.Error("expected error")
+	}
+	if err := w.Close(); err == nil {
+		t.Error("expected error on close")
+	}
+}
@@ -23,6 +23,8 @@ const (
 	ViewAdminProfiles   = Permission("view_admin_profiles")
 	ModifyUserProfiles  = Permission("modify_user_profiles")
 	ModifyAdminProfiles = Permission("modify_admin_profiles")
+	EraseUserFiles      = Permission("erase_user_files")
+	ManageFileRetention = Permission("manage_file_retention")
 )
 
 type Policy func(interface{}, *users.User) bool
@@ -126,6 +128,42 @@ func (p *PermissionsService) CanAdminUploadFiles(filesOwner interface{}, user *u
 	return p.CheckPermission(actionKey, user)
 }
 
+// CanAdminEraseFiles checks if admin can erase all files of the user, permissions to modify profiles are not enough
+func (p *PermissionsService) CanAdminEraseFiles(filesOwner interface{}, user *users.User) bool {
+	owner := filesOwner.(*users.User)
+	if acl.RolesHelper.FromName(owner.RoleName) > acl.RolesHelper.FromName(user.RoleName) {
+		return false
+	}
+
+	return p.CheckPermission(EraseUserFiles, user)
+}
+
+// CanAdminManageRetention checks if admin can set legal hold and retention of the file. Permissions to delete
+// files do not grant it, otherwise admins could lift holds which stop them from deleting.
+func (p *PermissionsService) CanAdminManageRetention(file interface{}, user *users.User) bool {
+	f := file.(*database.FileModel)
+	fileOwner, err := p.usersService.GetByUID(f.UserId)
+	if err != nil {
+		p.logger.New("method", "CanAdminManageRetention", "err", err)
+		return false
+	}
+	if acl.RolesHelper.FromName(fileOwner.RoleName) > acl.RolesHelper.FromName(user.RoleName) {
+		return false
+	}
+
+	return p.CheckPermission(ManageFileRetention, user)
+}
+
+// CanAdminManageQuarantine checks if admin can see and manage quarantined files of any user
+func (p *PermissionsService) CanAdminManageQuarantine(_ interface{}, user *users.User) bool {
+	return p.CheckPermission(ModifyUserProfiles, user)
+}
+
+// CanAdminReadDuplicates checks if admin can see which users uploaded the same documents
+func (p *PermissionsService) CanAdminReadDuplicates(_ interface{}, user *users.User) bool {
+	return p.CheckPermission(ViewUserProfiles, user)
+}
+
 // CheckPermission calls permission service in order to check if user granted permission
 func (p *PermissionsService) CheckPermission(permissionValue interface{}, user *users.User) bool {
 	perm := permissionValue.(Permission)
@@ -43,15 +43,34 @@ func initRoutes() {
 			mwRequestedUser := http.RequestedUser(c.UsersService())
 			v1Group.GET("/files/:id", mwRequestedFile, permChecker.CanWithFile(auth.ReadAction), fileHandler.GetHandler)
 			v1Group.DELETE("/files/:id", mwRequestedFile, permChecker.CanWithFile(auth.DeleteAction), fileHandler.DeleteHandler)
+			v1Group.PUT("/files/:id/collection", mwRequestedFile, permChecker.CanWithFileResource(auth.UpdateAction, auth.FilesMoveResource), fileHandler.MoveFileHandler)
+			v1Group.GET("/quarantine", permChecker.Can(auth.ReadListAction, auth.QuarantineResource), fileHandler.GetQuarantinedHandler)
+			v1Group.POST("/quarantine/:id/release", mwRequestedFile, permChecker.Can(auth.UpdateAction, auth.QuarantineResource), fileHandler.ReleaseHandler)
+			v1Group.POST("/files/:id/scan", mwRequestedFile, permChecker.Can(auth.UpdateAction, auth.QuarantineResource), fileHandler.RescanHandler)
+			v1Group.GET("/replication", permChecker.Can(auth.ReadAction, auth.ReplicationResource), fileHandler.GetReplicationBacklogHandler)
+			v1Group.GET("/files/:id/duplicates", mwRequestedFile, permChecker.Can(auth.ReadListAction, auth.DuplicatesResource), fileHandler.GetDuplicatesHandler)
+			v1Group.PUT("/files/:id/retention", mwRequestedFile, permChecker.CanWithFileResource(auth.UpdateAction, auth.FilesRetentionResource), fileHandler.UpdateRetentionHandler)
 			v1Group.POST("/files/public/:uid", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.CreateAction, auth.FilesUploadPublicResource), fileHandler.CreatePublicHandler)
 			v1Group.POST("/files/private/:uid", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.CreateAction, auth.FilesUploadPrivateResource), fileHandler.CreatePrivateHandler)
 			v1Group.POST("/files/admin-only/:uid", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.CreateAction, auth.FilesUploadPrivateResource), fileHandler.CreateAdminOnlyHandler)
 			v1Group.POST("/files/profile-image", fileHandler.CreateProfileImageHandler)
+			v1Group.POST("/files/archive", fileHandler.ArchiveHandler)
+			v1Group.POST("/files/bulk-delete", fileHandler.BulkDeleteHandler)
 
 			usersGroup := v1Group.Group("/users")
 			{
 
 				usersGroup.GET("/:uid", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.ReadListAction, auth.FilesResource), fileHandler.GetUserFilesHandler)
+				usersGroup.POST("/:uid/erase", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.DeleteAction, auth.FilesErasureResource), fileHandler.EraseUserFilesHandler)
+				usersGroup.POST("/:uid/combine", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.CreateAction, auth.FilesUploadPrivateResource), fileHandler.CombineImagesHandler)
+				usersGroup.GET("/:uid/collections", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.ReadListAction, auth.CollectionsResource), fileHandler.GetCollectionsHandler)
+				usersGroup.POST("/:uid/collections", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.CreateAction, auth.CollectionsResource), fileHandler.CreateCollectionHandler)
+				usersGroup.PUT("/:uid/collections/:collectionId", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.UpdateAction, auth.CollectionsResource), fileHandler.UpdateCollectionHandler)
+				usersGroup.DELETE("/:uid/collections/:collectionId", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.DeleteAction, auth.CollectionsResource), fileHandler.DeleteCollectionHandler)
+				usersGroup.GET("/:uid/export", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.ReadAction, auth.FilesExportResource), fileHandler.ExportUserFilesHandler)
+				usersGroup.POST("/:uid/exports", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.CreateAction, auth.FilesExportResource), fileHandler.CreateExportHandler)
+				usersGroup.GET("/:uid/exports/:exportId", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.ReadAction, auth.FilesExportResource), fileHandler.GetExportHandler)
+				usersGroup.GET("/:uid/exports/:exportId/download", mwRequestedUser, http.OwnerOrAdminOrRoot, permChecker.CanWithUser(auth.ReadAction, auth.FilesExportResource), fileHandler.DownloadExportHandler)
 			}
 
 			storageGroup := v1Group.Group("storage")
@@ -0,0 +1,205 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts a single INSTREAM session, sends the content it received to streams and answers with reply
+func fakeClamd(t *testing.T, network string, address string, reply string, streams chan<- []byte) net.Listener {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		command, err := r.ReadString(0)
+		if err != nil || command != "zINSTREAM\x00" {
+			streams <- nil
+			return
+		}
+
+		var stream []byte
+		size := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(r, size); err != nil {
+				streams <- nil
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			if n > clamdChunkSize {
+				t.Errorf("chunk of %d bytes is larger than %d", n, clamdChunkSize)
+			}
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				streams <- nil
+				return
+			}
+			stream = append(stream, chunk...)
+		}
+		streams <- stream
+
+		if reply != "" {
+			_, _ = conn.Write([]byte(reply + "\x00"))
+		}
+	}()
+	return l
+}
+
+func TestClamdScan(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		content   []byte
+		infected  bool
+		signature string
+		err       bool
+	}{
+		{name: "clean", reply: "stream: OK", content: []byte("hello")},
+		{name: "empty", reply: "stream: OK", content: []byte{}},
+		{name: "several chunks", reply: "stream: OK", content: bytes.Repeat([]byte("x"), clamdChunkSize*2+10)},
+		{
+			name:      "infected",
+			reply:     "stream: Win.Test.EICAR_HDB-1 FOUND",
+			content:   []byte("X5O!P%@AP"),
+			infected:  true,
+			signature: "Win.Test.EICAR_HDB-1",
+		},
+		{name: "size limit", reply: "INSTREAM size limit exceeded. ERROR", content: []byte("big"), err: true},
+		{name: "no reply", content: []byte("hello"), err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams := make(chan []byte, 1)
+			l := fakeClamd(t, "tcp", "127.0.0.1:0", tt.reply, streams)
+			defer l.Close()
+
+			c, err := NewClamd("tcp://"+l.Addr().String(), 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := c.Scan(bytes.NewReader(tt.content))
+			if stream := <-streams; !bytes.Equal(stream, tt.content) {
+				t.Errorf("clamd received %d bytes, expected %d", len(stream), len(tt.content))
+			}
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected error, got %+v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Infected != tt.infected || result.Signature != tt.signature {
+				t.Errorf("expected infected=%v signature=%q, got %+v", tt.infected, tt.signature, result)
+			}
+		})
+	}
+}
+
+func TestClamdScanUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clamd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "clamd.sock")
+	streams := make(chan []byte, 1)
+	l := fakeClamd(t, "unix", socket, "stream: OK", streams)
+	defer l.Close()
+
+	c, err := NewClamd("unix://"+socket, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.Scan(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Infected {
+		t.Errorf("expected clean result, got %+v", result)
+	}
+	<-streams
+}
+
+func TestClamdScanUnavailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	_ = l.Close()
+
+	c, err := NewClamd("tcp://"+address, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Scan(strings.NewReader("hello")); err == nil {
+		t.Error("expected connection error")
+	}
+}
+
+func TestNewClamdInvalidAddress(t *testing.T) {
+	for _, address := range []string{"", "clamav:3310", "http://clamav:3310", "://"} {
+		if _, err := NewClamd(address, time.Second); err == nil {
+			t.Errorf("expected error for %q", address)
+		}
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	tests := []struct {
+		reply     string
+		infected  bool
+		signature string
+		err       bool
+	}{
+		{reply: "stream: OK"},
+		{reply: "OK"},
+		{reply: "stream: Eicar-Signature FOUND", infected: true, signature: "Eicar-Signature"},
+		{reply: "stream: Some Name With Spaces FOUND", infected: true, signature: "Some Name With Spaces"},
+		{reply: "stream: Can't allocate memory ERROR", err: true},
+		{reply: "", err: true},
+		{reply: "stream: ", err: true},
+	}
+
+	for _, tt := range tests {
+		result, err := parseClamdReply(tt.reply)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error, got %+v", tt.reply, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.reply, err)
+			continue
+		}
+		if result.Infected != tt.infected || result.Signature != tt.signature {
+			t.Errorf("%q: expected infected=%v signature=%q, got %+v", tt.reply, tt.infected, tt.signature, result)
+		}
+	}
+}
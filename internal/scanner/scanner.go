@@ -0,0 +1,15 @@
+package scanner
+
+import "io"
+
+// Result is a result of a scan
+type Result struct {
+	Infected bool
+	// Signature is the name of the found malware
+	Signature string
+}
+
+// Scanner scans file content for malware
+type Scanner interface {
+	Scan(r io.Reader) (*Result, error)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/Confialink/wallet-files/internal/archive"
+	"github.com/Confialink/wallet-files/internal/database"
+)
+
+const (
+	ArchiveItemErrorUnavailable = "unavailable"
+	ArchiveItemErrorQuarantined = "quarantined"
+	ArchiveItemErrorNotScanned  = "not_scanned"
+	// ArchiveItemErrorWatermark means the file must be watermarked but the watermark can not be added to it
+	ArchiveItemErrorWatermark = "watermark_unsupported"
+)
+
+// ArchiveManifest is written into every archive as manifest.json
+type ArchiveManifest struct {
+	UserId       string                 `json:"userId,omitempty"`
+	CreatedAt    time.Time              `json:"createdAt"`
+	Files        []*ArchiveManifestItem `json:"files"`
+	Inaccessible []uint64               `json:"inaccessible,omitempty"`
+}
+
+// ArchiveManifestItem describes a single archived file
+type ArchiveManifestItem struct {
+	ID           uint64    `json:"id"`
+	OriginalName string    `json:"originalName"`
+	Path         string    `json:"path,omitempty"`
+	Category     *string   `json:"category"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Size         int64     `json:"size"`
+	Checksum     string    `json:"checksum,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// ArchiveService streams files into zip archives
+type ArchiveService struct {
+	storageService   *StorageService
+	watermarkService *WatermarkService
+	logger           log15.Logger
+}
+
+func NewArchiveService(
+	storageService *StorageService,
+	watermarkService *WatermarkService,
+	logger log15.Logger,
+) *ArchiveService {
+	return &ArchiveService{storageService: storageService, watermarkService: watermarkService, logger: logger}
+}
+
+// WriteArchive streams the files and the manifest into w. Entries are named by
+// original file names, files which can not be read are listed in the manifest with an error.
+// Files of watermarked categories are stamped with the watermark and the archive time unless it is empty.
+func (s *ArchiveService) WriteArchive(
+	w io.Writer,
+	manifest *ArchiveManifest,
+	files []*database.FileModel,
+	watermark string,
+) error {
+	zw := archive.NewWriter(w)
+	manifest.CreatedAt = time.Now()
+	manifest.Files = []*ArchiveManifestItem{}
+
+	for _, file := range files {
+		item := &ArchiveManifestItem{
+			ID:           file.ID,
+			OriginalName: file.OriginalFilename,
+			Category:     file.Category,
+			CreatedAt:    file.CreatedAt,
+		}
+		manifest.Files = append(manifest.Files, item)
+
+		if file.IsQuarantined() {
+			item.Error = ArchiveItemErrorQuarantined
+			continue
+		}
+		if s.storageService.Unscanned(file) {
+			item.Error = ArchiveItemErrorNotScanned
+			continue
+		}
+
+		var content io.ReadCloser
+		var err error
+		if watermark != "" && s.watermarkService.Forced(file) {
+			content, err = s.openWatermarked(file, watermark, manifest.CreatedAt)
+		} else {
+			content, err = s.storageService.Open(file)
+		}
+		if err == ErrWatermarkUnsupported {
+			item.Error = ArchiveItemErrorWatermark
+			continue
+		}
+		if err != nil {
+			s.logger.Error("can't open file for archive", "id", file.ID, "err", err)
+			item.Error = ArchiveItemErrorUnavailable
+			continue
+		}
+
+		entry, err := zw.Add(item.OriginalName, file.CreatedAt, content)
+		_ = content.Close()
+		if err != nil {
+			return err
+		}
+		item.Path = entry.Name
+		item.Size = entry.Size
+		item.Checksum = entry.Checksum
+	}
+
+	if err := zw.AddManifest(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// openWatermarked returns content of the file stamped with the watermark
+func (s *ArchiveService) openWatermarked(file *database.FileModel, watermark string, at time.Time) (io.ReadCloser, error) {
+	if !s.watermarkService.Supported(file) {
+		return nil, ErrWatermarkUnsupported
+	}
+	b, _, err := s.watermarkService.Watermark(file, watermark, at)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
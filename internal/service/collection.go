@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/Confialink/wallet-files/internal/database"
+)
+
+var (
+	ErrCollectionNotFound      = errors.New("collection not found")
+	ErrCollectionInvalidParent = errors.New("collection parent is invalid")
+)
+
+// CollectionTree is a collection with all nested collections and files
+type CollectionTree struct {
+	// Collections are ordered from the root to the deepest level
+	Collections []*database.CollectionModel
+	Files       []*database.FileModel
+}
+
+// CollectionService manages collections of user files
+type CollectionService struct {
+	repository           *database.Repository
+	collectionRepository *database.CollectionRepository
+	storageService       *StorageService
+	logger               log15.Logger
+}
+
+func NewCollectionService(
+	repository *database.Repository,
+	collectionRepository *database.CollectionRepository,
+	storageService *StorageService,
+	logger log15.Logger,
+) *CollectionService {
+	return &CollectionService{
+		repository:           repository,
+		collectionRepository: collectionRepository,
+		storageService:       storageService,
+		logger:               logger,
+	}
+}
+
+// Find returns the collection of the user
+func (s *CollectionService) Find(uid string, id uint64) (*database.CollectionModel, error) {
+	collection, err := s.collectionRepository.FindByID(id)
+	if err != nil || collection.UserId != uid {
+		return nil, ErrCollectionNotFound
+	}
+	return collection, nil
+}
+
+// List returns all collections of the user with files count and size
+func (s *CollectionService) List(uid string) ([]*database.CollectionWithStats, error) {
+	return s.collectionRepository.FindWithStatsByUID(uid)
+}
+
+// Create creates a new collection of the user
+func (s *CollectionService) Create(uid string, name string, parentId *uint64) (*database.CollectionModel, error) {
+	if parentId != nil {
+		if _, err := s.Find(uid, *parentId); err != nil {
+			return nil, ErrCollectionInvalidParent
+		}
+	}
+
+	return s.collectionRepository.Create(&database.CollectionModel{
+		UserId:   uid,
+		ParentId: parentId,
+		Name:     name,
+	})
+}
+
+// Update renames the collection or moves it into another parent
+func (s *CollectionService) Update(collection *database.CollectionModel, name string, parentId *uint64) (*database.CollectionModel, error) {
+	if parentId != nil {
+		if _, err := s.Find(collection.UserId, *parentId); err != nil {
+			return nil, ErrCollectionInvalidParent
+		}
+
+		// a collection can not be moved into itself or into its descendant
+		tree, err := s.Tree(collection)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range tree.Collections {
+			if c.ID == *parentId {
+				return nil, ErrCollectionInvalidParent
+			}
+		}
+	}
+
+	collection.Name = name
+	collection.ParentId = parentId
+	return s.collectionRepository.Save(collection)
+}
+
+// MoveFile moves the file into the collection of its owner, nil moves it out of any collection
+func (s *CollectionService) MoveFile(file *database.FileModel, collectionId *uint64) (*database.FileModel, error) {
+	if collectionId != nil {
+		if _, err := s.Find(file.UserId, *collectionId); err != nil {
+			return nil, err
+		}
+	}
+
+	file.CollectionId = collectionId
+	return s.repository.UpdateCollection(file)
+}
+
+// Tree returns the collection with all nested collections and their files
+func (s *CollectionService) Tree(root *database.CollectionModel) (*CollectionTree, error) {
+	tree := &CollectionTree{Collections: []*database.CollectionModel{root}}
+
+	level := []uint64{root.ID}
+	for len(level) > 0 {
+		children, err := s.collectionRepository.FindByParentIDs(level)
+		if err != nil {
+			return nil, err
+		}
+
+		level = level[:0]
+		for _, child := range children {
+			tree.Collections = append(tree.Collections, child)
+			level = append(level, child.ID)
+		}
+	}
+
+	ids := make([]uint64, len(tree.Collections))
+	for i, c := range tree.Collections {
+		ids[i] = c.ID
+	}
+
+	files, err := s.repository.FindByCollectionIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	tree.Files = files
+
+	return tree, nil
+}
+
+// DeleteTree deletes all files and collections of the tree.
+// Collections are deleted only after all their files are deleted, so a failed deletion may be repeated.
+func (s *CollectionService) DeleteTree(tree *CollectionTree) error {
+	for _, file := range tree.Files {
+		if err := s.storageService.Delete(file); err != nil {
+			s.logger.Error("can't delete file of collection", "id", file.ID, "err", err)
+			return err
+		}
+	}
+
+	for i := len(tree.Collections) - 1; i >= 0; i-- {
+		if err := s.collectionRepository.Delete(tree.Collections[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
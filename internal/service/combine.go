@@ -0,0 +1,158 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"path/filepath"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/errcodes"
+	"github.com/Confialink/wallet-files/internal/imaging"
+	"github.com/Confialink/wallet-files/internal/pdf"
+	"github.com/Confialink/wallet-files/internal/validation"
+	errorsPkg "github.com/Confialink/wallet-pkg-errors"
+)
+
+// MaxCombinedImages limits the number of pages of a combined document
+const MaxCombinedImages = 50
+
+// combinedImageMaxSide limits resolution of pages, which is about 300 dpi on A4
+const combinedImageMaxSide = 2480
+
+const combinedImageQuality = 85
+
+// CombineService combines images into pdf documents
+type CombineService struct {
+	storageService *StorageService
+	logger         log15.Logger
+}
+
+func NewCombineService(storageService *StorageService, logger log15.Logger) *CombineService {
+	return &CombineService{storageService: storageService, logger: logger}
+}
+
+// Combine creates a private pdf document of the user with a page per image in the given order.
+// All images must belong to the user. Source files are deleted after the document is stored if requested,
+// failed deletions are only logged because the document is already created.
+func (s *CombineService) Combine(
+	uid string,
+	files []*database.FileModel,
+	fileName string,
+	category *string,
+	deleteSources bool,
+	uploaderRole string,
+) (*database.FileModel, errorsPkg.TypedError) {
+	now := time.Now()
+	for _, file := range files {
+		if tErr := s.checkSource(uid, file); tErr != nil {
+			return nil, tErr
+		}
+		if deleteSources && s.storageService.RetainReason(file, now) != "" {
+			return nil, &errorsPkg.PublicError{
+				Title:      "File is under legal hold or retention",
+				Details:    fmt.Sprintf("file %d can't be deleted", file.ID),
+				Code:       errcodes.FileRetained,
+				HttpStatus: errcodes.StatusCodes[errcodes.FileRetained],
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	pw := pdf.NewWriter(&buf)
+	for _, file := range files {
+		if err := s.addPage(pw, file); err != nil {
+			pErr := &errorsPkg.PrivateError{Message: "can't add image to document"}
+			pErr.AddLogPair("id", file.ID)
+			pErr.AddLogPair("err", err)
+			return nil, pErr
+		}
+	}
+	if err := pw.Close(); err != nil {
+		pErr := &errorsPkg.PrivateError{Message: "can't write document"}
+		pErr.AddLogPair("err", err)
+		return nil, pErr
+	}
+
+	document, tErr := s.storageService.UploadBytes(buf.Bytes(), documentName(fileName), uid, false, true, category, uploaderRole)
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	if deleteSources {
+		for _, file := range files {
+			if err := s.storageService.Delete(file); err != nil {
+				s.logger.Error("can't delete combined image", "id", file.ID, "document", document.ID, "err", err)
+			}
+		}
+	}
+
+	return document, nil
+}
+
+// checkSource checks that the file is an image of the user which may be read
+func (s *CombineService) checkSource(uid string, file *database.FileModel) errorsPkg.TypedError {
+	switch {
+	case file.UserId != uid:
+		return &errorsPkg.PublicError{
+			Title:      "All images must belong to the user",
+			Details:    fmt.Sprintf("file %d belongs to another user", file.ID),
+			Code:       errcodes.InvalidCombineFiles,
+			HttpStatus: errcodes.StatusCodes[errcodes.InvalidCombineFiles],
+		}
+	case file.IsQuarantined():
+		return &errorsPkg.PublicError{
+			Title:      "File is quarantined",
+			Details:    fmt.Sprintf("file %d is quarantined", file.ID),
+			Code:       errcodes.FileQuarantined,
+			HttpStatus: errcodes.StatusCodes[errcodes.FileQuarantined],
+		}
+	case s.storageService.Unscanned(file):
+		return &errorsPkg.PublicError{
+			Title:      "File is not scanned yet",
+			Details:    fmt.Sprintf("file %d is not scanned by the antivirus yet", file.ID),
+			Code:       errcodes.FileNotScanned,
+			HttpStatus: errcodes.StatusCodes[errcodes.FileNotScanned],
+		}
+	case variantSourceFormat(file) == "":
+		return &errorsPkg.PublicError{
+			Title:      "Only jpeg, png and gif images can be combined",
+			Details:    fmt.Sprintf("file %d is not a supported image", file.ID),
+			Code:       errcodes.UnsupportedFileType,
+			HttpStatus: errcodes.StatusCodes[errcodes.UnsupportedFileType],
+		}
+	}
+	return nil
+}
+
+// addPage adds the upright image downscaled to the page resolution
+func (s *CombineService) addPage(pw *pdf.Writer, file *database.FileModel) error {
+	img, err := decodeImage(s.storageService, file, variantSourceFormat(file))
+	if err != nil {
+		return err
+	}
+
+	img = imaging.Flatten(imaging.Fit(img, combinedImageMaxSide, combinedImageMaxSide), color.White)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: combinedImageQuality}); err != nil {
+		return err
+	}
+	return pw.AddJpegPage(buf.Bytes(), img.Bounds().Dx(), img.Bounds().Dy())
+}
+
+// documentName removes directories from the name and adds pdf extension
+func documentName(name string) string {
+	name = filepath.Base(name)
+	if name == "." || name == "/" {
+		name = "document"
+	}
+	if validation.Extension(name) != "pdf" {
+		name += ".pdf"
+	}
+	return name
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"sort"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/Confialink/wallet-files/internal/config"
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/imaging"
+)
+
+// Kinds of duplicate matches
+const (
+	DuplicateMatchExact      = "exact"
+	DuplicateMatchPerceptual = "perceptual"
+)
+
+// maxPerceptualDistance is the max number of different bits of hashes of near-identical images
+const maxPerceptualDistance = 10
+
+// MaxDuplicates limits the number of matches of each kind
+const MaxDuplicates = 100
+
+// Duplicate is a file of another user with identical or near-identical content
+type Duplicate struct {
+	FileId uint64 `json:"fileId"`
+	UserId string `json:"userId"`
+	Match  string `json:"match"`
+	// Similarity is 1 for identical files and decreases with the difference of perceptual hashes
+	Similarity float64 `json:"similarity"`
+}
+
+// DuplicateService fingerprints uploaded files in background and finds the same documents uploaded by other users.
+// Files are hashed with SHA-256 and images additionally with a perceptual hash which survives resizing and recompression.
+type DuplicateService struct {
+	repository     *database.Repository
+	storageService *StorageService
+	config         *config.Config
+	logger         log15.Logger
+	queue          chan uint64
+}
+
+func NewDuplicateService(
+	repository *database.Repository,
+	storageService *StorageService,
+	config *config.Config,
+	logger log15.Logger,
+) *DuplicateService {
+	return &DuplicateService{
+		repository:     repository,
+		storageService: storageService,
+		config:         config,
+		logger:         logger,
+		queue:          make(chan uint64, 1000),
+	}
+}
+
+// Start starts the fingerprint worker. Files uploaded before fingerprints were introduced, or which were
+// dropped from the full queue, are fingerprinted by the fingerprint command.
+func (s *DuplicateService) Start() {
+	go s.work()
+}
+
+// FileUploaded queues the file for fingerprinting
+func (s *DuplicateService) FileUploaded(file *database.FileModel) {
+	select {
+	case s.queue <- file.ID:
+	default:
+		// the queue is full, the file is picked up by the fingerprint command
+		s.logger.Warn("fingerprint queue is full", "id", file.ID)
+	}
+}
+
+// Find returns files of other users which are identical or near-identical to the file ordered by similarity.
+// The file is fingerprinted first if it was not yet.
+func (s *DuplicateService) Find(file *database.FileModel) ([]*Duplicate, error) {
+	if file.Sha256 == nil {
+		if err := s.Fingerprint(file); err != nil {
+			return nil, err
+		}
+	}
+
+	exact, err := s.repository.FindBySha256(*file.Sha256, file.UserId, MaxDuplicates)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := make([]*Duplicate, 0, len(exact))
+	found := make(map[uint64]bool)
+	for _, match := range exact {
+		found[match.ID] = true
+		duplicates = append(duplicates, &Duplicate{
+			FileId:     match.ID,
+			UserId:     match.UserId,
+			Match:      DuplicateMatchExact,
+			Similarity: 1,
+		})
+	}
+
+	if file.PerceptualHash != nil {
+		similar, err := s.repository.FindByPerceptualHash(*file.PerceptualHash, maxPerceptualDistance, file.UserId, MaxDuplicates)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range similar {
+			if found[match.ID] {
+				continue
+			}
+			distance := imaging.Distance(uint64(*file.PerceptualHash), uint64(*match.PerceptualHash))
+			duplicates = append(duplicates, &Duplicate{
+				FileId:     match.ID,
+				UserId:     match.UserId,
+				Match:      DuplicateMatchPerceptual,
+				Similarity: 1 - float64(distance)/64,
+			})
+		}
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Similarity > duplicates[j].Similarity
+	})
+	return duplicates, nil
+}
+
+// Fingerprint computes and saves hashes of the file content.
+// Images which can not be decoded get only the SHA-256 hash.
+func (s *DuplicateService) Fingerprint(file *database.FileModel) error {
+	content, err := s.storageService.Open(file)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(content)
+	_ = content.Close()
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(b)
+	hash := hex.EncodeToString(sum[:])
+	file.Sha256 = &hash
+	file.PerceptualHash = nil
+
+	if format := variantSourceFormat(file); format != "" {
+		if img, err := decodeImageBytes(b, format); err == nil {
+			perceptual := int64(imaging.DHash(img))
+			file.PerceptualHash = &perceptual
+		} else {
+			s.logger.Warn("can't decode image for perceptual hash", "id", file.ID, "err", err)
+		}
+	}
+
+	_, err = s.repository.UpdateFingerprint(file)
+	return err
+}
+
+func (s *DuplicateService) work() {
+	for id := range s.queue {
+		file, err := s.repository.FindByID(id)
+		if err != nil {
+			// the file could be deleted while it waited for fingerprinting
+			s.logger.Warn("can't load file for fingerprinting", "id", id, "err", err)
+			continue
+		}
+		if err := s.Fingerprint(file); err != nil {
+			s.logger.Error("can't fingerprint file", "id", id, "err", err)
+			continue
+		}
+		s.alert(file)
+	}
+}
+
+// alert logs a duplicate event if the file matches files of other users with similarity above the threshold
+func (s *DuplicateService) alert(file *database.FileModel) {
+	if s.config.DuplicateAlertSimilarity <= 0 {
+		return
+	}
+
+	duplicates, err := s.Find(file)
+	if err != nil {
+		s.logger.Error("can't find duplicates", "id", file.ID, "err", err)
+		return
+	}
+	for _, duplicate := range duplicates {
+		if duplicate.Similarity < s.config.DuplicateAlertSimilarity {
+			break
+		}
+		s.logger.Warn("file matches a file of another user", "event", "duplicate_file",
+			"id", file.ID, "uid", file.UserId, "matchId", duplicate.FileId, "matchUid", duplicate.UserId,
+			"match", duplicate.Match, "similarity", duplicate.Similarity)
+	}
+}
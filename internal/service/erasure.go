@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/Confialink/wallet-files/internal/config"
+	"github.com/Confialink/wallet-files/internal/database"
+)
+
+const (
+	KeepReasonExcludedCategory = "excluded_category"
+	KeepReasonLegalHold        = "legal_hold"
+	KeepReasonRetention        = "retention"
+	KeepReasonFailed           = "failed"
+)
+
+// ErrShredProtected means the user has files under a legal hold or retention which must stay readable
+var ErrShredProtected = errors.New("user has files under legal hold or retention")
+
+// ErasureReport describes which files were removed and which were kept,
+// Exports and Collections are numbers of removed exports and collections of the user
+type ErasureReport struct {
+	DryRun      bool           `json:"dryRun"`
+	Removed     []*ErasureItem `json:"removed"`
+	Kept        []*ErasureItem `json:"kept"`
+	Exports     int            `json:"exports"`
+	Collections int            `json:"collections"`
+}
+
+// ErasureItem is a single file in the erasure report
+type ErasureItem struct {
+	ID       uint64  `json:"id"`
+	Filename string  `json:"filename"`
+	Category *string `json:"category"`
+	Size     int64   `json:"size"`
+	Reason   string  `json:"reason,omitempty"`
+}
+
+// ErasureService erases user files from the database and the storages.
+// Every file is erased independently, so an interrupted erasure may be safely run again:
+// already erased files are gone and the rest are picked up by the next run.
+type ErasureService struct {
+	repository           *database.Repository
+	collectionRepository *database.CollectionRepository
+	storageService       *StorageService
+	exportService        *ExportService
+	keyService           *KeyService
+	config               *config.Config
+	logger               log15.Logger
+}
+
+func NewErasureService(
+	repository *database.Repository,
+	collectionRepository *database.CollectionRepository,
+	storageService *StorageService,
+	exportService *ExportService,
+	keyService *KeyService,
+	config *config.Config,
+	logger log15.Logger,
+) *ErasureService {
+	return &ErasureService{
+		repository:           repository,
+		collectionRepository: collectionRepository,
+		storageService:       storageService,
+		exportService:        exportService,
+		keyService:           keyService,
+		config:               config,
+		logger:               logger,
+	}
+}
+
+// EraseUserFiles deletes all files of the user which are not protected by
+// a legal hold, a retention rule or an excluded category, and all exports and collections of the user.
+// If dryRun is true nothing is deleted, the report shows what would happen.
+func (s *ErasureService) EraseUserFiles(uid string, excludeCategories []string, dryRun bool) (*ErasureReport, error) {
+	logger := s.logger.New("method", "EraseUserFiles", "uid", uid, "dryRun", dryRun)
+
+	files, err := s.repository.FindByUID(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ErasureReport{DryRun: dryRun, Removed: []*ErasureItem{}, Kept: []*ErasureItem{}}
+	now := time.Now()
+	for _, file := range files {
+		item := newErasureItem(file)
+
+		if reason := s.keepReason(file, excludeCategories, now); reason != "" {
+			item.Reason = reason
+			report.Kept = append(report.Kept, item)
+			continue
+		}
+
+		if !dryRun {
+			if err := s.storageService.Delete(file); err != nil {
+				logger.Error("can't erase file", "id", file.ID, "err", err)
+				item.Reason = KeepReasonFailed
+				report.Kept = append(report.Kept, item)
+				continue
+			}
+		}
+		report.Removed = append(report.Removed, item)
+	}
+
+	if dryRun {
+		if report.Exports, err = s.exportService.CountUserExports(uid); err != nil {
+			return nil, err
+		}
+		if report.Collections, err = s.collectionRepository.CountByUID(uid); err != nil {
+			return nil, err
+		}
+	} else if report.Exports, report.Collections, err = s.eraseUserData(uid); err != nil {
+		return nil, err
+	}
+
+	logger.Info("user files erased", "removed", len(report.Removed), "kept", len(report.Kept),
+		"exports", report.Exports, "collections", report.Collections)
+	return report, nil
+}
+
+// ShredUser deletes all files, exports and collections of the user and destroys the user key which wraps their data keys,
+// so copies of the files left in object versions and backups become unreadable. Files which fail
+// to be deleted are unreadable as well and are deleted when shredding is repeated.
+// ErrShredProtected is returned and nothing is changed if any file is under a legal hold or retention.
+func (s *ErasureService) ShredUser(uid string, requestedBy string) (*database.ShredEventModel, error) {
+	logger := s.logger.New("method", "ShredUser", "uid", uid)
+
+	files, err := s.repository.FindByUID(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, file := range files {
+		if reason := s.keepReason(file, nil, now); reason != "" {
+			return nil, ErrShredProtected
+		}
+	}
+
+	event := &database.ShredEventModel{UserId: uid, RequestedBy: requestedBy}
+	for _, file := range files {
+		if err := s.storageService.Delete(file); err != nil {
+			logger.Error("can't delete file", "id", file.ID, "err", err)
+			event.FailedCount++
+			continue
+		}
+		event.FilesCount++
+	}
+
+	exports, collections, err := s.eraseUserData(uid)
+	if err != nil {
+		// remaining exports are encrypted for the user, so they are unreadable after the key is shredded
+		logger.Error("can't delete exports and collections", "err", err)
+	}
+
+	if event, err = s.keyService.Shred(event); err != nil {
+		return nil, err
+	}
+
+	logger.Info("user key shredded", "event", "shred_user", "requestedBy", requestedBy,
+		"files", event.FilesCount, "failed", event.FailedCount, "exports", exports, "collections", collections)
+	return event, nil
+}
+
+// eraseUserData deletes exports and collections of the user and returns their numbers
+func (s *ErasureService) eraseUserData(uid string) (int, int, error) {
+	exports, err := s.exportService.DeleteUserExports(uid)
+	if err != nil {
+		return exports, 0, err
+	}
+	collections, err := s.collectionRepository.DeleteByUID(uid)
+	return exports, collections, err
+}
+
+// keepReason returns the reason why the file must not be erased or empty string
+func (s *ErasureService) keepReason(file *database.FileModel, excludeCategories []string, now time.Time) string {
+	if file.Category != nil {
+		for _, category := range excludeCategories {
+			if *file.Category == category {
+				return KeepReasonExcludedCategory
+			}
+		}
+	}
+
+	return s.storageService.RetainReason(file, now)
+}
+
+func newErasureItem(file *database.FileModel) *ErasureItem {
+	return &ErasureItem{
+		ID:       file.ID,
+		Filename: file.Filename,
+		Category: file.Category,
+		Size:     file.Size,
+	}
+}
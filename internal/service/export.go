@@ -0,0 +1,284 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/Confialink/wallet-files/internal/config"
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/storage"
+)
+
+const (
+	exportsDir            = "exports"
+	exportWorkers         = 2
+	exportCleanupInterval = time.Hour
+)
+
+// ExportService exports user files into zip archives.
+// Small exports are streamed directly, large ones are built by background jobs
+// which store the archive in the default storage until it expires.
+type ExportService struct {
+	repository       *database.Repository
+	exportRepository *database.ExportRepository
+	storageService   *StorageService
+	archiveService   *ArchiveService
+	config           *config.Config
+	logger           log15.Logger
+	queue            chan uint64
+}
+
+func NewExportService(
+	repository *database.Repository,
+	exportRepository *database.ExportRepository,
+	storageService *StorageService,
+	archiveService *ArchiveService,
+	config *config.Config,
+	logger log15.Logger,
+) *ExportService {
+	return &ExportService{
+		repository:       repository,
+		exportRepository: exportRepository,
+		storageService:   storageService,
+		archiveService:   archiveService,
+		config:           config,
+		logger:           logger,
+		queue:            make(chan uint64, 100),
+	}
+}
+
+// Start starts export workers, requeues unfinished exports and removes expired archives periodically.
+// It blocks, so it should be run in a goroutine.
+func (s *ExportService) Start() {
+	for i := 0; i < exportWorkers; i++ {
+		go s.work()
+	}
+
+	unfinished, err := s.exportRepository.FindByStatuses([]string{database.ExportStatusPending, database.ExportStatusProcessing})
+	if err != nil {
+		s.logger.Error("can't load unfinished exports", "err", err)
+	}
+	for _, export := range unfinished {
+		s.queue <- export.ID
+	}
+
+	for {
+		s.removeExpired()
+		time.Sleep(exportCleanupInterval)
+	}
+}
+
+// UserFiles returns files which are exported for the user
+func (s *ExportService) UserFiles(uid string) ([]*database.FileModel, error) {
+	return s.repository.FindClientVisibleByUID(uid)
+}
+
+// CanStream checks if the files are small enough to be exported within a request
+func (s *ExportService) CanStream(files []*database.FileModel) bool {
+	var size int64
+	for _, file := range files {
+		size += file.Size
+	}
+	return size <= s.config.Export.StreamLimit
+}
+
+// WriteArchive writes a zip archive with the files and the manifest into w,
+// files of watermarked categories are stamped with the watermark unless it is empty
+func (s *ExportService) WriteArchive(
+	w io.Writer,
+	uid string,
+	files []*database.FileModel,
+	watermark string,
+) (*ArchiveManifest, error) {
+	manifest := &ArchiveManifest{UserId: uid}
+	return manifest, s.archiveService.WriteArchive(w, manifest, files, watermark)
+}
+
+// CreateExport creates a background export of the user files, see WriteArchive for the watermark
+func (s *ExportService) CreateExport(uid string, requestedBy string, watermark string) (*database.ExportModel, error) {
+	export := &database.ExportModel{
+		UserId:      uid,
+		RequestedBy: requestedBy,
+		Status:      database.ExportStatusPending,
+	}
+	if watermark != "" {
+		export.Watermark = &watermark
+	}
+	export, err := s.exportRepository.Create(export)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queue <- export.ID:
+	default:
+		// the queue is full, the export is picked up on the next start
+		s.logger.Warn("export queue is full", "id", export.ID)
+	}
+	return export, nil
+}
+
+// FindExport returns the export by id
+func (s *ExportService) FindExport(id uint64) (*database.ExportModel, error) {
+	return s.exportRepository.FindByID(id)
+}
+
+// OpenArchive opens the archive of a finished and not expired export
+func (s *ExportService) OpenArchive(export *database.ExportModel) (io.ReadCloser, error) {
+	if export.Status != database.ExportStatusDone || export.ExpiresAt == nil || export.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("export %d is not available", export.ID)
+	}
+	return s.storageService.OpenObject(export.UserId, exportLocation(export), &export.ObjectKey)
+}
+
+func (s *ExportService) work() {
+	for id := range s.queue {
+		export, err := s.exportRepository.FindByID(id)
+		if err != nil {
+			s.logger.Error("can't load export", "id", id, "err", err)
+			continue
+		}
+		s.process(export)
+	}
+}
+
+// process builds the archive and uploads it to the storage
+func (s *ExportService) process(export *database.ExportModel) {
+	logger := s.logger.New("method", "process", "id", export.ID, "uid", export.UserId)
+
+	export.Status = database.ExportStatusProcessing
+	if _, err := s.exportRepository.Save(export); err != nil {
+		logger.Error("can't update export", "err", err)
+		return
+	}
+
+	files, err := s.UserFiles(export.UserId)
+	if err != nil {
+		s.fail(export, err, logger)
+		return
+	}
+
+	reader, writer := io.Pipe()
+	manifests := make(chan *ArchiveManifest, 1)
+	go func() {
+		var watermark string
+		if export.Watermark != nil {
+			watermark = *export.Watermark
+		}
+		manifest, err := s.WriteArchive(writer, export.UserId, files, watermark)
+		manifests <- manifest
+		_ = writer.CloseWithError(err)
+	}()
+
+	counter := &countingReader{reader: reader}
+	name := fmt.Sprintf("%d-%s.zip", export.ID, export.UserId)
+	location, err := s.storageService.PutObject(export.UserId, exportsDir, name, counter, "application/zip", &export.ObjectKey)
+	_ = reader.CloseWithError(io.ErrClosedPipe)
+	manifest := <-manifests
+	if err != nil {
+		s.fail(export, err, logger)
+		return
+	}
+
+	// the export is deleted while it is processed when files of the user are erased
+	if exists, err := s.exportRepository.Exists(export.ID); err != nil || !exists {
+		_ = s.storageService.DeleteObject(location)
+		logger.Warn("export is deleted while it was processed", "err", err)
+		return
+	}
+
+	expiresAt := time.Now().Add(s.config.Export.TTL)
+	export.Status = database.ExportStatusDone
+	export.Storage = location.Storage
+	export.Bucket = location.Bucket
+	export.Path = location.Path
+	export.Filename = location.Filename
+	export.Size = counter.n
+	export.FilesCount = len(manifest.Files)
+	export.ExpiresAt = &expiresAt
+	if _, err := s.exportRepository.Save(export); err != nil {
+		logger.Error("can't update export", "err", err)
+		return
+	}
+	logger.Info("export is done", "size", export.Size, "files", export.FilesCount)
+}
+
+func (s *ExportService) fail(export *database.ExportModel, err error, logger log15.Logger) {
+	logger.Error("export failed", "err", err)
+	export.Status = database.ExportStatusFailed
+	export.Error = err.Error()
+	if _, err := s.exportRepository.Save(export); err != nil {
+		logger.Error("can't update export", "err", err)
+	}
+}
+
+// DeleteUserExports deletes all exports of the user with their archives and returns their number.
+// Exports which are being processed are deleted as well, their archives are deleted when they finish.
+func (s *ExportService) DeleteUserExports(uid string) (int, error) {
+	exports, err := s.exportRepository.FindByUID(uid)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, export := range exports {
+		if export.Status == database.ExportStatusDone {
+			if err := s.storageService.DeleteObject(exportLocation(export)); err != nil {
+				return i, err
+			}
+		}
+		if err := s.exportRepository.Delete(export); err != nil {
+			return i, err
+		}
+	}
+	return len(exports), nil
+}
+
+// CountUserExports counts exports of the user
+func (s *ExportService) CountUserExports(uid string) (int, error) {
+	exports, err := s.exportRepository.FindByUID(uid)
+	return len(exports), err
+}
+
+// removeExpired deletes archives of expired exports
+func (s *ExportService) removeExpired() {
+	exports, err := s.exportRepository.FindExpired(time.Now())
+	if err != nil {
+		s.logger.Error("can't load expired exports", "err", err)
+		return
+	}
+
+	for _, export := range exports {
+		if err := s.storageService.DeleteObject(exportLocation(export)); err != nil {
+			s.logger.Error("can't delete expired export", "id", export.ID, "err", err)
+			continue
+		}
+		export.Status = database.ExportStatusExpired
+		if _, err := s.exportRepository.Save(export); err != nil {
+			s.logger.Error("can't update export", "id", export.ID, "err", err)
+		}
+	}
+}
+
+func exportLocation(export *database.ExportModel) *storage.Location {
+	return &storage.Location{
+		Storage:  export.Storage,
+		Bucket:   export.Bucket,
+		Path:     export.Path,
+		Filename: export.Filename,
+	}
+}
+
+// countingReader counts bytes read from the underlying reader
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/encryption"
+)
+
+// ErrKeyShredded means the key of the user was destroyed, so their files can't be decrypted anymore
+var ErrKeyShredded = database.ErrKeyShredded
+
+// KeyService wraps data keys of files with key-encryption keys of their owners, which are in turn wrapped
+// with the master key. Destroying the key of a user makes every copy of their files unreadable,
+// including object versions and backups which can't be deleted.
+type KeyService struct {
+	userKeys *database.UserKeyRepository
+	keys     encryption.KeyProvider
+}
+
+func NewKeyService(userKeys *database.UserKeyRepository, keys encryption.KeyProvider) *KeyService {
+	return &KeyService{
+		userKeys: userKeys,
+		keys:     keys,
+	}
+}
+
+// Enabled tells if uploaded files are encrypted
+func (s *KeyService) Enabled() bool {
+	return s.keys != nil
+}
+
+// WrapDataKey wraps the data key with the key of the file owner, the key is created on first use
+func (s *KeyService) WrapDataKey(file *database.FileModel, dataKey []byte) error {
+	wrapped, keyId, err := s.wrap(file.UserId, dataKey)
+	if err != nil {
+		return err
+	}
+
+	file.WrappedKey, file.KeyId = wrapped, keyId
+	return nil
+}
+
+// UnwrapDataKey returns the data key of the file, it is nil if the content is not encrypted.
+// Files encrypted before user keys were introduced have data keys wrapped directly with the master key.
+func (s *KeyService) UnwrapDataKey(file *database.FileModel) ([]byte, error) {
+	if file.WrappedKey == nil {
+		return nil, nil
+	}
+	if s.keys == nil || file.KeyId == nil {
+		return nil, errors.New("file is encrypted but no master key is configured")
+	}
+	if *file.KeyId != database.UserKeyId {
+		return s.keys.Unwrap(file.WrappedKey, *file.KeyId)
+	}
+
+	return s.unwrap(file.UserId, file.WrappedKey)
+}
+
+// WrapObjectKey wraps the data key of an object derived from files of the user with the key of the user
+func (s *KeyService) WrapObjectKey(uid string, key *database.ObjectKey, dataKey []byte) error {
+	wrapped, keyId, err := s.wrap(uid, dataKey)
+	if err != nil {
+		return err
+	}
+
+	key.WrappedKey, key.KeyId = wrapped, keyId
+	return nil
+}
+
+// UnwrapObjectKey returns the data key of an object derived from files of the user, it is nil if the object
+// is not encrypted
+func (s *KeyService) UnwrapObjectKey(uid string, key *database.ObjectKey) ([]byte, error) {
+	if key.WrappedKey == nil {
+		return nil, nil
+	}
+	if s.keys == nil {
+		return nil, errors.New("object is encrypted but no master key is configured")
+	}
+
+	return s.unwrap(uid, key.WrappedKey)
+}
+
+func (s *KeyService) wrap(uid string, dataKey []byte) ([]byte, *string, error) {
+	kek, err := s.userKey(uid, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := encryption.WrapKey(kek, dataKey, []byte(uid))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyId := database.UserKeyId
+	return wrapped, &keyId, nil
+}
+
+func (s *KeyService) unwrap(uid string, wrapped []byte) ([]byte, error) {
+	kek, err := s.userKey(uid, false)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.UnwrapKey(kek, wrapped, []byte(uid))
+}
+
+// RewrapUserKey wraps the key of the user with the current master key
+func (s *KeyService) RewrapUserKey(key *database.UserKeyModel) error {
+	if key.WrappedKey == nil || *key.KeyId == s.keys.CurrentKeyId() {
+		return nil
+	}
+
+	kek, err := s.keys.Unwrap(key.WrappedKey, *key.KeyId)
+	if err != nil {
+		return err
+	}
+	wrapped, keyId, err := s.keys.Wrap(kek)
+	if err != nil {
+		return err
+	}
+
+	oldKeyId := *key.KeyId
+	key.WrappedKey, key.KeyId = wrapped, &keyId
+	return s.userKeys.UpdateWrappedKey(key, oldKeyId)
+}
+
+// Shred destroys the key of the user and records the event, no new key is created for the user afterwards
+func (s *KeyService) Shred(event *database.ShredEventModel) (*database.ShredEventModel, error) {
+	return s.userKeys.Shred(event)
+}
+
+// userKey returns the unwrapped key of the user, a missing key is created if create is true
+func (s *KeyService) userKey(uid string, create bool) ([]byte, error) {
+	if s.keys == nil {
+		return nil, errors.New("no master key is configured")
+	}
+
+	key, err := s.userKeys.FindByUID(uid)
+	if gorm.IsRecordNotFoundError(err) && create {
+		key, err = s.createUserKey(uid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.ShreddedAt != nil {
+		return nil, ErrKeyShredded
+	}
+
+	return s.keys.Unwrap(key.WrappedKey, *key.KeyId)
+}
+
+func (s *KeyService) createUserKey(uid string) (*database.UserKeyModel, error) {
+	kek, err := encryption.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, keyId, err := s.keys.Wrap(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userKeys.Create(&database.UserKeyModel{UserId: uid, WrappedKey: wrapped, KeyId: &keyId})
+}
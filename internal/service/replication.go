@@ -0,0 +1,178 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/Confialink/wallet-files/internal/config"
+	"github.com/Confialink/wallet-files/internal/database"
+)
+
+const (
+	replicationWorkers   = 2
+	replicationBatch     = 100
+	replicationInterval  = 10 * time.Second
+	replicationReportAt  = time.Minute
+	replicaMaxAttempts   = 10
+	replicaMaxRetryDelay = time.Hour
+)
+
+// ReplicationService copies stored objects of new files into the replica target in background. Files of users
+// of a residency region are copied only into the replica target of the region.
+// Failed copies are retried with a growing delay until replicaMaxAttempts, after that the file is marked as failed.
+type ReplicationService struct {
+	repository     *database.Repository
+	storageService *StorageService
+	residency      *ResidencyService
+	config         *config.Config
+	logger         log15.Logger
+}
+
+func NewReplicationService(
+	repository *database.Repository,
+	storageService *StorageService,
+	residency *ResidencyService,
+	config *config.Config,
+	logger log15.Logger,
+) *ReplicationService {
+	return &ReplicationService{
+		repository:     repository,
+		storageService: storageService,
+		residency:      residency,
+		config:         config,
+		logger:         logger,
+	}
+}
+
+// Enabled checks if a replica target is configured
+func (s *ReplicationService) Enabled() bool {
+	return s.residency.ReplicationEnabled()
+}
+
+// Start replicates due files periodically and logs the backlog
+func (s *ReplicationService) Start() {
+	if !s.Enabled() {
+		return
+	}
+
+	reportedAt := time.Now()
+	for {
+		if s.replicateDue() < replicationBatch {
+			time.Sleep(replicationInterval)
+		}
+
+		if time.Since(reportedAt) >= replicationReportAt {
+			reportedAt = time.Now()
+			s.report()
+		}
+	}
+}
+
+// FileUploaded marks the file as pending, the replica target is resolved by the next run
+func (s *ReplicationService) FileUploaded(file *database.FileModel) {
+	if !s.Enabled() {
+		return
+	}
+
+	now := time.Now()
+	file.ReplicaStatus = database.ReplicaStatusPending
+	file.ReplicaAttempts = 0
+	file.ReplicaRetryAt = &now
+	if _, err := s.repository.UpdateReplica(file); err != nil {
+		s.logger.Error("can't mark file for replication", "id", file.ID, "err", err)
+	}
+}
+
+// Backlog counts files which are not replicated yet
+func (s *ReplicationService) Backlog() (*database.ReplicaBacklog, error) {
+	return s.repository.GetReplicaBacklog()
+}
+
+// replicateDue replicates a batch of due files and returns their number
+func (s *ReplicationService) replicateDue() int {
+	files, err := s.repository.FindReplicaDue(time.Now(), replicationBatch)
+	if err != nil {
+		s.logger.Error("can't load files for replication", "err", err)
+		return 0
+	}
+
+	queue := make(chan *database.FileModel)
+	var wg sync.WaitGroup
+	for i := 0; i < replicationWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range queue {
+				s.replicate(file)
+			}
+		}()
+	}
+	for _, file := range files {
+		queue <- file
+	}
+	close(queue)
+	wg.Wait()
+
+	return len(files)
+}
+
+func (s *ReplicationService) replicate(file *database.FileModel) {
+	logger := s.logger.New("method", "replicate", "id", file.ID)
+
+	targetName, err := s.storageService.ReplicaTarget(file)
+	if err == nil && targetName != "" {
+		err = s.storageService.Replicate(file, targetName)
+	}
+
+	if err != nil {
+		file.ReplicaAttempts++
+		if file.ReplicaAttempts >= replicaMaxAttempts {
+			logger.Error("replication failed", "attempts", file.ReplicaAttempts, "err", err)
+			file.ReplicaStatus = database.ReplicaStatusFailed
+			file.ReplicaRetryAt = nil
+		} else {
+			logger.Warn("can't replicate file", "attempts", file.ReplicaAttempts, "err", err)
+			retryAt := time.Now().Add(replicaRetryDelay(file.ReplicaAttempts))
+			file.ReplicaRetryAt = &retryAt
+		}
+	} else if targetName == "" {
+		// the region of the owner has no replica target or the file is stored in it
+		file.ReplicaStatus = ""
+		file.ReplicaRetryAt = nil
+	} else {
+		file.ReplicaStatus = database.ReplicaStatusDone
+		file.ReplicaRetryAt = nil
+	}
+
+	if _, err := s.repository.UpdateReplica(file); err != nil {
+		logger.Error("can't update replica status", "err", err)
+	}
+}
+
+func (s *ReplicationService) report() {
+	backlog, err := s.Backlog()
+	if err != nil {
+		s.logger.Error("can't count replication backlog", "err", err)
+		return
+	}
+	if backlog.Pending == 0 && backlog.Failed == 0 {
+		return
+	}
+
+	pairs := []interface{}{"pending", backlog.Pending, "failed", backlog.Failed}
+	if backlog.OldestPendingAt != nil {
+		pairs = append(pairs, "lag", time.Since(*backlog.OldestPendingAt).Round(time.Second).String())
+	}
+	s.logger.Info("replication backlog", pairs...)
+}
+
+// replicaRetryDelay grows exponentially from a minute up to replicaMaxRetryDelay
+func replicaRetryDelay(attempts int) time.Duration {
+	delay := time.Minute << uint(attempts-1)
+	if delay > replicaMaxRetryDelay || delay <= 0 {
+		return replicaMaxRetryDelay
+	}
+	return delay
+}
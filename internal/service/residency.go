@@ -0,0 +1,86 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/Confialink/wallet-files/internal/config"
+)
+
+// ResidencyService decides where files of users must be stored depending on their country of residence
+type ResidencyService struct {
+	users  *Users
+	config *config.Config
+}
+
+func NewResidencyService(users *Users, config *config.Config) *ResidencyService {
+	return &ResidencyService{users, config}
+}
+
+// Enabled tells if residency regions are configured
+func (s *ResidencyService) Enabled() bool {
+	return len(s.config.Residency) > 0
+}
+
+// Region returns the residency region of the user and the storage target it requires,
+// both are empty if residency is not enforced for the user
+func (s *ResidencyService) Region(uid string) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", nil
+	}
+
+	user, err := s.users.GetByUID(uid)
+	if err != nil {
+		return "", "", err
+	}
+	name, region := s.regionOf(user.CountryOfResidenceIsoTwo)
+	return name, region.Target, nil
+}
+
+// ReplicaTarget returns the target where files of the user are replicated. Users of a residency region
+// are replicated only into the replica target of the region, it is empty if the region has none.
+func (s *ResidencyService) ReplicaTarget(uid string) (string, error) {
+	if !s.Enabled() {
+		return s.config.ReplicaTarget, nil
+	}
+
+	user, err := s.users.GetByUID(uid)
+	if err != nil {
+		return "", err
+	}
+	name, region := s.regionOf(user.CountryOfResidenceIsoTwo)
+	if name == "" {
+		return s.config.ReplicaTarget, nil
+	}
+	return region.ReplicaTarget, nil
+}
+
+// ReplicationEnabled tells if any replica target is configured, globally or for a region
+func (s *ResidencyService) ReplicationEnabled() bool {
+	if s.config.ReplicaTarget != "" {
+		return true
+	}
+	for _, region := range s.config.Residency {
+		if region.ReplicaTarget != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// regionOf finds the region which lists the country or the region without countries
+func (s *ResidencyService) regionOf(country string) (string, config.ResidencyRegion) {
+	var fallbackName string
+	var fallback config.ResidencyRegion
+	for name, region := range s.config.Residency {
+		if len(region.Countries) == 0 {
+			fallbackName, fallback = name, region
+			continue
+		}
+		for _, c := range region.Countries {
+			if country != "" && strings.EqualFold(c, country) {
+				return name, region
+			}
+		}
+	}
+	return fallbackName, fallback
+}
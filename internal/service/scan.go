@@ -0,0 +1,181 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/scanner"
+)
+
+const scanWorkers = 2
+
+// Failed scans are retried every scanRetryInterval until the file fails scanMaxAttempts times,
+// afterwards it may be rescanned by admins
+const (
+	scanMaxAttempts   = 5
+	scanRetryInterval = 5 * time.Minute
+	scanRetryBatch    = 100
+)
+
+var ErrScanDisabled = errors.New("scanning is disabled")
+
+// ScanService scans uploaded files for malware in background and quarantines infected ones.
+// If scanner is nil scanning is disabled, quarantined files may still be managed.
+type ScanService struct {
+	repository     *database.Repository
+	storageService *StorageService
+	scanner        scanner.Scanner
+	logger         log15.Logger
+	queue          chan uint64
+}
+
+func NewScanService(
+	repository *database.Repository,
+	storageService *StorageService,
+	scanner scanner.Scanner,
+	logger log15.Logger,
+) *ScanService {
+	return &ScanService{
+		repository:     repository,
+		storageService: storageService,
+		scanner:        scanner,
+		logger:         logger,
+		queue:          make(chan uint64, 1000),
+	}
+}
+
+// Enabled checks if a scanner is configured
+func (s *ScanService) Enabled() bool {
+	return s.scanner != nil
+}
+
+// Start starts scan workers, requeues files which are still pending and retries failed scans periodically
+func (s *ScanService) Start() {
+	if !s.Enabled() {
+		return
+	}
+
+	for i := 0; i < scanWorkers; i++ {
+		go s.work()
+	}
+
+	pending, err := s.repository.FindByScanStatus(database.ScanStatusPending)
+	if err != nil {
+		s.logger.Error("can't load pending files", "err", err)
+		return
+	}
+	for _, file := range pending {
+		s.queue <- file.ID
+	}
+
+	go s.retry()
+}
+
+// retry requeues files which failed to be scanned
+func (s *ScanService) retry() {
+	for {
+		files, err := s.repository.FindScanRetries(scanMaxAttempts, scanRetryBatch)
+		if err != nil {
+			s.logger.Error("can't load failed scans", "err", err)
+		}
+		for _, file := range files {
+			select {
+			case s.queue <- file.ID:
+			default:
+				s.logger.Warn("scan queue is full", "id", file.ID)
+			}
+		}
+		time.Sleep(scanRetryInterval)
+	}
+}
+
+// FileUploaded marks the file as pending and queues it for scanning
+func (s *ScanService) FileUploaded(file *database.FileModel) {
+	if err := s.Enqueue(file); err != nil {
+		s.logger.Error("can't queue file for scanning", "id", file.ID, "err", err)
+	}
+}
+
+// Enqueue marks the file as pending and queues it for scanning
+func (s *ScanService) Enqueue(file *database.FileModel) error {
+	if !s.Enabled() {
+		return ErrScanDisabled
+	}
+
+	file.ScanStatus = database.ScanStatusPending
+	file.ScanSignature = ""
+	file.ScanAttempts = 0
+	if _, err := s.repository.UpdateScan(file); err != nil {
+		return err
+	}
+
+	select {
+	case s.queue <- file.ID:
+	default:
+		// the queue is full, the file is picked up on the next start
+		s.logger.Warn("scan queue is full", "id", file.ID)
+	}
+	return nil
+}
+
+// Quarantined returns a page of quarantined files and the count of all of them,
+// files of all users are returned if uid is empty
+func (s *ScanService) Quarantined(uid string, limit int, offset int) ([]*database.FileModel, int64, error) {
+	return s.repository.FindPageByScanStatus(database.ScanStatusInfected, uid, limit, offset)
+}
+
+// Release marks a quarantined file as clean, e.g. after a false positive
+func (s *ScanService) Release(file *database.FileModel) (*database.FileModel, error) {
+	file.ScanStatus = database.ScanStatusClean
+	file.QuarantinedAt = nil
+	return s.repository.UpdateScan(file)
+}
+
+func (s *ScanService) work() {
+	for id := range s.queue {
+		file, err := s.repository.FindByID(id)
+		if err != nil {
+			// the file could be deleted while it waited for scanning
+			s.logger.Warn("can't load file for scanning", "id", id, "err", err)
+			continue
+		}
+		s.scan(file)
+	}
+}
+
+func (s *ScanService) scan(file *database.FileModel) {
+	logger := s.logger.New("method", "scan", "id", file.ID)
+
+	result, err := s.scanFile(file)
+	switch {
+	case err != nil:
+		file.ScanAttempts++
+		logger.Error("can't scan file", "attempts", file.ScanAttempts, "err", err)
+		file.ScanStatus = database.ScanStatusError
+	case result.Infected:
+		logger.Warn("infected file is quarantined", "signature", result.Signature, "uid", file.UserId)
+		now := time.Now()
+		file.ScanStatus = database.ScanStatusInfected
+		file.ScanSignature = result.Signature
+		file.QuarantinedAt = &now
+	default:
+		file.ScanStatus = database.ScanStatusClean
+	}
+
+	if _, err := s.repository.UpdateScan(file); err != nil {
+		logger.Error("can't update scan status", "err", err)
+	}
+}
+
+func (s *ScanService) scanFile(file *database.FileModel) (*scanner.Result, error) {
+	content, err := s.storageService.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return s.scanner.Scan(content)
+}
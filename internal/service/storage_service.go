@@ -1,39 +1,97 @@
 package service
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"regexp"
+	"strings"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/jinzhu/gorm"
 
 	"github.com/Confialink/wallet-files/internal/service/syssettings"
 
 	"github.com/Confialink/wallet-files/internal/config"
 	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/encryption"
 	"github.com/Confialink/wallet-files/internal/errcodes"
+	"github.com/Confialink/wallet-files/internal/inspection"
+	"github.com/Confialink/wallet-files/internal/metadata"
 	"github.com/Confialink/wallet-files/internal/storage"
+	"github.com/Confialink/wallet-files/internal/validation"
 	errorsPkg "github.com/Confialink/wallet-pkg-errors"
 )
 
 const MaxStorageSizePerUserBytes = 5e+7
 const MaxFileSizeBytes = 5e+6
 
+// blobDir is the directory of content addressed objects, they are spread over subdirectories by hash prefix
+const blobDir = "blobs"
+
+// Visibilities of files which storage routes match
+const (
+	VisibilityPublic    = "public"
+	VisibilityPrivate   = "private"
+	VisibilityAdminOnly = "admin-only"
+)
+
+// UploaderRoleService is the uploader role of files uploaded by other services
+const UploaderRoleService = "service"
+
+// ErrFileRetained means the file is under a legal hold or retention and must not be deleted
+var ErrFileRetained = errors.New("file is under legal hold or retention")
+
+// ErrContentUnavailable means neither the storage of the file nor its replica returned the content
+var ErrContentUnavailable = errors.New("file content is not available")
+
+// UploadListener is notified about every uploaded file
+type UploadListener interface {
+	FileUploaded(file *database.FileModel)
+}
+
+// DeleteListener is notified about every deleted file
+type DeleteListener interface {
+	FileDeleted(file *database.FileModel)
+}
+
 type StorageService struct {
 	pool       map[string]storage.Storage
 	config     *config.Config
 	repository *database.Repository
+	blobs      *database.BlobRepository
+	keys       *KeyService
+	residency  *ResidencyService
+	listeners  []UploadListener
+	deleters   []DeleteListener
+	logger     log15.Logger
 }
 
 func NewStorageService(
 	pool map[string]storage.Storage,
 	config *config.Config,
 	repository *database.Repository,
+	blobs *database.BlobRepository,
+	keys *KeyService,
+	residency *ResidencyService,
+	logger log15.Logger,
 ) *StorageService {
 	return &StorageService{
 		pool:       pool,
 		config:     config,
 		repository: repository,
+		blobs:      blobs,
+		keys:       keys,
+		residency:  residency,
+		logger:     logger,
 	}
 }
 
@@ -43,54 +101,99 @@ func (s *StorageService) Upload(
 	userId string,
 	isAdminOnly bool,
 	isPrivate bool,
+	uploaderRole string,
 	contentTypeRegexpValidator *regexp.Regexp,
 ) (*database.FileModel, errorsPkg.TypedError) {
-	st, ok := s.pool[s.config.Storage]
-	if !ok {
-		return nil, &errorsPkg.PrivateError{Message: "can't find storage"}
+	if isPrivate || isAdminOnly {
+		if tErr := s.checkLimits(userId, []int64{header.Size}); tErr != nil {
+			_ = file.Close()
+			return nil, tErr
+		}
+	}
+
+	b, props, tErr := s.prepareMultipart(file, header, nil)
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	res, tErr := s.store(b, header.Filename, userId, isAdminOnly, isPrivate, nil, uploaderRole, contentTypeRegexpValidator, props)
+	if tErr != nil {
+		return nil, tErr
 	}
 
+	s.notifyUploaded(res)
+	return res, nil
+}
+
+// UploadErrorFailed is the error of a batch upload which failed because of an internal error
+const UploadErrorFailed = "UPLOAD_FAILED"
+
+// UploadResult is the outcome of a single file upload in a batch,
+// Error is the public error code or UploadErrorFailed
+type UploadResult struct {
+	Filename string              `json:"filename"`
+	File     *database.FileModel `json:"file,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// UploadMany uploads several files. Limits are checked for the whole batch before
+// anything is uploaded, after that every file is uploaded independently.
+func (s *StorageService) UploadMany(
+	headers []*multipart.FileHeader,
+	userId string,
+	isAdminOnly bool,
+	isPrivate bool,
+	uploaderRole string,
+	contentTypeRegexpValidator *regexp.Regexp,
+) ([]*UploadResult, errorsPkg.TypedError) {
 	if isPrivate || isAdminOnly {
-		totalSize, err := s.repository.GetTotalSizeOfUserFiles(userId)
-		if err != nil {
-			pErr := &errorsPkg.PrivateError{Message: "can't get total size of user files"}
-			pErr.AddLogPair("err", err)
-			return nil, pErr
+		sizes := make([]int64, len(headers))
+		for i, header := range headers {
+			sizes[i] = header.Size
+		}
+		if tErr := s.checkLimits(userId, sizes); tErr != nil {
+			return nil, tErr
 		}
+	}
 
-		limits, err := syssettings.GetUserFilesStorageLimits()
+	results := make([]*UploadResult, len(headers))
+	for i, header := range headers {
+		results[i] = &UploadResult{Filename: header.Filename}
+
+		file, err := header.Open()
 		if err != nil {
-			pErr := &errorsPkg.PrivateError{Message: "can't get storage limits from settings service"}
-			pErr.AddLogPair("err", err)
-			return nil, pErr
+			s.logger.Error("can't read file", "filename", header.Filename, "err", err)
+			results[i].Error = UploadErrorFailed
+			continue
 		}
 
-		if header.Size > limits.FileSizeLimitBytes {
-			return nil, &errorsPkg.PublicError{
-				Title:      "File is too large",
-				Code:       errcodes.CodeFileTooLarge,
-				HttpStatus: http.StatusRequestEntityTooLarge,
-			}
+		b, props, tErr := s.prepareMultipart(file, header, nil)
+		if tErr != nil {
+			results[i].Error = s.uploadError(header.Filename, tErr)
+			continue
 		}
 
-		if totalSize+float64(header.Size) > float64(limits.TotalLimitBytes) {
-			return nil, &errorsPkg.PublicError{
-				Title:      "Not enough space in your files storage",
-				Code:       errcodes.CodeNotEnoughSpaceInFilesStorage,
-				HttpStatus: http.StatusBadRequest,
-			}
+		res, tErr := s.store(b, header.Filename, userId, isAdminOnly, isPrivate, nil, uploaderRole, contentTypeRegexpValidator, props)
+		if tErr != nil {
+			results[i].Error = s.uploadError(header.Filename, tErr)
+			continue
 		}
+
+		s.notifyUploaded(res)
+		results[i].File = res
 	}
 
-	res, err := st.Upload(file, header, userId, isAdminOnly, isPrivate, contentTypeRegexpValidator)
-	if err != nil {
-		pErr := &errorsPkg.PrivateError{Message: "can't upload file"}
-		pErr.AddLogPair("err", err)
+	return results, nil
+}
 
-		return nil, pErr
+// uploadError returns the code of a public error, other errors are logged and reported as UploadErrorFailed
+func (s *StorageService) uploadError(filename string, tErr errorsPkg.TypedError) string {
+	if pErr, ok := tErr.(*errorsPkg.PublicError); ok && pErr.Code != "" {
+		return pErr.Code
 	}
 
-	return res, nil
+	s.logger.Error("can't upload file", "filename", filename, "err", tErr.Error())
+	return UploadErrorFailed
 }
 
 func (s *StorageService) UploadBytes(
@@ -100,71 +203,918 @@ func (s *StorageService) UploadBytes(
 	isAdminOnly bool,
 	isPrivate bool,
 	category *string,
+	uploaderRole string,
 ) (*database.FileModel, errorsPkg.TypedError) {
-	st, ok := s.pool[s.config.Storage]
+	size := binary.Size(bytes)
+	if isPrivate || isAdminOnly {
+		if tErr := s.checkLimits(userId, []int64{int64(size)}); tErr != nil {
+			return nil, tErr
+		}
+	}
+
+	props, tErr := s.validate(bytes, fileName, category)
+	if tErr != nil {
+		return nil, tErr
+	}
+	bytes = s.stripMetadata(bytes, props, category)
+
+	res, tErr := s.store(bytes, fileName, userId, isAdminOnly, isPrivate, category, uploaderRole, nil, props)
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	s.notifyUploaded(res)
+	return res, nil
+}
+
+// store registers the file with the content in the default storage. Content is stored once per storage
+// as a blob named by its SHA-256, uploads of known content only reference the existing blob.
+func (s *StorageService) store(
+	b []byte,
+	fileName string,
+	userId string,
+	isAdminOnly bool,
+	isPrivate bool,
+	category *string,
+	uploaderRole string,
+	contentTypeRegexpValidator *regexp.Regexp,
+	props *database.FileProperties,
+) (*database.FileModel, errorsPkg.TypedError) {
+	targetName, err := s.residentTarget(userId, s.route(category, isAdminOnly, isPrivate, uploaderRole))
+	if err != nil {
+		pErr := &errorsPkg.PrivateError{Message: "can't resolve residency of the user"}
+		pErr.AddLogPair("err", err)
+		return nil, pErr
+	}
+	target := s.config.StorageTargets[targetName]
+	st, ok := s.pool[target.Storage]
 	if !ok {
 		return nil, &errorsPkg.PrivateError{Message: "can't find storage"}
 	}
 
-	size := binary.Size(bytes)
-	if isPrivate || isAdminOnly {
-		totalSize, err := s.repository.GetTotalSizeOfUserFiles(userId)
-		if err != nil {
-			pErr := &errorsPkg.PrivateError{Message: "can't get total size of user files"}
-			pErr.AddLogPair("err", err)
-			return nil, pErr
+	contentType := http.DetectContentType(b)
+	if contentTypeRegexpValidator != nil && !contentTypeRegexpValidator.MatchString(contentType) {
+		pErr := &errorsPkg.PrivateError{Message: "can't upload file"}
+		pErr.AddLogPair("err", "content type is not allowed")
+		return nil, pErr
+	}
+
+	// the uploaded name is only shown to users, objects are stored under random keys
+	fileName = validation.SanitizeFilename(fileName)
+	file := &database.FileModel{
+		Filename:         fileName,
+		OriginalFilename: fileName,
+		Storage:          target.Storage,
+		Bucket:           target.Bucket,
+		Target:           targetName,
+		Size:             int64(len(b)),
+		ContentType:      contentType,
+		UserId:           userId,
+		IsAdminOnly:      isAdminOnly,
+		IsPrivate:        isPrivate,
+		Category:         category,
+		Properties:       props,
+	}
+	if err := s.putContent(st, file, b, s.blobs.CreateFile); err != nil {
+		pErr := &errorsPkg.PrivateError{Message: "can't upload file"}
+		pErr.AddLogPair("err", err)
+		return nil, pErr
+	}
+
+	return file, nil
+}
+
+// residentTarget returns the target of the residency region of the user instead of the given one,
+// files must not leave the region whatever the routes are
+func (s *StorageService) residentTarget(uid string, targetName string) (string, error) {
+	region, required, err := s.residency.Region(uid)
+	if err != nil {
+		return "", err
+	}
+	if region != "" {
+		return required, nil
+	}
+	return targetName, nil
+}
+
+// route returns the name of the storage target of a new file, the first matching route wins
+func (s *StorageService) route(category *string, isAdminOnly bool, isPrivate bool, uploaderRole string) string {
+	visibility := VisibilityPublic
+	if isAdminOnly {
+		visibility = VisibilityAdminOnly
+	} else if isPrivate {
+		visibility = VisibilityPrivate
+	}
+
+	for _, route := range s.config.StorageRoutes {
+		if route.Category != "" && (category == nil || *category != route.Category) {
+			continue
 		}
+		if route.Visibility != "" && route.Visibility != visibility {
+			continue
+		}
+		if route.Role != "" && route.Role != uploaderRole {
+			continue
+		}
+		return route.Target
+	}
+	return s.config.DefaultTarget
+}
 
-		limits, err := syssettings.GetUserFilesStorageLimits()
+// ConvertToBlob moves content of a file stored before blobs were introduced into a blob in the same bucket.
+// The old object is deleted after the file references the blob.
+func (s *StorageService) ConvertToBlob(file *database.FileModel) error {
+	if file.BlobId != nil {
+		return nil
+	}
+	st, ok := s.pool[file.Storage]
+	if !ok {
+		return errors.New("storage not found")
+	}
+
+	old := storage.FileLocation(file)
+	content, err := st.OpenObject(old)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(content)
+	_ = content.Close()
+	if err != nil {
+		return err
+	}
+
+	if err = s.putContent(st, file, b, s.blobs.AttachFile); err != nil {
+		return err
+	}
+	s.deleteReplica(file, old)
+	if err = s.scheduleReplica(file); err != nil {
+		return err
+	}
+	return st.DeleteObject(old)
+}
+
+// Move moves the file content into the storage target. The content is copied and verified before the file
+// is switched to the copy, the source object is deleted when no file references it anymore.
+func (s *StorageService) Move(file *database.FileModel, targetName string) error {
+	target, ok := s.config.StorageTargets[targetName]
+	if !ok {
+		return errors.New("storage target not found")
+	}
+	dst, ok := s.pool[target.Storage]
+	if !ok {
+		return errors.New("storage not found")
+	}
+	if file.BlobId == nil {
+		if err := s.ConvertToBlob(file); err != nil {
+			return err
+		}
+	}
+
+	blob, err := s.blobs.FindByID(*file.BlobId)
+	if err != nil {
+		return err
+	}
+	if blob.Storage == target.Storage && blob.Bucket == target.Bucket {
+		return nil
+	}
+	src, ok := s.pool[blob.Storage]
+	if !ok {
+		return errors.New("storage not found")
+	}
+
+	file.Target = targetName
+	var newBlob *database.BlobModel
+	if _, err = s.blobs.Find(blob.Sha256, file.UserId, target.Storage, target.Bucket); gorm.IsRecordNotFoundError(err) {
+		if newBlob, err = s.copyBlob(src, dst, blob, s.putOptions(file)); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	_, err = s.blobs.MoveFile(file, target.Storage, target.Bucket, newBlob, s.shareKey, func(blob *database.BlobModel) error {
+		s.deleteReplica(file, blobLocation(blob))
+		return src.DeleteObject(blobLocation(blob))
+	})
+	if newBlob != nil && (err != nil || file.Path != newBlob.Path) {
+		// the copy is not used, the file references a blob which was created concurrently
+		_ = dst.DeleteObject(blobLocation(newBlob))
+	}
+	if err != nil {
+		return err
+	}
+	return s.scheduleReplica(file)
+}
+
+// Replicate copies the stored object of the file as is into the replica target and verifies the copy
+func (s *StorageService) Replicate(file *database.FileModel, targetName string) error {
+	target, ok := s.config.StorageTargets[targetName]
+	if !ok {
+		return errors.New("replica target not found")
+	}
+	if file.Storage == target.Storage && file.Bucket == target.Bucket {
+		return errors.New("file is stored in the replica target")
+	}
+	src, ok := s.pool[file.Storage]
+	if !ok {
+		return errors.New("storage not found")
+	}
+	dst, ok := s.pool[target.Storage]
+	if !ok {
+		return errors.New("storage not found")
+	}
+
+	location := storage.FileLocation(file)
+	content, err := src.OpenObject(location)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(content)
+	_ = content.Close()
+	if err != nil {
+		return err
+	}
+
+	contentType := file.ContentType
+	if file.WrappedKey != nil {
+		contentType = "application/octet-stream"
+	}
+	options := &storage.PutOptions{Bucket: target.Bucket, ACL: target.ACL, StorageClass: target.StorageClass}
+	replica, err := dst.PutObject(location.Path, location.Filename, bytes.NewReader(b), contentType, options)
+	if err != nil {
+		return err
+	}
+	if err = verifyObject(dst, replica, b); err != nil {
+		_ = dst.DeleteObject(replica)
+		return err
+	}
+
+	file.ReplicaStorage, file.ReplicaBucket, file.ReplicaPath = &replica.Storage, &replica.Bucket, &replica.Path
+	return nil
+}
+
+// ReplicaTarget returns the replica target of the residency region of the owner of the file. It is empty
+// if the file must not be replicated or it is already stored in the bucket of the replica target.
+func (s *StorageService) ReplicaTarget(file *database.FileModel) (string, error) {
+	name, err := s.residency.ReplicaTarget(file.UserId)
+	if err != nil || name == "" {
+		return "", err
+	}
+	if target := s.config.StorageTargets[name]; file.Storage == target.Storage && file.Bucket == target.Bucket {
+		return "", nil
+	}
+	return name, nil
+}
+
+// MoveReplica replicates the file into its current replica target and deletes the previous replica
+// unless other files of the blob still reference it
+func (s *StorageService) MoveReplica(file *database.FileModel) error {
+	old := replicaLocation(file, storage.FileLocation(file))
+	targetName, err := s.ReplicaTarget(file)
+	if err != nil {
+		return err
+	}
+
+	file.ReplicaAttempts, file.ReplicaRetryAt = 0, nil
+	if targetName == "" {
+		file.ReplicaStatus = ""
+		file.ReplicaStorage, file.ReplicaBucket, file.ReplicaPath = nil, nil, nil
+	} else {
+		if err = s.Replicate(file, targetName); err != nil {
+			return err
+		}
+		file.ReplicaStatus = database.ReplicaStatusDone
+	}
+	if _, err = s.repository.UpdateReplica(file); err != nil {
+		return err
+	}
+
+	if old == nil {
+		return nil
+	}
+	if current := replicaLocation(file, storage.FileLocation(file)); current != nil && *current == *old {
+		return nil
+	}
+	if file.BlobId != nil {
+		count, err := s.repository.CountReplicas(*file.BlobId, old.Storage, old.Bucket)
+		if err != nil || count > 0 {
+			return err
+		}
+	}
+	return s.DeleteObject(old)
+}
+
+// scheduleReplica makes a replicated file pending after its object changed
+func (s *StorageService) scheduleReplica(file *database.FileModel) error {
+	if file.ReplicaStatus == "" {
+		return nil
+	}
+
+	now := time.Now()
+	file.ReplicaStatus = database.ReplicaStatusPending
+	file.ReplicaAttempts = 0
+	file.ReplicaRetryAt = &now
+	_, err := s.repository.UpdateReplica(file)
+	return err
+}
+
+// replicaLocation returns location of the replica of the object of the file, it is nil if there is no replica
+func replicaLocation(file *database.FileModel, object *storage.Location) *storage.Location {
+	if file.ReplicaStorage == nil {
+		return nil
+	}
+
+	location := &storage.Location{Storage: *file.ReplicaStorage, Filename: object.Filename}
+	if file.ReplicaBucket != nil {
+		location.Bucket = *file.ReplicaBucket
+	}
+	if file.ReplicaPath != nil {
+		location.Path = *file.ReplicaPath
+	}
+	return location
+}
+
+// deleteReplica deletes the replica of the object. The replica is a copy for disaster recovery,
+// so a failure is not fatal and may only leave an orphaned object.
+func (s *StorageService) deleteReplica(file *database.FileModel, object *storage.Location) {
+	if location := replicaLocation(file, object); location != nil {
+		_ = s.DeleteObject(location)
+	}
+}
+
+// copyBlob copies the blob object into the target storage and verifies the checksum of the copy
+func (s *StorageService) copyBlob(
+	src storage.Storage,
+	dst storage.Storage,
+	blob *database.BlobModel,
+	options *storage.PutOptions,
+) (*database.BlobModel, error) {
+	content, err := src.OpenObject(blobLocation(blob))
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(content)
+	_ = content.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := newBlobDir(blob.Sha256)
+	if err != nil {
+		return nil, err
+	}
+	location, err := dst.PutObject(dir, blob.Sha256, bytes.NewReader(b), "application/octet-stream", options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verifyObject(dst, location, b); err != nil {
+		_ = dst.DeleteObject(location)
+		return nil, err
+	}
+
+	return &database.BlobModel{
+		Sha256:   blob.Sha256,
+		Storage:  location.Storage,
+		Bucket:   location.Bucket,
+		Path:     location.Path,
+		Filename: location.Filename,
+		Size:     int64(len(b)),
+	}, nil
+}
+
+// verifyObject reads the stored object back and compares its checksum with the expected content
+func verifyObject(st storage.Storage, location *storage.Location, expected []byte) error {
+	content, err := st.OpenObject(location)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, content); err != nil {
+		return err
+	}
+	if sum := sha256.Sum256(expected); !bytes.Equal(h.Sum(nil), sum[:]) {
+		return errors.New("checksum of the copy does not match")
+	}
+	return nil
+}
+
+// attachBlob is CreateFile or AttachFile of the blob repository
+type attachBlob func(file *database.FileModel, sha256 string, newBlob *database.BlobModel, shareKey database.ShareKey) (*database.FileModel, error)
+
+// putContent makes the file reference the blob of the content, the blob is written if the content is new
+// in the bucket of the file
+func (s *StorageService) putContent(st storage.Storage, file *database.FileModel, b []byte, attach attachBlob) error {
+	sum := sha256.Sum256(b)
+	hash := hex.EncodeToString(sum[:])
+
+	var err error
+	// a blob may be created or deleted concurrently between the lookup and the reference, so it is retried once
+	for attempt := 0; attempt < 2; attempt++ {
+		var newBlob *database.BlobModel
+		if _, err = s.blobs.Find(hash, file.UserId, file.Storage, file.Bucket); gorm.IsRecordNotFoundError(err) {
+			if newBlob, err = s.putBlob(st, file, b, hash); err != nil {
+				return err
+			}
+		}
+
+		_, err = attach(file, hash, newBlob, s.shareKey)
+		if newBlob != nil && (err != nil || file.Path != newBlob.Path) {
+			// the blob was not created, a concurrent upload of the same content won
+			_ = st.DeleteObject(blobLocation(newBlob))
+		}
+		if err != database.ErrBlobMissing {
+			return err
+		}
+	}
+	return err
+}
+
+// putOptions returns options of new objects of the file target,
+// files uploaded before routing keep their bucket
+func (s *StorageService) putOptions(file *database.FileModel) *storage.PutOptions {
+	target, ok := s.config.StorageTargets[file.Target]
+	if !ok {
+		return &storage.PutOptions{Bucket: file.Bucket}
+	}
+	return &storage.PutOptions{Bucket: target.Bucket, ACL: target.ACL, StorageClass: target.StorageClass}
+}
+
+// putBlob writes the content as a new blob object. Objects of blobs are named by the hash of the content
+// under a random directory, so concurrent uploads of the same content never overwrite each other.
+// The content is encrypted with a new data key which is wrapped for the file owner if encryption is enabled.
+func (s *StorageService) putBlob(st storage.Storage, file *database.FileModel, b []byte, hash string) (*database.BlobModel, error) {
+	file.WrappedKey, file.KeyId = nil, nil
+	contentType := file.ContentType
+	if s.keys.Enabled() {
+		dataKey, err := encryption.GenerateKey()
 		if err != nil {
-			pErr := &errorsPkg.PrivateError{Message: "can't get storage limits from settings service"}
-			pErr.AddLogPair("err", err)
-			return nil, pErr
+			return nil, err
+		}
+		if err = s.keys.WrapDataKey(file, dataKey); err != nil {
+			return nil, err
+		}
+		if b, err = encryption.Encrypt(b, dataKey); err != nil {
+			return nil, err
+		}
+		contentType = "application/octet-stream"
+	}
+
+	dir, err := newBlobDir(hash)
+	if err != nil {
+		return nil, err
+	}
+	location, err := st.PutObject(dir, hash, bytes.NewReader(b), contentType, s.putOptions(file))
+	if err != nil {
+		return nil, err
+	}
+
+	return &database.BlobModel{
+		Sha256:   hash,
+		Storage:  location.Storage,
+		Bucket:   location.Bucket,
+		Path:     location.Path,
+		Filename: location.Filename,
+		Size:     int64(len(b)),
+	}, nil
+}
+
+// shareKey gives the file the data key of the blob which is wrapped for another file referencing it
+func (s *StorageService) shareKey(file *database.FileModel, sibling *database.FileModel) error {
+	file.WrappedKey, file.KeyId = nil, nil
+	if sibling.WrappedKey == nil {
+		return nil
+	}
+
+	key, err := s.keys.UnwrapDataKey(sibling)
+	if err != nil {
+		return err
+	}
+	return s.keys.WrapDataKey(file, key)
+}
+
+// RewrapKey wraps the data key of a file encrypted before user keys were introduced with the key of its owner,
+// the content is not re-encrypted
+func (s *StorageService) RewrapKey(file *database.FileModel) error {
+	if file.WrappedKey == nil || (file.KeyId != nil && *file.KeyId == database.UserKeyId) {
+		return nil
+	}
+
+	key, err := s.keys.UnwrapDataKey(file)
+	if err != nil {
+		return err
+	}
+	oldKeyId := *file.KeyId
+	if err = s.keys.WrapDataKey(file, key); err != nil {
+		return err
+	}
+	return s.repository.UpdateWrappedKey(file, oldKeyId)
+}
+
+// prepareMultipart reads and closes the uploaded file, validates its content and strips metadata
+func (s *StorageService) prepareMultipart(
+	file multipart.File,
+	header *multipart.FileHeader,
+	category *string,
+) ([]byte, *database.FileProperties, errorsPkg.TypedError) {
+	b, err := ioutil.ReadAll(file)
+	_ = file.Close()
+	if err != nil {
+		pErr := &errorsPkg.PrivateError{Message: "can't read file"}
+		pErr.AddLogPair("err", err)
+		return nil, nil, pErr
+	}
+
+	props, tErr := s.validate(b, header.Filename, category)
+	if tErr != nil {
+		return nil, nil, tErr
+	}
+
+	return s.stripMetadata(b, props, category), props, nil
+}
+
+// validate checks that the content type is allowed for the category and matches the file extension,
+// then inspects the document structure. Documents with problems are rejected if it is configured.
+func (s *StorageService) validate(b []byte, fileName string, category *string) (*database.FileProperties, errorsPkg.TypedError) {
+	allowed := s.config.AllowedTypes[config.DefaultCategory]
+	if category != nil {
+		if categoryAllowed, ok := s.config.AllowedTypes[*category]; ok {
+			allowed = categoryAllowed
+		}
+	}
+
+	fileType, err := validation.Validate(b, fileName, allowed)
+	switch err {
+	case nil:
+	case validation.ErrTypeMismatch:
+		return nil, &errorsPkg.PublicError{
+			Title:      "File content does not match its extension",
+			Code:       errcodes.FileTypeMismatch,
+			HttpStatus: errcodes.StatusCodes[errcodes.FileTypeMismatch],
+		}
+	default:
+		return nil, &errorsPkg.PublicError{
+			Title:      "File type is not supported",
+			Code:       errcodes.UnsupportedFileType,
+			HttpStatus: errcodes.StatusCodes[errcodes.UnsupportedFileType],
 		}
+	}
 
-		if int64(size) > limits.FileSizeLimitBytes {
-			return nil, &errorsPkg.PublicError{
+	props := inspection.Inspect(b, fileType.Name, s.config.Inspection.Limits)
+	if len(props.Problems) == 0 || !s.config.Inspection.Reject {
+		return props, nil
+	}
+
+	code := errcodes.FileLimitsExceeded
+	switch props.Problems[0] {
+	case inspection.ProblemCorrupt:
+		code = errcodes.CorruptFile
+	case inspection.ProblemEncrypted:
+		code = errcodes.EncryptedFile
+	}
+	return nil, &errorsPkg.PublicError{
+		Title:      "File can not be accepted",
+		Details:    strings.Join(props.Problems, ", "),
+		Code:       code,
+		HttpStatus: errcodes.StatusCodes[code],
+	}
+}
+
+// stripEnabled checks if metadata stripping is enabled for the category and supported for the format
+func (s *StorageService) stripEnabled(category *string, props *database.FileProperties) bool {
+	enabled := s.config.StripMetadata[config.DefaultCategory]
+	if category != nil {
+		if categoryEnabled, ok := s.config.StripMetadata[*category]; ok {
+			enabled = categoryEnabled
+		}
+	}
+	return enabled && metadata.Supported(props.Format)
+}
+
+// stripMetadata removes metadata from the content if it is enabled for the category, properties
+// are marked as sanitized. The original content is returned if its structure can not be parsed.
+func (s *StorageService) stripMetadata(b []byte, props *database.FileProperties, category *string) []byte {
+	if !s.stripEnabled(category, props) {
+		return b
+	}
+
+	stripped, err := metadata.Strip(b, props.Format)
+	if err != nil {
+		return b
+	}
+	props.Sanitized = true
+	return stripped
+}
+
+// checkLimits checks that every file fits the file size limit
+// and all files together fit the user storage limit
+func (s *StorageService) checkLimits(userId string, sizes []int64) errorsPkg.TypedError {
+	totalSize, err := s.repository.GetTotalSizeOfUserFiles(userId)
+	if err != nil {
+		pErr := &errorsPkg.PrivateError{Message: "can't get total size of user files"}
+		pErr.AddLogPair("err", err)
+		return pErr
+	}
+
+	limits, err := syssettings.GetUserFilesStorageLimits()
+	if err != nil {
+		pErr := &errorsPkg.PrivateError{Message: "can't get storage limits from settings service"}
+		pErr.AddLogPair("err", err)
+		return pErr
+	}
+
+	for _, size := range sizes {
+		if size > limits.FileSizeLimitBytes {
+			return &errorsPkg.PublicError{
 				Title:      "File is too large",
 				Code:       errcodes.CodeFileTooLarge,
 				HttpStatus: http.StatusRequestEntityTooLarge,
 			}
 		}
+		totalSize += float64(size)
+	}
 
-		if totalSize+float64(int64(size)) > float64(limits.TotalLimitBytes) {
-			return nil, &errorsPkg.PublicError{
-				Title:      "Not enough space in your files storage",
-				Code:       errcodes.CodeNotEnoughSpaceInFilesStorage,
-				HttpStatus: http.StatusBadRequest,
-			}
+	if totalSize > float64(limits.TotalLimitBytes) {
+		return &errorsPkg.PublicError{
+			Title:      "Not enough space in your files storage",
+			Code:       errcodes.CodeNotEnoughSpaceInFilesStorage,
+			HttpStatus: http.StatusBadRequest,
 		}
 	}
 
-	res, err := st.UploadBytes(bytes, fileName, userId, isAdminOnly, isPrivate, category)
-	if err != nil {
-		pErr := &errorsPkg.PrivateError{Message: "can't upload bytes"}
-		pErr.AddLogPair("err", err)
+	return nil
+}
 
-		return nil, pErr
-	}
+// AddUploadListener registers a listener of uploaded files
+func (s *StorageService) AddUploadListener(listener UploadListener) {
+	s.listeners = append(s.listeners, listener)
+}
 
-	return res, nil
+func (s *StorageService) notifyUploaded(file *database.FileModel) {
+	for _, listener := range s.listeners {
+		listener.FileUploaded(file)
+	}
 }
 
-// Delete deletes file from bucket and database
+// Delete deletes file from database, its blob is deleted with the last file referencing it.
+// ErrFileRetained is returned if the file is under a legal hold or retention.
 func (s *StorageService) Delete(file *database.FileModel) error {
+	if s.RetainReason(file, time.Now()) != "" {
+		return ErrFileRetained
+	}
+
 	st, ok := s.pool[file.Storage]
 	if !ok {
 		return errors.New("storage not found")
 	}
 
-	return st.Delete(file)
+	var err error
+	if file.BlobId != nil {
+		err = s.blobs.DeleteFile(file, func(blob *database.BlobModel) error {
+			s.deleteReplica(file, blobLocation(blob))
+			return st.DeleteObject(blobLocation(blob))
+		})
+	} else {
+		s.deleteReplica(file, storage.FileLocation(file))
+		err = st.Delete(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, listener := range s.deleters {
+		listener.FileDeleted(file)
+	}
+	return nil
 }
 
-func (s *StorageService) Download(file *database.FileModel) []byte {
-	st, ok := s.pool[file.Storage]
+// Unscanned checks if the file must not be served until it is scanned
+func (s *StorageService) Unscanned(file *database.FileModel) bool {
+	return s.config.ClamdAddress != "" && s.config.ScanBlockUnscanned && file.IsScanPending()
+}
+
+// RetainReason returns KeepReasonLegalHold or KeepReasonRetention if the file must not be deleted at the time,
+// it is empty otherwise
+func (s *StorageService) RetainReason(file *database.FileModel, now time.Time) string {
+	if file.LegalHold {
+		return KeepReasonLegalHold
+	}
+
+	if file.RetainUntil != nil && file.RetainUntil.After(now) {
+		return KeepReasonRetention
+	}
+
+	if file.Category != nil {
+		if period, ok := s.config.RetentionPeriods[*file.Category]; ok && file.CreatedAt.Add(period).After(now) {
+			return KeepReasonRetention
+		}
+	}
+
+	return ""
+}
+
+// AddDeleteListener registers a listener of deleted files
+func (s *StorageService) AddDeleteListener(listener DeleteListener) {
+	s.deleters = append(s.deleters, listener)
+}
+
+// Download returns the decrypted file content. ErrKeyShredded is returned if the key of the owner is destroyed,
+// ErrContentUnavailable if the content can't be read.
+func (s *StorageService) Download(file *database.FileModel) ([]byte, error) {
+	key, err := s.keys.UnwrapDataKey(file)
+	if err != nil {
+		return nil, err
+	}
+
+	b := s.download(file)
+	if len(b) == 0 && (file.Size > 0 || key != nil) {
+		return nil, ErrContentUnavailable
+	}
+	if key == nil {
+		return b, nil
+	}
+	return encryption.Decrypt(b, key)
+}
+
+// download returns the stored object of the file, it is read from the replica if the storage fails
+func (s *StorageService) download(file *database.FileModel) []byte {
+	if st, ok := s.pool[file.Storage]; ok {
+		// storages return no content instead of errors, empty files are empty unless encrypted
+		if b := st.Download(file); len(b) > 0 || (file.Size == 0 && file.WrappedKey == nil) {
+			return b
+		}
+	}
+	if file.ReplicaStatus != database.ReplicaStatusDone || file.ReplicaStorage == nil {
+		return nil
+	}
+
+	st, ok := s.pool[*file.ReplicaStorage]
 	if !ok {
 		return nil
 	}
+	location := replicaLocation(file, storage.FileLocation(file))
+	replica := *file
+	replica.Storage, replica.Bucket, replica.Path = location.Storage, location.Bucket, location.Path
+	return st.Download(&replica)
+}
+
+// Open opens the file content for reading, the caller must close it. Encrypted content is decrypted while it is read.
+// The replica is read if the storage of the file fails.
+func (s *StorageService) Open(file *database.FileModel) (io.ReadCloser, error) {
+	key, err := s.keys.UnwrapDataKey(file)
+	if err != nil {
+		return nil, err
+	}
+
+	location := storage.FileLocation(file)
+	content, err := s.openObject(location)
+	if err != nil && file.ReplicaStatus == database.ReplicaStatusDone {
+		if replica := replicaLocation(file, location); replica != nil {
+			content, err = s.openObject(replica)
+		}
+	}
+	if err != nil || key == nil {
+		return content, err
+	}
+
+	r, err := encryption.NewReader(content, key)
+	if err != nil {
+		_ = content.Close()
+		return nil, err
+	}
+	return &decryptingReader{Reader: r, Closer: content}, nil
+}
 
-	return st.Download(file)
+// decryptingReader decrypts content of the underlying object and closes it
+type decryptingReader struct {
+	io.Reader
+	io.Closer
+}
+
+// PutObject stores an object derived from files of the user which is not registered as a file, e.g. a variant or
+// an export archive. The object is kept in the target of the user residency or the default one under a random
+// directory, it is encrypted with a new data key which is wrapped for the user into key if encryption is enabled.
+func (s *StorageService) PutObject(
+	uid string,
+	dir string,
+	name string,
+	body io.Reader,
+	contentType string,
+	key *database.ObjectKey,
+) (*storage.Location, error) {
+	targetName, err := s.residentTarget(uid, s.config.DefaultTarget)
+	if err != nil {
+		return nil, err
+	}
+	target := s.config.StorageTargets[targetName]
+	st, ok := s.pool[target.Storage]
+	if !ok {
+		return nil, errors.New("storage not found")
+	}
+	if dir, err = newObjectDir(dir); err != nil {
+		return nil, err
+	}
+
+	key.WrappedKey, key.KeyId = nil, nil
+	if s.keys.Enabled() {
+		dataKey, err := encryption.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		if err = s.keys.WrapObjectKey(uid, key, dataKey); err != nil {
+			return nil, err
+		}
+		encrypted := encryptingReader(body, dataKey)
+		// the encrypting goroutine stops if the storage does not read the whole content
+		defer encrypted.Close()
+		body, contentType = encrypted, "application/octet-stream"
+	}
+
+	return st.PutObject(dir, name, body, contentType, &storage.PutOptions{Bucket: target.Bucket, StorageClass: target.StorageClass})
+}
+
+// OpenObject opens an object derived from files of the user for reading, the caller must close it.
+// Encrypted content is decrypted while it is read.
+func (s *StorageService) OpenObject(uid string, location *storage.Location, key *database.ObjectKey) (io.ReadCloser, error) {
+	dataKey, err := s.keys.UnwrapObjectKey(uid, key)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.openObject(location)
+	if err != nil || dataKey == nil {
+		return content, err
+	}
+
+	r, err := encryption.NewReader(content, dataKey)
+	if err != nil {
+		_ = content.Close()
+		return nil, err
+	}
+	return &decryptingReader{Reader: r, Closer: content}, nil
+}
+
+// openObject opens a stored object for reading, the caller must close it
+func (s *StorageService) openObject(location *storage.Location) (io.ReadCloser, error) {
+	st, ok := s.pool[location.Storage]
+	if !ok {
+		return nil, errors.New("storage not found")
+	}
+
+	return st.OpenObject(location)
+}
+
+// encryptingReader returns the content encrypted with the data key, it is encrypted while it is read
+func encryptingReader(r io.Reader, dataKey []byte) *io.PipeReader {
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := encryption.NewWriter(pw, dataKey)
+		if err == nil {
+			_, err = io.Copy(w, r)
+		}
+		if err == nil {
+			err = w.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// DeleteObject deletes an object from its storage
+func (s *StorageService) DeleteObject(location *storage.Location) error {
+	st, ok := s.pool[location.Storage]
+	if !ok {
+		return errors.New("storage not found")
+	}
+
+	return st.DeleteObject(location)
+}
+
+// newBlobDir returns a random directory for a new object of the blob
+func newBlobDir(hash string) (string, error) {
+	dir := make([]byte, 8)
+	if _, err := rand.Read(dir); err != nil {
+		return "", err
+	}
+	return blobDir + "/" + hash[:2] + "/" + hex.EncodeToString(dir), nil
+}
+
+// newObjectDir returns a random directory for a new derived object, so objects are never overwritten
+func newObjectDir(dir string) (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return dir + "/" + hex.EncodeToString(random), nil
+}
+
+func blobLocation(blob *database.BlobModel) *storage.Location {
+	return &storage.Location{
+		Storage:  blob.Storage,
+		Bucket:   blob.Bucket,
+		Path:     blob.Path,
+		Filename: blob.Filename,
+	}
 }
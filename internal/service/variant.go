@@ -0,0 +1,384 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+	"github.com/jinzhu/gorm"
+
+	"github.com/Confialink/wallet-files/internal/config"
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/imaging"
+	"github.com/Confialink/wallet-files/internal/storage"
+)
+
+const (
+	VariantThumb  = "thumb"
+	VariantSmall  = "small"
+	VariantMedium = "medium"
+)
+
+// Formats of variants
+const (
+	VariantFormatJpeg = "jpeg"
+	VariantFormatPng  = "png"
+	VariantFormatWebp = "webp"
+)
+
+// maxVariantSourcePixels limits images which are decoded to generate variants
+const maxVariantSourcePixels = 50e6
+
+const defaultVariantQuality = 85
+
+var (
+	ErrVariantUnknown     = errors.New("unknown variant")
+	ErrVariantUnsupported = errors.New("variants are not supported for the file")
+)
+
+// Variant is a resized or converted copy of an image file
+type Variant struct {
+	Name string
+	// Size is the maximal width and height of the variant, zero keeps the original size
+	Size int
+	// Crop crops the image to a square before resizing
+	Crop bool
+	// Format is the output format, jpeg sources are kept in jpeg and others are converted into png if empty
+	Format string
+	// Quality of lossy formats from 1 to 100, defaultVariantQuality is used if zero or format is not set
+	Quality int
+}
+
+// Key identifies the stored variant, variants in the default format are stored by name
+func (v *Variant) Key() string {
+	if v.Format == "" {
+		return v.Name
+	}
+	return fmt.Sprintf("%s-%s-q%d", v.Name, v.Format, v.quality())
+}
+
+func (v *Variant) quality() int {
+	if v.Quality == 0 || v.Format == "" {
+		return defaultVariantQuality
+	}
+	return v.Quality
+}
+
+// Variants are all predefined variants
+var Variants = []*Variant{
+	{Name: VariantThumb, Size: 150, Crop: true},
+	{Name: VariantSmall, Size: 320},
+	{Name: VariantMedium, Size: 800},
+}
+
+// VariantService generates and stores resized copies of image files.
+// If webp encoder is nil conversion into webp is not supported.
+type VariantService struct {
+	repository     *database.VariantRepository
+	storageService *StorageService
+	webp           *imaging.WebpEncoder
+	config         *config.Config
+	logger         log15.Logger
+}
+
+func NewVariantService(
+	repository *database.VariantRepository,
+	storageService *StorageService,
+	webp *imaging.WebpEncoder,
+	config *config.Config,
+	logger log15.Logger,
+) *VariantService {
+	return &VariantService{
+		repository:     repository,
+		storageService: storageService,
+		webp:           webp,
+		config:         config,
+		logger:         logger,
+	}
+}
+
+// FindVariant returns the predefined variant by name or nil if it is unknown
+func FindVariant(name string) *Variant {
+	for _, v := range Variants {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// RoundSize returns the smallest configured variant size which is not less than the requested size
+// or the largest one
+func (s *VariantService) RoundSize(size int) int {
+	for _, configured := range s.config.VariantSizes {
+		if configured >= size {
+			return configured
+		}
+	}
+	return s.config.VariantSizes[len(s.config.VariantSizes)-1]
+}
+
+// RoundQuality returns the configured variant quality which is the closest to the requested quality
+func (s *VariantService) RoundQuality(quality int) int {
+	closest := s.config.VariantQualities[0]
+	for _, configured := range s.config.VariantQualities {
+		if abs(configured-quality) < abs(closest-quality) {
+			closest = configured
+		}
+	}
+	return closest
+}
+
+// Supported checks if variants can be generated for the file
+func (s *VariantService) Supported(file *database.FileModel) bool {
+	return variantSourceFormat(file) != ""
+}
+
+// FormatSupported checks if variants can be converted into the format
+func (s *VariantService) FormatSupported(format string) bool {
+	switch format {
+	case VariantFormatJpeg, VariantFormatPng:
+		return true
+	case VariantFormatWebp:
+		return s.webp != nil
+	}
+	return false
+}
+
+// Open opens the variant of the file, the variant is generated if it does not exist yet.
+// The caller must close the content.
+func (s *VariantService) Open(file *database.FileModel, v *Variant) (io.ReadCloser, *database.VariantModel, error) {
+	if v.Format != "" && !s.FormatSupported(v.Format) {
+		return nil, nil, ErrVariantUnknown
+	}
+	if !s.Supported(file) {
+		return nil, nil, ErrVariantUnsupported
+	}
+
+	variant, err := s.repository.Find(file.ID, v.Key())
+	if gorm.IsRecordNotFoundError(err) {
+		variants, err := s.generate(file, []*Variant{v})
+		if err != nil {
+			return nil, nil, err
+		}
+		variant = variants[0]
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.storageService.OpenObject(file.UserId, variantLocation(variant), &variant.ObjectKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, variant, nil
+}
+
+// Generate creates predefined variants of the file, all of them are created if no names are given
+func (s *VariantService) Generate(file *database.FileModel, names ...string) ([]*database.VariantModel, error) {
+	variants := Variants
+	if len(names) > 0 {
+		variants = make([]*Variant, 0, len(names))
+		for _, name := range names {
+			v := FindVariant(name)
+			if v == nil {
+				return nil, ErrVariantUnknown
+			}
+			variants = append(variants, v)
+		}
+	}
+
+	return s.generate(file, variants)
+}
+
+func (s *VariantService) generate(file *database.FileModel, variants []*Variant) ([]*database.VariantModel, error) {
+	format := variantSourceFormat(file)
+	if format == "" {
+		return nil, ErrVariantUnsupported
+	}
+
+	img, err := decodeImage(s.storageService, file, format)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*database.VariantModel, 0, len(variants))
+	for _, v := range variants {
+		model, err := s.store(file, v, img, format)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, model)
+	}
+	return result, nil
+}
+
+// FileDeleted deletes all variants of the deleted file
+func (s *VariantService) FileDeleted(file *database.FileModel) {
+	variants, err := s.repository.FindByFileID(file.ID)
+	if err != nil {
+		s.logger.Error("can't find variants of deleted file", "id", file.ID, "err", err)
+		return
+	}
+
+	for _, variant := range variants {
+		if err := s.storageService.DeleteObject(variantLocation(variant)); err != nil {
+			s.logger.Error("can't delete variant", "id", file.ID, "variant", variant.Name, "err", err)
+			continue
+		}
+		if err := s.repository.Delete(variant); err != nil {
+			s.logger.Error("can't delete variant", "id", file.ID, "variant", variant.Name, "err", err)
+		}
+	}
+}
+
+// store resizes the image and saves the variant. If the variant was stored concurrently
+// the existing one is returned.
+func (s *VariantService) store(file *database.FileModel, v *Variant, img image.Image, sourceFormat string) (*database.VariantModel, error) {
+	var resized image.Image
+	switch {
+	case v.Crop:
+		resized = imaging.Thumbnail(img, v.Size)
+	case v.Size > 0:
+		resized = imaging.Fit(img, v.Size, v.Size)
+	default:
+		resized = img
+	}
+
+	// jpeg has no transparency, other formats are converted into png by default to keep it
+	format := v.Format
+	if format == "" {
+		format = VariantFormatPng
+		if sourceFormat == "jpeg" {
+			format = VariantFormatJpeg
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.encode(&buf, resized, format, v.quality()); err != nil {
+		return nil, err
+	}
+
+	size := int64(buf.Len())
+	contentType := "image/" + format
+	name := fmt.Sprintf("%d-%s.%s", file.ID, v.Key(), format)
+	var key database.ObjectKey
+	location, err := s.storageService.PutObject(file.UserId, "variants", name, &buf, contentType, &key)
+	if err != nil {
+		return nil, err
+	}
+
+	variant, err := s.repository.Create(&database.VariantModel{
+		FileId:      file.ID,
+		Name:        v.Key(),
+		Storage:     location.Storage,
+		Bucket:      location.Bucket,
+		Path:        location.Path,
+		Filename:    location.Filename,
+		ContentType: contentType,
+		Size:        size,
+		Width:       resized.Bounds().Dx(),
+		Height:      resized.Bounds().Dy(),
+		ObjectKey:   key,
+	})
+	if err != nil {
+		if existing, findErr := s.repository.Find(file.ID, v.Key()); findErr == nil {
+			// the variant was stored by a concurrent request
+			_ = s.storageService.DeleteObject(location)
+			return existing, nil
+		}
+		_ = s.storageService.DeleteObject(location)
+		return nil, err
+	}
+	return variant, nil
+}
+
+func (s *VariantService) encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case VariantFormatJpeg:
+		return jpeg.Encode(w, imaging.Flatten(img, color.White), &jpeg.Options{Quality: quality})
+	case VariantFormatPng:
+		return png.Encode(w, img)
+	case VariantFormatWebp:
+		if s.webp != nil {
+			return s.webp.Encode(w, img, quality)
+		}
+	}
+	return ErrVariantUnknown
+}
+
+// decodeImage reads the image file of the format and turns it upright
+func decodeImage(storageService *StorageService, file *database.FileModel, format string) (image.Image, error) {
+	content, err := storageService.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(content)
+	_ = content.Close()
+	if err != nil {
+		return nil, err
+	}
+	return decodeImageBytes(b, format)
+}
+
+// decodeImageBytes decodes the image content of the format and turns it upright
+func decodeImageBytes(b []byte, format string) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if float64(cfg.Width)*float64(cfg.Height) > maxVariantSourcePixels {
+		return nil, ErrVariantUnsupported
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "jpeg" {
+		img = imaging.Orient(img, imaging.Orientation(b))
+	}
+	return img, nil
+}
+
+// variantSourceFormat returns the format of the image if variants can be generated from it
+func variantSourceFormat(file *database.FileModel) string {
+	var format string
+	if file.Properties != nil {
+		format = file.Properties.Format
+	} else {
+		// files uploaded before properties were introduced
+		format = strings.TrimPrefix(file.ContentType, "image/")
+	}
+
+	switch format {
+	case "jpeg", "png", "gif":
+		return format
+	}
+	return ""
+}
+
+func variantLocation(variant *database.VariantModel) *storage.Location {
+	return &storage.Location{
+		Storage:  variant.Storage,
+		Bucket:   variant.Bucket,
+		Path:     variant.Path,
+		Filename: variant.Filename,
+	}
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
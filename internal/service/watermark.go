@@ -0,0 +1,104 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"time"
+
+	"github.com/Confialink/wallet-files/internal/config"
+	"github.com/Confialink/wallet-files/internal/database"
+	"github.com/Confialink/wallet-files/internal/imaging"
+	"github.com/Confialink/wallet-files/internal/pdf"
+)
+
+const watermarkJpegQuality = 90
+
+// ErrWatermarkUnsupported means the watermark can not be added to the file
+var ErrWatermarkUnsupported = errors.New("watermark is not supported for the file")
+
+// watermarkColor is semi-transparent gray which is visible on both light and dark images
+var watermarkColor = color.NRGBA{R: 128, G: 128, B: 128, A: 96}
+
+// WatermarkService stamps downloaded documents with the user who downloaded them and the time.
+// Watermarks are added on the fly, stored files are never modified.
+type WatermarkService struct {
+	storageService *StorageService
+	config         *config.Config
+}
+
+func NewWatermarkService(storageService *StorageService, config *config.Config) *WatermarkService {
+	return &WatermarkService{storageService: storageService, config: config}
+}
+
+// Forced checks if downloads of the file by admins must be watermarked because of its category
+func (s *WatermarkService) Forced(file *database.FileModel) bool {
+	return file.Category != nil && s.config.WatermarkCategories[*file.Category]
+}
+
+// Supported checks if the watermark can be added to the file
+func (s *WatermarkService) Supported(file *database.FileModel) bool {
+	return isPdf(file) || variantSourceFormat(file) != ""
+}
+
+// Watermark returns content of the file with the text over every page or over the image and its content type.
+// Jpeg images are kept in jpeg, other images are converted into png.
+func (s *WatermarkService) Watermark(file *database.FileModel, text string, at time.Time) ([]byte, string, error) {
+	lines := []string{text, at.UTC().Format(time.RFC3339)}
+
+	if isPdf(file) {
+		content, err := s.storageService.Open(file)
+		if err != nil {
+			return nil, "", err
+		}
+		b, err := ioutil.ReadAll(content)
+		_ = content.Close()
+		if err != nil {
+			return nil, "", err
+		}
+
+		out, err := pdf.Watermark(b, lines, s.config.Inspection.Limits.MaxDecompressedSize)
+		if err == pdf.ErrEncrypted || err == pdf.ErrUnsupported || err == pdf.ErrDecompressionLimit {
+			return nil, "", ErrWatermarkUnsupported
+		}
+		return out, "application/pdf", err
+	}
+
+	format := variantSourceFormat(file)
+	if format == "" {
+		return nil, "", ErrWatermarkUnsupported
+	}
+	img, err := decodeImage(s.storageService, file, format)
+	if err == ErrVariantUnsupported {
+		return nil, "", ErrWatermarkUnsupported
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	img = imaging.Watermark(img, lines, watermarkColor)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: watermarkJpegQuality})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err = png.Encode(&buf, img)
+	return buf.Bytes(), "image/png", err
+}
+
+// WatermarkText identifies the user who downloads a file
+func WatermarkText(email, uid string) string {
+	return fmt.Sprintf("Downloaded by %s (%s)", email, uid)
+}
+
+func isPdf(file *database.FileModel) bool {
+	if file.Properties != nil {
+		return file.Properties.Format == "pdf"
+	}
+	// files uploaded before properties were introduced
+	return file.ContentType == "application/pdf"
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Confialink/wallet-files/internal/config"
+	"github.com/Confialink/wallet-files/internal/database"
+)
+
+const StorageAzure = "azure"
+
+// azureVersion is the version of the Blob service REST API
+const azureVersion = "2019-12-12"
+
+var errAzureNotFound = errors.New("blob not found")
+
+// Azure stores objects as block blobs in containers of an Azure Blob storage account, buckets are containers.
+// Requests are authorized with the shared key of the account.
+type Azure struct {
+	name     string
+	account  string
+	key      []byte
+	endpoint string
+	config   config.StorageConfig
+	client   *http.Client
+	repo     *database.Repository
+}
+
+func init() {
+	Register(StorageAzure, openAzure)
+}
+
+// NewAzure creates Azure Blob storage of the account which name and base64 encoded key are the access and secret keys.
+// Endpoint is https://<account>.blob.core.windows.net if empty, emulators address the account in the path,
+// e.g. http://127.0.0.1:10000/devstoreaccount1 for Azurite.
+func NewAzure(name string, cfg config.StorageConfig, repo *database.Repository) (*Azure, error) {
+	key, err := base64.StdEncoding.DecodeString(cfg.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account key: %v", err)
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://" + cfg.AccessKey + ".blob.core.windows.net"
+	}
+
+	return &Azure{
+		name:     name,
+		account:  cfg.AccessKey,
+		key:      key,
+		endpoint: strings.TrimRight(endpoint, "/"),
+		config:   cfg,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		repo:     repo,
+	}, nil
+}
+
+func openAzure(name string, cfg config.StorageConfig, repo *database.Repository) (Storage, error) {
+	return NewAzure(name, cfg, repo)
+}
+
+// Delete deletes file from container and database
+func (s *Azure) Delete(file *database.FileModel) error {
+	if err := s.DeleteObject(FileLocation(file)); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(file)
+}
+
+func (s *Azure) Download(file *database.FileModel) []byte {
+	content, err := s.OpenObject(FileLocation(file))
+	if err != nil {
+		return nil
+	}
+	defer content.Close()
+
+	b, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// PutObject uploads a block blob into the container, the configured container is used by default.
+// The storage class is the access tier of the blob.
+func (s *Azure) PutObject(dir string, name string, body io.Reader, contentType string, options *PutOptions) (*Location, error) {
+	container, tier := s.config.Bucket, ""
+	if options != nil {
+		if options.Bucket != "" {
+			container = options.Bucket
+		}
+		tier = options.StorageClass
+	}
+	if container == "" {
+		return nil, fmt.Errorf("azure storage %s requires a container", s.name)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	location := &Location{Storage: s.name, Bucket: container, Path: prefixed(s.config.Prefix, dir), Filename: name}
+	req, err := http.NewRequest(http.MethodPut, s.url(location), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	if tier != "" {
+		req.Header.Set("x-ms-access-tier", tier)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return location, nil
+}
+
+// OpenObject opens a blob for reading
+func (s *Azure) OpenObject(location *Location) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url(location), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// DeleteObject deletes a blob, a missing blob is not an error
+func (s *Azure) DeleteObject(location *Location) error {
+	req, err := http.NewRequest(http.MethodDelete, s.url(location), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err == errAzureNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// do signs and sends the request, responses with error statuses are returned as errors
+func (s *Azure) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureVersion)
+	req.Header.Set("Authorization", "SharedKey "+s.account+":"+s.sign(req))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errAzureNotFound
+	}
+	return nil, fmt.Errorf("azure request failed with status %d: %s", resp.StatusCode, resp.Header.Get("x-ms-error-code"))
+}
+
+// sign returns the shared key signature of the request
+func (s *Azure) sign(req *http.Request) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(s.stringToSign(req)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// stringToSign returns the canonicalized headers and resource of the request which are signed
+func (s *Azure) stringToSign(req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name+":"+strings.Join(values, ","))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	return strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, x-ms-date is used instead
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}, "\n") + "\n" + strings.Join(msHeaders, "\n") + "\n/" + s.account + req.URL.EscapedPath()
+}
+
+// url returns the url of the blob, every segment of the key is escaped
+func (s *Azure) url(location *Location) string {
+	segments := strings.Split(location.Key(), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return s.endpoint + "/" + url.PathEscape(location.Bucket) + "/" + strings.Join(segments, "/")
+}
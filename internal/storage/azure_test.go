@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Confialink/wallet-files/internal/config"
+)
+
+const azureTestDate = "Mon, 19 Oct 2026 10:00:00 GMT"
+
+func newTestAzure(t *testing.T, endpoint string) *Azure {
+	s, err := NewAzure("azure", config.StorageConfig{
+		AccessKey: "account",
+		SecretKey: base64.StdEncoding.EncodeToString([]byte("secret")),
+		Endpoint:  endpoint,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestAzureStringToSign(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		method   string
+		location *Location
+		body     string
+		headers  map[string]string
+		expected []string
+	}{
+		{
+			name:     "get",
+			method:   http.MethodGet,
+			location: &Location{Bucket: "files", Path: "user", Filename: "a.txt"},
+			expected: []string{"GET", "", "", "", "", "", "", "", "", "", "", "",
+				"x-ms-date:" + azureTestDate, "x-ms-version:2019-12-12", "/account/files/user/a.txt"},
+		},
+		{
+			name:     "put with headers",
+			method:   http.MethodPut,
+			location: &Location{Bucket: "files", Path: "user", Filename: "a.txt"},
+			body:     "hello",
+			headers: map[string]string{
+				"Content-Type":     "text/plain",
+				"X-Ms-Blob-Type":   "BlockBlob",
+				"X-Ms-Access-Tier": "Cool",
+			},
+			expected: []string{"PUT", "", "", "5", "", "text/plain", "", "", "", "", "", "",
+				"x-ms-access-tier:Cool", "x-ms-blob-type:BlockBlob", "x-ms-date:" + azureTestDate,
+				"x-ms-version:2019-12-12", "/account/files/user/a.txt"},
+		},
+		{
+			name:     "range",
+			method:   http.MethodGet,
+			location: &Location{Bucket: "files", Path: "user", Filename: "a.txt"},
+			headers:  map[string]string{"Range": "bytes=0-99"},
+			expected: []string{"GET", "", "", "", "", "", "", "", "", "", "", "bytes=0-99",
+				"x-ms-date:" + azureTestDate, "x-ms-version:2019-12-12", "/account/files/user/a.txt"},
+		},
+		{
+			name:     "escaped key",
+			method:   http.MethodDelete,
+			location: &Location{Bucket: "files", Path: "dir/sub dir", Filename: "a b?#%.txt"},
+			expected: []string{"DELETE", "", "", "", "", "", "", "", "", "", "", "",
+				"x-ms-date:" + azureTestDate, "x-ms-version:2019-12-12",
+				"/account/files/dir/sub%20dir/a%20b%3F%23%25.txt"},
+		},
+		{
+			name:     "emulator",
+			endpoint: "http://127.0.0.1:10000/account/",
+			method:   http.MethodGet,
+			location: &Location{Bucket: "files", Path: "user", Filename: "a.txt"},
+			expected: []string{"GET", "", "", "", "", "", "", "", "", "", "", "",
+				"x-ms-date:" + azureTestDate, "x-ms-version:2019-12-12", "/account/account/files/user/a.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestAzure(t, tt.endpoint)
+			req, err := http.NewRequest(tt.method, s.url(tt.location), strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("x-ms-date", azureTestDate)
+			req.Header.Set("x-ms-version", azureVersion)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			expected := strings.Join(tt.expected, "\n")
+			if actual := s.stringToSign(req); actual != expected {
+				t.Errorf("expected\n%q\ngot\n%q", expected, actual)
+			}
+		})
+	}
+}
+
+func TestAzureSign(t *testing.T) {
+	s := newTestAzure(t, "")
+	req, err := http.NewRequest(http.MethodGet, s.url(&Location{Bucket: "files", Path: "user", Filename: "a.txt"}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.Host != "account.blob.core.windows.net" {
+		t.Errorf("unexpected host %s", req.URL.Host)
+	}
+	req.Header.Set("x-ms-date", azureTestDate)
+	req.Header.Set("x-ms-version", azureVersion)
+
+	// HMAC-SHA256 of the string to sign with key "secret", computed independently
+	expected := "L/xkJoydTiKNTyIRrD9S1k4owDrYqSW215tzZXQoOns="
+	if actual := s.sign(req); actual != expected {
+		t.Errorf("expected %s, got %s (%q)", expected, actual, s.stringToSign(req))
+	}
+}
+
+func TestNewAzureInvalidKey(t *testing.T) {
+	if _, err := NewAzure("azure", config.StorageConfig{AccessKey: "account", SecretKey: "not base64!"}, nil); err == nil {
+		t.Error("expected error")
+	}
+}
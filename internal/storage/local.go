@@ -1,190 +1,146 @@
 package storage
 
 import (
-	"encoding/binary"
 	"errors"
+	"io"
 	"io/ioutil"
-	"mime/multipart"
-	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
-	"time"
 
+	"github.com/Confialink/wallet-files/internal/config"
 	"github.com/Confialink/wallet-files/internal/database"
 )
 
-// Local
+// Local stores objects in a directory, paths of objects include the prefix and are relative to the root
 type Local struct {
-	repo *database.Repository
+	name   string
+	root   string
+	prefix string
+	repo   *database.Repository
 }
 
+// errOutsideRoot means the key of an object points outside of the root of the storage
+var errOutsideRoot = errors.New("object path is outside of the storage root")
+
+// StorageDir is the prefix of the built-in local storage under the working directory
 const StorageDir = "files"
 
+func init() {
+	Register(StorageLocal, openLocal)
+}
+
 func NewLocal(
+	name string,
+	root string,
+	prefix string,
 	repo *database.Repository,
 ) *Local {
-	return &Local{repo}
+	return &Local{name, root, prefix, repo}
 }
 
-// Upload uploads file to bucket and create record in database
-func (s *Local) Upload(
-	file multipart.File,
-	header *multipart.FileHeader,
-	userId string,
-	isAdminOnly bool,
-	isPrivate bool,
-	contentTypeRegexpValidator *regexp.Regexp,
-) (*database.FileModel, error) {
-	defer file.Close()
-
-	size := header.Size
-	b := make([]byte, size)
-	_, _ = file.Read(b)
-	_, _ = file.Seek(0, 0)
-
-	contentType := http.DetectContentType(b)
-	if contentTypeRegexpValidator != nil && !contentTypeRegexpValidator.MatchString(contentType) {
-		return nil, errors.New("content type is not allowed")
-	}
+// openLocal creates local storage, the root is the working directory if empty
+func openLocal(name string, cfg config.StorageConfig, repo *database.Repository) (Storage, error) {
+	root := cfg.Root
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		root = wd
+	}
+	return NewLocal(name, root, cfg.Prefix, repo), nil
+}
 
-	// retrieve extension from filename and remove dot from extension
-	extDir := strings.Replace(filepath.Ext(header.Filename), ".", "", -1)
-	if len(extDir) == 0 {
-		extDir = "others"
-	}
+// Delete deletes file from bucket and database
+func (s *Local) Delete(file *database.FileModel) error {
+	err := s.deleteFromLocalStorage(FileLocation(file).Key())
 
-	wd, err := os.Getwd()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	path := StorageDir + "/" + extDir + "/" + time.Now().Format("2006-01-02")
-	dirPath := wd + "/" + path
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		return nil, err
-	}
+	err = s.repo.Delete(file)
 
-	filename := strconv.FormatInt(time.Now().Unix(), 10) + "-" + header.Filename
+	return err
+}
 
-	err = ioutil.WriteFile(dirPath+"/"+filename, b, 0644)
+func (s *Local) Download(file *database.FileModel) []byte {
+	filePath, err := s.path(FileLocation(file).Key())
 	if err != nil {
-		return nil, err
-	}
-
-	fileModel := database.FileModel{
-		Filename:    filename,
-		Path:        path,
-		Size:        size,
-		ContentType: contentType,
-		UserId:      userId,
-		IsAdminOnly: isAdminOnly,
-		IsPrivate:   isPrivate,
-		Storage:     StorageLocal,
+		return nil
 	}
-
-	createdFile, err := s.repo.Create(&fileModel)
-
+	b, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		_ = s.deleteFromLocalStorage(path, filename)
-		return nil, err
+		return nil
 	}
 
-	return createdFile, nil
+	return b
 }
 
-// Upload uploads file to bucket and create record in database
-func (s *Local) UploadBytes(
-	b []byte,
-	fileName string,
-	userId string,
-	isAdminOnly bool,
-	isPrivate bool,
-	category *string,
-) (*database.FileModel, error) {
-	size := binary.Size(b)
-	contentType := http.DetectContentType(b)
-
-	// retrieve extension from filename and remove dot from extension
-	extDir := strings.Replace(filepath.Ext(fileName), ".", "", -1)
-	if len(extDir) == 0 {
-		extDir = "others"
-	}
-
-	wd, err := os.Getwd()
+// PutObject writes an object to the local storage, options do not apply to local files
+func (s *Local) PutObject(dir string, name string, body io.Reader, _ string, _ *PutOptions) (*Location, error) {
+	path := prefixed(s.prefix, dir)
+	filePath, err := s.path(path + "/" + name)
 	if err != nil {
 		return nil, err
 	}
-
-	path := StorageDir + "/" + extDir + "/" + time.Now().Format("2006-01-02")
-	dirPath := wd + "/" + path
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
 		return nil, err
 	}
-	filename := strconv.FormatInt(time.Now().Unix(), 10) + "-" + fileName
 
-	err = ioutil.WriteFile(dirPath+"/"+filename, b, 0644)
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return nil, err
 	}
 
-	fileModel := database.FileModel{
-		Filename:    filename,
-		Path:        path,
-		Size:        int64(size),
-		ContentType: contentType,
-		UserId:      userId,
-		IsAdminOnly: isAdminOnly,
-		IsPrivate:   isPrivate,
-		Storage:     StorageLocal,
-		Category:    category,
+	if _, err = io.Copy(f, body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(filePath)
+		return nil, err
 	}
 
-	createdFile, err := s.repo.Create(&fileModel)
-
-	if err != nil {
-		_ = s.deleteFromLocalStorage(path, filename)
+	if err = f.Close(); err != nil {
 		return nil, err
 	}
 
-	return createdFile, nil
+	return &Location{Storage: s.name, Path: path, Filename: name}, nil
 }
 
-// Delete deletes file from bucket and database
-func (s *Local) Delete(file *database.FileModel) error {
-	err := s.deleteFromLocalStorage(file.Path, file.Filename)
-
+// OpenObject opens an object from the local storage
+func (s *Local) OpenObject(location *Location) (io.ReadCloser, error) {
+	filePath, err := s.path(location.Key())
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return os.Open(filePath)
+}
 
-	err = s.repo.Delete(file)
-
-	return err
+// DeleteObject deletes an object from the local storage
+func (s *Local) DeleteObject(location *Location) error {
+	return s.deleteFromLocalStorage(location.Key())
 }
 
-func (s *Local) Download(file *database.FileModel) []byte {
-	wd, err := os.Getwd()
+// deleteFromLocalStorage deletes file from local storage.
+// A missing file is not an error so that deletion may be repeated.
+func (s *Local) deleteFromLocalStorage(key string) error {
+	filePath, err := s.path(key)
 	if err != nil {
-		return nil
+		return err
 	}
-
-	b, err := ioutil.ReadFile(wd + "/" + file.Path + "/" + file.Filename)
-	if err != nil {
-		return nil
+	err = os.Remove(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
 	}
-
-	return b
+	return nil
 }
 
-// deleteFromLocalStorage deletes file from local storage
-func (s *Local) deleteFromLocalStorage(path string, filename string) error {
-	wd, err := os.Getwd()
-	if err != nil {
-		return err
+// path returns the file path of the object key, keys which escape the root with ".." are rejected
+func (s *Local) path(key string) (string, error) {
+	filePath := filepath.Join(s.root, filepath.FromSlash(key))
+	rel, err := filepath.Rel(s.root, filePath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errOutsideRoot
 	}
-
-	return os.Remove(wd + "/" + path + "/" + filename)
+	return filePath, nil
 }
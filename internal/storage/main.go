@@ -1,8 +1,7 @@
 package storage
 
 import (
-	"mime/multipart"
-	"regexp"
+	"io"
 
 	"github.com/Confialink/wallet-files/internal/database"
 )
@@ -10,24 +9,56 @@ import (
 const StorageS3 = "s3"
 const StorageLocal = "local"
 
+// Regional returns the name of the s3 storage which accesses buckets in the region with the options of the storage
+func Regional(name string, region string) string {
+	return name + "-" + region
+}
+
 // Storage
 type Storage interface {
-	Upload(
-		file multipart.File,
-		header *multipart.FileHeader,
-		userId string,
-		isAdminOnly bool,
-		isPrivate bool,
-		contentTypeRegexpValidator *regexp.Regexp,
-	) (*database.FileModel, error)
-	UploadBytes(
-		b []byte,
-		fileName string,
-		userId string,
-		isAdminOnly bool,
-		isPrivate bool,
-		category *string,
-	) (*database.FileModel, error)
+	// Delete deletes the object of a file stored before blobs were introduced and the file itself
 	Delete(file *database.FileModel) error
 	Download(file *database.FileModel) []byte
+	// PutObject stores an object which is not registered as a file, options may be nil
+	PutObject(dir string, name string, body io.Reader, contentType string, options *PutOptions) (*Location, error)
+	// OpenObject opens an object for reading, the caller must close it
+	OpenObject(location *Location) (io.ReadCloser, error)
+	// DeleteObject deletes an object, a missing object is not an error
+	DeleteObject(location *Location) error
+}
+
+// PutOptions define where and how an object is stored, empty fields keep defaults of the storage
+type PutOptions struct {
+	Bucket       string
+	ACL          string
+	StorageClass string
+}
+
+// Location points to an object in a storage
+type Location struct {
+	Storage  string
+	Bucket   string
+	Path     string
+	Filename string
+}
+
+// Key returns the object key within the bucket
+func (l *Location) Key() string {
+	return l.Path + "/" + l.Filename
+}
+
+// FileLocation returns location of the file object
+func FileLocation(file *database.FileModel) *Location {
+	filename := file.Filename
+	if file.BlobId != nil {
+		// blobs are named by the hash of their content
+		filename = *file.Sha256
+	}
+
+	return &Location{
+		Storage:  file.Storage,
+		Bucket:   file.Bucket,
+		Path:     file.Path,
+		Filename: filename,
+	}
 }
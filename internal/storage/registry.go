@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/Confialink/wallet-files/internal/config"
+	"github.com/Confialink/wallet-files/internal/database"
+)
+
+// Driver creates a storage of its type from the configuration
+type Driver func(name string, cfg config.StorageConfig, repo *database.Repository) (Storage, error)
+
+var drivers = make(map[string]Driver)
+
+// Register makes the driver available for storages of the type, drivers register themselves on init
+func Register(storageType string, driver Driver) {
+	if _, ok := drivers[storageType]; ok {
+		panic("storage driver " + storageType + " is registered twice")
+	}
+	drivers[storageType] = driver
+}
+
+// Registered checks if there is a driver of the type
+func Registered(storageType string) bool {
+	_, ok := drivers[storageType]
+	return ok
+}
+
+// Open creates the named storage by the driver of its type
+func Open(name string, cfg config.StorageConfig, repo *database.Repository) (Storage, error) {
+	driver, ok := drivers[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q of storage %q", cfg.Type, name)
+	}
+	return driver(name, cfg, repo)
+}
+
+// prefixed places the directory under the prefix of a storage
+func prefixed(prefix string, dir string) string {
+	if prefix == "" {
+		return dir
+	}
+	return prefix + "/" + dir
+}
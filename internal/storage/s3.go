@@ -1,163 +1,69 @@
 package storage
 
 import (
-	"bytes"
-	"encoding/binary"
-	"errors"
-	"mime/multipart"
-	"net/http"
-	"path/filepath"
-	"regexp"
-	"strconv"
-	"strings"
-	"time"
+	"io"
 
 	"github.com/Confialink/wallet-files/internal/config"
 	"github.com/Confialink/wallet-files/internal/database"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
-// S3
+// S3 stores objects in buckets of AWS S3 or an S3-compatible service, keys of objects include the prefix
 type S3 struct {
+	name       string
 	uploader   *s3manager.Uploader
 	downloader *s3manager.Downloader
 	s3         *s3.S3
-	config     config.AwsConfig
+	config     config.StorageConfig
 	repo       *database.Repository
 }
 
+func init() {
+	Register(StorageS3, openS3)
+}
+
 func NewS3(
+	name string,
 	uploader *s3manager.Uploader,
 	downloader *s3manager.Downloader,
 	s3 *s3.S3,
-	config config.AwsConfig,
+	config config.StorageConfig,
 	repo *database.Repository,
 ) *S3 {
-	return &S3{uploader, downloader, s3, config, repo}
+	return &S3{name, uploader, downloader, s3, config, repo}
 }
 
-// Upload uploads file to bucket and create record in database
-func (s *S3) Upload(
-	file multipart.File,
-	header *multipart.FileHeader,
-	userId string,
-	isAdminOnly bool,
-	isPrivate bool,
-	contentTypeRegexpValidator *regexp.Regexp,
-) (*database.FileModel, error) {
-	defer file.Close()
-
-	size := header.Size
-	b := make([]byte, size)
-	file.Read(b)
-	file.Seek(0, 0)
-
-	contentType := http.DetectContentType(b)
-	if contentTypeRegexpValidator != nil && !contentTypeRegexpValidator.MatchString(contentType) {
-		return nil, errors.New("content type is not allowed")
+// openS3 creates s3 storage with its own session, AWS credentials are read from environment
+// unless static credentials are configured
+func openS3(name string, cfg config.StorageConfig, repo *database.Repository) (Storage, error) {
+	sessionConfig := &aws.Config{
+		Region:           aws.String(cfg.Region),
+		Credentials:      credentials.NewEnvCredentials(),
+		S3ForcePathStyle: aws.Bool(cfg.PathStyle),
 	}
-
-	// retrieve extension from filename and remove dot from extension
-	extDir := strings.Replace(filepath.Ext(header.Filename), ".", "", -1)
-	if len(extDir) == 0 {
-		extDir = "others"
+	if cfg.Endpoint != "" {
+		sessionConfig.Endpoint = aws.String(cfg.Endpoint)
 	}
-
-	path := extDir + "/" + time.Now().Format("2006-01-02")
-	filename := strconv.FormatInt(time.Now().Unix(), 10) + "-" + header.Filename
-
-	_, err := s.uploader.Upload(&s3manager.UploadInput{
-		Bucket:               aws.String(s.config.S3Bucket),
-		Key:                  aws.String(path + "/" + filename),
-		Body:                 file,
-		ContentType:          aws.String(contentType),
-		ACL:                  aws.String("private"),
-		ServerSideEncryption: aws.String("AES256"),
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	fileModel := database.FileModel{
-		Filename:    filename,
-		Path:        path,
-		Size:        size,
-		ContentType: contentType,
-		Bucket:      s.config.S3Bucket,
-		UserId:      userId,
-		IsAdminOnly: isAdminOnly,
-		IsPrivate:   isPrivate,
-		Storage:     StorageS3,
+	if cfg.AccessKey != "" {
+		sessionConfig.Credentials = credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, "")
 	}
 
-	createdFile, err := s.repo.Create(&fileModel)
-
+	sess, err := session.NewSession(sessionConfig)
 	if err != nil {
-		s.deleteFromS3(s.config.S3Bucket, path+"/"+filename)
 		return nil, err
 	}
-
-	return createdFile, nil
-}
-
-// UploadBytes uploads bytes to bucket and create record in database
-func (s *S3) UploadBytes(
-	b []byte,
-	fileName string,
-	userId string,
-	isAdminOnly bool,
-	isPrivate bool,
-	category *string,
-) (*database.FileModel, error) {
-	size := binary.Size(b)
-	contentType := http.DetectContentType(b)
-
-	// retrieve extension from filename and remove dot from extension
-	extDir := strings.Replace(filepath.Ext(fileName), ".", "", -1)
-	if len(extDir) == 0 {
-		extDir = "others"
-	}
-
-	path := extDir + "/" + time.Now().Format("2006-01-02")
-	filename := strconv.FormatInt(time.Now().Unix(), 10) + "-" + fileName
-
-	_, err := s.uploader.Upload(&s3manager.UploadInput{
-		Bucket:               aws.String(s.config.S3Bucket),
-		Key:                  aws.String(path + "/" + filename),
-		Body:                 bytes.NewReader(b),
-		ContentType:          aws.String(contentType),
-		ACL:                  aws.String("private"),
-		ServerSideEncryption: aws.String("AES256"),
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	fileModel := database.FileModel{
-		Filename:    filename,
-		Path:        path,
-		Size:        int64(size),
-		ContentType: contentType,
-		Bucket:      s.config.S3Bucket,
-		UserId:      userId,
-		IsAdminOnly: isAdminOnly,
-		IsPrivate:   isPrivate,
-		Storage:     StorageS3,
-		Category:    category,
-	}
-
-	createdFile, err := s.repo.Create(&fileModel)
-
-	if err != nil {
-		s.deleteFromS3(s.config.S3Bucket, path+"/"+filename)
-		return nil, err
-	}
-
-	return createdFile, nil
+	return NewS3(
+		name,
+		s3manager.NewUploader(sess),
+		s3manager.NewDownloader(sess),
+		s3.New(sess),
+		cfg,
+		repo,
+	), nil
 }
 
 // Delete deletes file from bucket and database
@@ -177,11 +83,62 @@ func (s *S3) Download(file *database.FileModel) []byte {
 	b := &aws.WriteAtBuffer{}
 	s.downloader.Download(b, &s3.GetObjectInput{
 		Bucket: aws.String(file.Bucket),
-		Key:    aws.String(file.Path + "/" + file.Filename),
+		Key:    aws.String(FileLocation(file).Key()),
 	})
 	return b.Bytes()
 }
 
+// PutObject uploads an object to the bucket, the configured bucket and private ACL are used by default
+func (s *S3) PutObject(dir string, name string, body io.Reader, contentType string, options *PutOptions) (*Location, error) {
+	bucket, acl := s.config.Bucket, "private"
+	dir = prefixed(s.config.Prefix, dir)
+	input := &s3manager.UploadInput{
+		Key:         aws.String(dir + "/" + name),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	}
+	if s.config.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(s.config.ServerSideEncryption)
+	}
+	if options != nil {
+		if options.Bucket != "" {
+			bucket = options.Bucket
+		}
+		if options.ACL != "" {
+			acl = options.ACL
+		}
+		if options.StorageClass != "" {
+			input.StorageClass = aws.String(options.StorageClass)
+		}
+	}
+	input.Bucket = aws.String(bucket)
+	input.ACL = aws.String(acl)
+
+	if _, err := s.uploader.Upload(input); err != nil {
+		return nil, err
+	}
+
+	return &Location{Storage: s.name, Bucket: bucket, Path: dir, Filename: name}, nil
+}
+
+// OpenObject opens an object from the bucket
+func (s *S3) OpenObject(location *Location) (io.ReadCloser, error) {
+	out, err := s.s3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(location.Bucket),
+		Key:    aws.String(location.Key()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Body, nil
+}
+
+// DeleteObject deletes an object from the bucket
+func (s *S3) DeleteObject(location *Location) error {
+	return s.deleteFromS3(location.Bucket, location.Key())
+}
+
 // deleteFromS3 deletes file from bucket
 func (s *S3) deleteFromS3(bucket string, key string) error {
 	input := &s3.DeleteObjectInput{
@@ -0,0 +1,185 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedType means the content is not recognized or is not allowed
+	ErrUnsupportedType = errors.New("unsupported file type")
+	// ErrTypeMismatch means the content does not match the file extension
+	ErrTypeMismatch = errors.New("file type does not match extension")
+)
+
+// sniffLen is the size of the head which is checked for markup of non-container types
+const sniffLen = 1024
+
+// polyglotTailLen is the size of the tail which is checked for appended archives
+const polyglotTailLen = 64 << 10
+
+// Type is a supported file type
+type Type struct {
+	Name       string
+	Extensions []string
+	match      func(b []byte) bool
+	// container is true for types which may legally contain archives
+	container bool
+}
+
+var types = []*Type{
+	{Name: "jpeg", Extensions: []string{"jpg", "jpeg", "jfif"}, match: prefix("\xFF\xD8\xFF")},
+	{Name: "png", Extensions: []string{"png"}, match: prefix("\x89PNG\r\n\x1A\n")},
+	{Name: "gif", Extensions: []string{"gif"}, match: func(b []byte) bool {
+		return bytes.HasPrefix(b, []byte("GIF87a")) || bytes.HasPrefix(b, []byte("GIF89a"))
+	}},
+	{Name: "webp", Extensions: []string{"webp"}, match: func(b []byte) bool {
+		return len(b) >= 12 && bytes.Equal(b[:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WEBP"))
+	}},
+	{Name: "tiff", Extensions: []string{"tif", "tiff"}, match: func(b []byte) bool {
+		return bytes.HasPrefix(b, []byte("II*\x00")) || bytes.HasPrefix(b, []byte("MM\x00*"))
+	}},
+	{Name: "heic", Extensions: []string{"heic", "heif"}, match: func(b []byte) bool {
+		if len(b) < 12 || !bytes.Equal(b[4:8], []byte("ftyp")) {
+			return false
+		}
+		brand := string(b[8:12])
+		return brand == "heic" || brand == "heix" || brand == "mif1" || brand == "msf1"
+	}},
+	{Name: "pdf", Extensions: []string{"pdf"}, match: prefix("%PDF-")},
+	{Name: "zip", Extensions: []string{"zip", "docx", "xlsx", "pptx", "odt", "ods"}, match: prefix("PK\x03\x04"), container: true},
+	{Name: "ole", Extensions: []string{"doc", "xls", "ppt"}, match: prefix("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"), container: true},
+	{Name: "text", Extensions: []string{"txt", "csv"}, match: isText},
+}
+
+// markup is a list of markers which make browsers treat content as html
+var markup = [][]byte{
+	[]byte("<!doctype html"),
+	[]byte("<html"),
+	[]byte("<head"),
+	[]byte("<body"),
+	[]byte("<script"),
+	[]byte("<iframe"),
+	[]byte("<svg"),
+	[]byte("<?xml"),
+}
+
+// Detect returns the type of the content or nil if it is not supported
+func Detect(b []byte) *Type {
+	for _, t := range types {
+		if t.match(b) {
+			return t
+		}
+	}
+	return nil
+}
+
+// Validate checks that the content is of a supported type, matches the file extension and is
+// not a polyglot. If allowed extensions are given the type must have one of them.
+// Containers are not checked for markup, their entries are xml documents and may be stored uncompressed.
+func Validate(b []byte, filename string, allowed []string) (*Type, error) {
+	t := Detect(b)
+	if t == nil {
+		return nil, ErrUnsupportedType
+	}
+	if !t.container && containsMarkup(b) {
+		return nil, ErrUnsupportedType
+	}
+
+	ext := Extension(filename)
+	if !contains(t.Extensions, ext) {
+		if Known(ext) {
+			return nil, ErrTypeMismatch
+		}
+		return nil, ErrUnsupportedType
+	}
+
+	if len(allowed) > 0 && !contains(allowed, ext) {
+		return nil, ErrUnsupportedType
+	}
+
+	if !t.container && containsArchive(b) {
+		return nil, ErrUnsupportedType
+	}
+
+	return t, nil
+}
+
+// Extension returns lower case extension of the file name without a dot
+func Extension(filename string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+}
+
+// Known checks if the extension belongs to any supported type
+func Known(ext string) bool {
+	for _, t := range types {
+		if contains(t.Extensions, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsMarkup checks the head of the content for html markers
+func containsMarkup(b []byte) bool {
+	head := b
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	head = bytes.ToLower(head)
+	for _, marker := range markup {
+		if bytes.Contains(head, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsArchive checks for an embedded zip archive or pdf document,
+// which makes the content valid for several types at once
+func containsArchive(b []byte) bool {
+	tail := b
+	if len(tail) > polyglotTailLen {
+		tail = tail[len(tail)-polyglotTailLen:]
+	}
+	if bytes.Contains(tail, []byte("PK\x05\x06")) {
+		return true
+	}
+
+	head := b
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	return bytes.Index(head, []byte("%PDF-")) > 0
+}
+
+// isText accepts UTF-8 and 8-bit encoded text, e.g. csv exported in a Windows code page.
+// Binary content is recognized by NUL and other control bytes, only whitespace controls are allowed.
+func isText(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if (c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f') || c == 0x7F {
+			return false
+		}
+	}
+	return true
+}
+
+func prefix(p string) func(b []byte) bool {
+	return func(b []byte) bool {
+		return bytes.HasPrefix(b, []byte(p))
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
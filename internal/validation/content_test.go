@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	docx := append([]byte("PK\x03\x04\x14\x00\x00\x00\x00\x00[Content_Types].xml"), []byte(`<?xml version="1.0"?><Types/>`)...)
+
+	tests := []struct {
+		name     string
+		b        []byte
+		filename string
+		expected string
+		err      error
+	}{
+		{"utf-8 text", []byte("name;city\nJürgen;München\n"), "a.csv", "text", nil},
+		{"8-bit text", []byte("name;city\r\nJ\xFCrgen;M\xFCnchen\r\n"), "a.csv", "text", nil},
+		{"tabs and form feed", []byte("a\tb\fc\n"), "a.txt", "text", nil},
+		{"stored xml entry of a container", docx, "a.docx", "zip", nil},
+		{"pdf", []byte("%PDF-1.7\n%%EOF\n"), "a.pdf", "pdf", nil},
+		{"empty", []byte{}, "a.txt", "", ErrUnsupportedType},
+		{"nul byte", []byte("abc\x00def"), "a.txt", "", ErrUnsupportedType},
+		{"escape byte", []byte("abc\x1B[31m"), "a.txt", "", ErrUnsupportedType},
+		{"delete byte", []byte("abc\x7F"), "a.txt", "", ErrUnsupportedType},
+		{"html", []byte("<!DOCTYPE html><p>hi</p>"), "a.txt", "", ErrUnsupportedType},
+		{"xml in text", []byte(`<?xml version="1.0"?><svg/>`), "a.txt", "", ErrUnsupportedType},
+		{"script after image signature", []byte("\x89PNG\r\n\x1A\n<script>"), "a.png", "", ErrUnsupportedType},
+		{"pdf in text", []byte("hello %PDF-1.4"), "a.txt", "", ErrUnsupportedType},
+		{"zip appended to image", []byte("\xFF\xD8\xFF\xE0PK\x05\x06"), "a.jpg", "", ErrUnsupportedType},
+		{"extension mismatch", []byte("%PDF-1.7"), "a.png", "", ErrTypeMismatch},
+		{"unknown extension", []byte("%PDF-1.7"), "a.exe", "", ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, err := Validate(tt.b, tt.filename, nil)
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && typ.Name != tt.expected {
+				t.Errorf("expected type %q, got %q", tt.expected, typ.Name)
+			}
+		})
+	}
+}
+
+func TestValidateAllowed(t *testing.T) {
+	if _, err := Validate([]byte("%PDF-1.7"), "a.pdf", []string{"jpg", "png"}); err != ErrUnsupportedType {
+		t.Errorf("expected ErrUnsupportedType, got %v", err)
+	}
+	if _, err := Validate([]byte("%PDF-1.7"), "a.PDF", []string{"pdf"}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package validation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFilenameLen is the limit of sanitized file names in bytes
+const maxFilenameLen = 255
+
+// defaultFilename replaces names which are empty after sanitizing
+const defaultFilename = "file"
+
+// SanitizeFilename returns the base name of an uploaded file which is safe to store and to send back in headers.
+// Directories of both separators, invalid UTF-8, control, format (e.g. bidi overrides) and line separating
+// characters are dropped, leading and trailing spaces and dots are trimmed.
+func SanitizeFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	var b strings.Builder
+	for _, r := range name {
+		if r == utf8.RuneError || unicode.IsControl(r) || unicode.In(r, unicode.Cf, unicode.Zl, unicode.Zp) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	name = strings.Trim(b.String(), " .")
+
+	if len(name) > maxFilenameLen {
+		name = truncateFilename(name)
+	}
+	if name == "" {
+		return defaultFilename
+	}
+	return name
+}
+
+// truncateFilename cuts the name to the limit on a rune boundary, the extension is kept if it is short
+func truncateFilename(name string) string {
+	ext := ""
+	if i := strings.LastIndexByte(name, '.'); i > 0 && len(name)-i <= 16 {
+		name, ext = name[:i], name[i:]
+	}
+
+	limit := maxFilenameLen - len(ext)
+	for limit > 0 && !utf8.RuneStart(name[limit]) {
+		limit--
+	}
+	return strings.TrimRight(name[:limit], " .") + ext
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		expected string
+	}{
+		{"plain", "report.pdf", "report.pdf"},
+		{"unicode", "отчёт 2020.pdf", "отчёт 2020.pdf"},
+		{"unix path", "../../etc/passwd", "passwd"},
+		{"windows path", `C:\Users\me\photo.jpg`, "photo.jpg"},
+		{"trailing separator", "dir/", defaultFilename},
+		{"empty", "", defaultFilename},
+		{"dots only", "..", defaultFilename},
+		{"trimmed", " . photo.jpg. ", "photo.jpg"},
+		{"control characters", "a\x00b\r\nc\t.txt", "abc.txt"},
+		{"header injection", "a.txt\r\nSet-Cookie: x=y", "a.txtSet-Cookie: x=y"},
+		{"bidi override", "invoice\u202egpj.exe", "invoicegpj.exe"},
+		{"zero width", "a\u200bb.png", "ab.png"},
+		{"line separator", "a\u2028b.png", "ab.png"},
+		{"invalid utf-8", "a\xff\xfeb.png", "ab.png"},
+		{"long name", strings.Repeat("a", 300) + ".pdf", strings.Repeat("a", maxFilenameLen-4) + ".pdf"},
+		{"long extension", "a." + strings.Repeat("b", 300), "a." + strings.Repeat("b", maxFilenameLen-2)},
+		{"long multibyte", strings.Repeat("я", 200) + ".pdf", strings.Repeat("я", (maxFilenameLen-4)/2) + ".pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := SanitizeFilename(tt.filename)
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+			if len(actual) > maxFilenameLen || !utf8.ValidString(actual) {
+				t.Errorf("invalid result %q", actual)
+			}
+		})
+	}
+}
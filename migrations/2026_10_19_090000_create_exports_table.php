@@ -0,0 +1,43 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class CreateExportsTable extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::dropIfExists('exports');
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::create('exports', function (Blueprint $table) {
+            $table->increments('id');
+            $table->string('user_id', 36)->index();
+            $table->string('requested_by', 36)->nullable();
+            $table->string('status', 16)->index();
+            $table->string('storage')->nullable();
+            $table->string('bucket')->nullable();
+            $table->string('path')->nullable();
+            $table->string('filename')->nullable();
+            $table->bigInteger('size')->default(0);
+            $table->integer('files_count')->default(0);
+            $table->text('error')->nullable();
+            $table->dateTime('expires_at')->nullable();
+            $table->dateTime('created_at')->nullable();
+            $table->dateTime('updated_at')->nullable();
+        });
+    }
+}
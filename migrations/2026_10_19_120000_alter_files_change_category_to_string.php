@@ -0,0 +1,32 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Support\Facades\DB;
+
+class AlterFilesChangeCategoryToString extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * Categories other than the original enum value can not be kept, they are cleared
+     *
+     * @return void
+     */
+    public function down()
+    {
+        DB::table('files')->whereNotNull('category')->where('category', '!=', 'gdpr')->update(['category' => null]);
+        DB::statement("ALTER TABLE `files` MODIFY `category` ENUM('gdpr') NULL DEFAULT NULL");
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * Categories are configured per deployment (allowed types, retention), so they can not be an enum
+     *
+     * @return void
+     */
+    public function up()
+    {
+        DB::statement('ALTER TABLE `files` MODIFY `category` VARCHAR(64) NULL DEFAULT NULL');
+    }
+}
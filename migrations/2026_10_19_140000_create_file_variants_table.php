@@ -0,0 +1,44 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class CreateFileVariantsTable extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::dropIfExists('file_variants');
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::create('file_variants', function (Blueprint $table) {
+            $table->increments('id');
+            $table->unsignedInteger('file_id');
+            $table->string('name', 32);
+            $table->string('storage')->nullable();
+            $table->string('bucket')->nullable();
+            $table->string('path')->nullable();
+            $table->string('filename')->nullable();
+            $table->string('content_type')->nullable();
+            $table->bigInteger('size')->default(0);
+            $table->integer('width')->default(0);
+            $table->integer('height')->default(0);
+            $table->dateTime('created_at')->nullable();
+            $table->dateTime('updated_at')->nullable();
+
+            $table->unique(['file_id', 'name']);
+        });
+    }
+}
@@ -0,0 +1,48 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class CreateBlobsTable extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('files', function (Blueprint $table) {
+            $table->dropColumn('blob_id');
+        });
+        Schema::dropIfExists('blobs');
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::create('blobs', function (Blueprint $table) {
+            $table->increments('id');
+            $table->char('sha256', 64);
+            $table->string('storage');
+            $table->string('bucket')->nullable();
+            $table->string('path')->nullable();
+            $table->string('filename')->nullable();
+            $table->bigInteger('size')->default(0);
+            $table->unsignedInteger('ref_count')->default(0);
+            $table->dateTime('created_at')->nullable();
+            $table->dateTime('updated_at')->nullable();
+
+            $table->unique(['sha256', 'storage']);
+        });
+
+        Schema::table('files', function (Blueprint $table) {
+            $table->unsignedInteger('blob_id')->nullable()->index();
+        });
+    }
+}
@@ -0,0 +1,46 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class CreateUserKeysTable extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::dropIfExists('shred_events');
+        Schema::dropIfExists('user_keys');
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::create('user_keys', function (Blueprint $table) {
+            $table->increments('id');
+            $table->string('user_id')->unique();
+            $table->binary('wrapped_key')->nullable();
+            $table->string('key_id')->nullable()->index();
+            $table->dateTime('shredded_at')->nullable();
+            $table->dateTime('created_at')->nullable();
+            $table->dateTime('updated_at')->nullable();
+        });
+
+        Schema::create('shred_events', function (Blueprint $table) {
+            $table->increments('id');
+            $table->string('user_id')->index();
+            $table->string('requested_by')->nullable();
+            $table->unsignedInteger('files_count')->default(0);
+            $table->unsignedInteger('failed_count')->default(0);
+            $table->dateTime('created_at')->nullable();
+        });
+    }
+}
@@ -0,0 +1,45 @@
+<?php
+
+use Illuminate\Support\Facades\DB;
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterFilesAddTarget extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('blobs', function (Blueprint $table) {
+            $table->dropUnique(['sha256', 'storage', 'bucket']);
+            $table->unique(['sha256', 'storage']);
+        });
+
+        Schema::table('files', function (Blueprint $table) {
+            $table->dropColumn('target');
+        });
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('files', function (Blueprint $table) {
+            $table->string('target')->default('');
+        });
+
+        // content is deduplicated per bucket, so routing never shares objects between buckets
+        DB::table('blobs')->whereNull('bucket')->update(['bucket' => '']);
+        Schema::table('blobs', function (Blueprint $table) {
+            $table->dropUnique(['sha256', 'storage']);
+            $table->unique(['sha256', 'storage', 'bucket']);
+        });
+    }
+}
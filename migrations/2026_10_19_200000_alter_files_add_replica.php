@@ -0,0 +1,41 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterFilesAddReplica extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('files', function (Blueprint $table) {
+            $table->dropIndex(['replica_status', 'replica_retry_at']);
+            $table->dropColumn(['replica_status', 'replica_attempts', 'replica_retry_at', 'replica_storage', 'replica_bucket', 'replica_path']);
+        });
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * Stored objects are copied into the replica target in background, pending files are retried at replica_retry_at
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('files', function (Blueprint $table) {
+            $table->string('replica_status', 16)->default('');
+            $table->unsignedInteger('replica_attempts')->default(0);
+            $table->timestamp('replica_retry_at')->nullable();
+            $table->string('replica_storage')->nullable();
+            $table->string('replica_bucket')->nullable();
+            $table->string('replica_path')->nullable();
+            $table->index(['replica_status', 'replica_retry_at']);
+        });
+    }
+}
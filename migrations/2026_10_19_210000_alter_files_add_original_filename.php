@@ -0,0 +1,39 @@
+<?php
+
+use Illuminate\Support\Facades\DB;
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterFilesAddOriginalFilename extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('files', function (Blueprint $table) {
+            $table->dropColumn('original_filename');
+        });
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * The uploaded name is kept apart from object keys, existing names are taken from filename without
+     * the upload time prefix. Filename and path are left as is so that objects of old files stay readable.
+     * Copied names are not sanitized here, the service sanitizes original names when files are read
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('files', function (Blueprint $table) {
+            $table->string('original_filename')->default('');
+        });
+
+        DB::update("UPDATE files SET original_filename = IF(filename REGEXP '^[0-9]+-', SUBSTRING(filename, LOCATE('-', filename) + 1), filename)");
+    }
+}
@@ -0,0 +1,43 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterDerivedObjectsAddWrappedKey extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('file_variants', function (Blueprint $table) {
+            $table->dropColumn(['wrapped_key', 'key_id']);
+        });
+        Schema::table('exports', function (Blueprint $table) {
+            $table->dropColumn(['wrapped_key', 'key_id']);
+        });
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * Variants and export archives are encrypted with data keys wrapped for the file owner,
+     * objects stored before stay unencrypted
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('file_variants', function (Blueprint $table) {
+            $table->binary('wrapped_key')->nullable();
+            $table->string('key_id')->nullable();
+        });
+        Schema::table('exports', function (Blueprint $table) {
+            $table->binary('wrapped_key')->nullable();
+            $table->string('key_id')->nullable();
+        });
+    }
+}
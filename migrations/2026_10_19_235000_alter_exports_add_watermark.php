@@ -0,0 +1,34 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterExportsAddWatermark extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('exports', function (Blueprint $table) {
+            $table->dropColumn('watermark');
+        });
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * Files of watermarked categories are stamped with the watermark when exports requested by admins are built
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('exports', function (Blueprint $table) {
+            $table->string('watermark')->nullable();
+        });
+    }
+}
@@ -0,0 +1,38 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterBlobsAddUserId extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('blobs', function (Blueprint $table) {
+            $table->dropUnique(['sha256', 'storage', 'bucket', 'user_id']);
+            $table->dropColumn('user_id');
+            $table->unique(['sha256', 'storage', 'bucket']);
+        });
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * Encrypted blobs are deduplicated per user, existing blobs stay shared by all users
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('blobs', function (Blueprint $table) {
+            $table->string('user_id', 36)->default('');
+            $table->dropUnique(['sha256', 'storage', 'bucket']);
+            $table->unique(['sha256', 'storage', 'bucket', 'user_id']);
+        });
+    }
+}
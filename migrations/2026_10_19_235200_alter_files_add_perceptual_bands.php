@@ -0,0 +1,46 @@
+<?php
+
+use Illuminate\Support\Facades\DB;
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterFilesAddPerceptualBands extends Migration
+{
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('files', function (Blueprint $table) {
+            $table->dropColumn(['perceptual_band0', 'perceptual_band1', 'perceptual_band2', 'perceptual_band3']);
+        });
+    }
+
+    /**
+     * Run the migrations.
+     *
+     * Bands are 16 bit parts of the perceptual hash, similar images are looked up by indexed bands
+     * instead of comparing the hash with every file
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('files', function (Blueprint $table) {
+            $table->unsignedSmallInteger('perceptual_band0')->nullable()->index();
+            $table->unsignedSmallInteger('perceptual_band1')->nullable()->index();
+            $table->unsignedSmallInteger('perceptual_band2')->nullable()->index();
+            $table->unsignedSmallInteger('perceptual_band3')->nullable()->index();
+        });
+
+        DB::table('files')->whereNotNull('perceptual_hash')->update([
+            'perceptual_band0' => DB::raw('(CAST(perceptual_hash AS UNSIGNED) >> 48) & 65535'),
+            'perceptual_band1' => DB::raw('(CAST(perceptual_hash AS UNSIGNED) >> 32) & 65535'),
+            'perceptual_band2' => DB::raw('(CAST(perceptual_hash AS UNSIGNED) >> 16) & 65535'),
+            'perceptual_band3' => DB::raw('CAST(perceptual_hash AS UNSIGNED) & 65535'),
+        ]);
+    }
+}
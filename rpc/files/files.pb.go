@@ -373,6 +373,674 @@ func (m *UploadFileResp) GetLocation() string {
 	return ""
 }
 
+type EraseUserFilesReq struct {
+	Uid                  string   `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	ExcludeCategories    []string `protobuf:"bytes,2,rep,name=excludeCategories,proto3" json:"excludeCategories,omitempty"`
+	DryRun               bool     `protobuf:"varint,3,opt,name=dryRun,proto3" json:"dryRun,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EraseUserFilesReq) Reset()         { *m = EraseUserFilesReq{} }
+func (m *EraseUserFilesReq) String() string { return proto.CompactTextString(m) }
+func (*EraseUserFilesReq) ProtoMessage()    {}
+func (*EraseUserFilesReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{7}
+}
+
+func (m *EraseUserFilesReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EraseUserFilesReq.Unmarshal(m, b)
+}
+func (m *EraseUserFilesReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EraseUserFilesReq.Marshal(b, m, deterministic)
+}
+func (m *EraseUserFilesReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EraseUserFilesReq.Merge(m, src)
+}
+func (m *EraseUserFilesReq) XXX_Size() int {
+	return xxx_messageInfo_EraseUserFilesReq.Size(m)
+}
+func (m *EraseUserFilesReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_EraseUserFilesReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EraseUserFilesReq proto.InternalMessageInfo
+
+func (m *EraseUserFilesReq) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *EraseUserFilesReq) GetExcludeCategories() []string {
+	if m != nil {
+		return m.ExcludeCategories
+	}
+	return nil
+}
+
+func (m *EraseUserFilesReq) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+type ErasedFile struct {
+	Id                   uint64   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Filename             string   `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Category             string   `protobuf:"bytes,3,opt,name=category,proto3" json:"category,omitempty"`
+	Size                 int64    `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	Reason               string   `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ErasedFile) Reset()         { *m = ErasedFile{} }
+func (m *ErasedFile) String() string { return proto.CompactTextString(m) }
+func (*ErasedFile) ProtoMessage()    {}
+func (*ErasedFile) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{8}
+}
+
+func (m *ErasedFile) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ErasedFile.Unmarshal(m, b)
+}
+func (m *ErasedFile) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ErasedFile.Marshal(b, m, deterministic)
+}
+func (m *ErasedFile) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ErasedFile.Merge(m, src)
+}
+func (m *ErasedFile) XXX_Size() int {
+	return xxx_messageInfo_ErasedFile.Size(m)
+}
+func (m *ErasedFile) XXX_DiscardUnknown() {
+	xxx_messageInfo_ErasedFile.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ErasedFile proto.InternalMessageInfo
+
+func (m *ErasedFile) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *ErasedFile) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *ErasedFile) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *ErasedFile) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *ErasedFile) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// EraseUserFilesResp reports erased files and the numbers of deleted exports and collections of the user
+type EraseUserFilesResp struct {
+	DryRun               bool          `protobuf:"varint,1,opt,name=dryRun,proto3" json:"dryRun,omitempty"`
+	Removed              []*ErasedFile `protobuf:"bytes,2,rep,name=removed,proto3" json:"removed,omitempty"`
+	Kept                 []*ErasedFile `protobuf:"bytes,3,rep,name=kept,proto3" json:"kept,omitempty"`
+	Exports              int64         `protobuf:"varint,4,opt,name=exports,proto3" json:"exports,omitempty"`
+	Collections          int64         `protobuf:"varint,5,opt,name=collections,proto3" json:"collections,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *EraseUserFilesResp) Reset()         { *m = EraseUserFilesResp{} }
+func (m *EraseUserFilesResp) String() string { return proto.CompactTextString(m) }
+func (*EraseUserFilesResp) ProtoMessage()    {}
+func (*EraseUserFilesResp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{9}
+}
+
+func (m *EraseUserFilesResp) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EraseUserFilesResp.Unmarshal(m, b)
+}
+func (m *EraseUserFilesResp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EraseUserFilesResp.Marshal(b, m, deterministic)
+}
+func (m *EraseUserFilesResp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EraseUserFilesResp.Merge(m, src)
+}
+func (m *EraseUserFilesResp) XXX_Size() int {
+	return xxx_messageInfo_EraseUserFilesResp.Size(m)
+}
+func (m *EraseUserFilesResp) XXX_DiscardUnknown() {
+	xxx_messageInfo_EraseUserFilesResp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EraseUserFilesResp proto.InternalMessageInfo
+
+func (m *EraseUserFilesResp) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+func (m *EraseUserFilesResp) GetRemoved() []*ErasedFile {
+	if m != nil {
+		return m.Removed
+	}
+	return nil
+}
+
+func (m *EraseUserFilesResp) GetKept() []*ErasedFile {
+	if m != nil {
+		return m.Kept
+	}
+	return nil
+}
+
+func (m *EraseUserFilesResp) GetExports() int64 {
+	if m != nil {
+		return m.Exports
+	}
+	return 0
+}
+
+func (m *EraseUserFilesResp) GetCollections() int64 {
+	if m != nil {
+		return m.Collections
+	}
+	return 0
+}
+
+type ExportUserFilesReq struct {
+	Uid                  string   `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	RequestedBy          string   `protobuf:"bytes,2,opt,name=requestedBy,proto3" json:"requestedBy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExportUserFilesReq) Reset()         { *m = ExportUserFilesReq{} }
+func (m *ExportUserFilesReq) String() string { return proto.CompactTextString(m) }
+func (*ExportUserFilesReq) ProtoMessage()    {}
+func (*ExportUserFilesReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{10}
+}
+
+func (m *ExportUserFilesReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportUserFilesReq.Unmarshal(m, b)
+}
+func (m *ExportUserFilesReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportUserFilesReq.Marshal(b, m, deterministic)
+}
+func (m *ExportUserFilesReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportUserFilesReq.Merge(m, src)
+}
+func (m *ExportUserFilesReq) XXX_Size() int {
+	return xxx_messageInfo_ExportUserFilesReq.Size(m)
+}
+func (m *ExportUserFilesReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportUserFilesReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExportUserFilesReq proto.InternalMessageInfo
+
+func (m *ExportUserFilesReq) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *ExportUserFilesReq) GetRequestedBy() string {
+	if m != nil {
+		return m.RequestedBy
+	}
+	return ""
+}
+
+type UserFilesExportReq struct {
+	Id                   uint64   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserFilesExportReq) Reset()         { *m = UserFilesExportReq{} }
+func (m *UserFilesExportReq) String() string { return proto.CompactTextString(m) }
+func (*UserFilesExportReq) ProtoMessage()    {}
+func (*UserFilesExportReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{11}
+}
+
+func (m *UserFilesExportReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserFilesExportReq.Unmarshal(m, b)
+}
+func (m *UserFilesExportReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserFilesExportReq.Marshal(b, m, deterministic)
+}
+func (m *UserFilesExportReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserFilesExportReq.Merge(m, src)
+}
+func (m *UserFilesExportReq) XXX_Size() int {
+	return xxx_messageInfo_UserFilesExportReq.Size(m)
+}
+func (m *UserFilesExportReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserFilesExportReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserFilesExportReq proto.InternalMessageInfo
+
+func (m *UserFilesExportReq) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+// UserFilesExport is a background export of user files, the zip archive may be downloaded
+// by DownloadUserFilesExport while status is "done" until expiresAt (RFC 3339)
+type UserFilesExport struct {
+	Id                   uint64   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Uid                  string   `protobuf:"bytes,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	Status               string   `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Size                 int64    `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	FilesCount           int64    `protobuf:"varint,5,opt,name=filesCount,proto3" json:"filesCount,omitempty"`
+	ExpiresAt            string   `protobuf:"bytes,6,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserFilesExport) Reset()         { *m = UserFilesExport{} }
+func (m *UserFilesExport) String() string { return proto.CompactTextString(m) }
+func (*UserFilesExport) ProtoMessage()    {}
+func (*UserFilesExport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{12}
+}
+
+func (m *UserFilesExport) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserFilesExport.Unmarshal(m, b)
+}
+func (m *UserFilesExport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserFilesExport.Marshal(b, m, deterministic)
+}
+func (m *UserFilesExport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserFilesExport.Merge(m, src)
+}
+func (m *UserFilesExport) XXX_Size() int {
+	return xxx_messageInfo_UserFilesExport.Size(m)
+}
+func (m *UserFilesExport) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserFilesExport.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserFilesExport proto.InternalMessageInfo
+
+func (m *UserFilesExport) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *UserFilesExport) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *UserFilesExport) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *UserFilesExport) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *UserFilesExport) GetFilesCount() int64 {
+	if m != nil {
+		return m.FilesCount
+	}
+	return 0
+}
+
+func (m *UserFilesExport) GetExpiresAt() string {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return ""
+}
+
+// CombineImagesReq combines images of the user into a private pdf document with a page per image
+type CombineImagesReq struct {
+	Uid                  string   `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	FileIds              []uint64 `protobuf:"varint,2,rep,packed,name=fileIds,proto3" json:"fileIds,omitempty"`
+	FileName             string   `protobuf:"bytes,3,opt,name=fileName,proto3" json:"fileName,omitempty"`
+	Category             string   `protobuf:"bytes,4,opt,name=category,proto3" json:"category,omitempty"`
+	DeleteSources        bool     `protobuf:"varint,5,opt,name=deleteSources,proto3" json:"deleteSources,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CombineImagesReq) Reset()         { *m = CombineImagesReq{} }
+func (m *CombineImagesReq) String() string { return proto.CompactTextString(m) }
+func (*CombineImagesReq) ProtoMessage()    {}
+func (*CombineImagesReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{13}
+}
+
+func (m *CombineImagesReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CombineImagesReq.Unmarshal(m, b)
+}
+func (m *CombineImagesReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CombineImagesReq.Marshal(b, m, deterministic)
+}
+func (m *CombineImagesReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CombineImagesReq.Merge(m, src)
+}
+func (m *CombineImagesReq) XXX_Size() int {
+	return xxx_messageInfo_CombineImagesReq.Size(m)
+}
+func (m *CombineImagesReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_CombineImagesReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CombineImagesReq proto.InternalMessageInfo
+
+func (m *CombineImagesReq) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *CombineImagesReq) GetFileIds() []uint64 {
+	if m != nil {
+		return m.FileIds
+	}
+	return nil
+}
+
+func (m *CombineImagesReq) GetFileName() string {
+	if m != nil {
+		return m.FileName
+	}
+	return ""
+}
+
+func (m *CombineImagesReq) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *CombineImagesReq) GetDeleteSources() bool {
+	if m != nil {
+		return m.DeleteSources
+	}
+	return false
+}
+
+// Duplicate is a file of another user with identical ("exact") or near-identical ("perceptual") content
+type Duplicate struct {
+	FileId               uint64   `protobuf:"varint,1,opt,name=fileId,proto3" json:"fileId,omitempty"`
+	Uid                  string   `protobuf:"bytes,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	Match                string   `protobuf:"bytes,3,opt,name=match,proto3" json:"match,omitempty"`
+	Similarity           float64  `protobuf:"fixed64,4,opt,name=similarity,proto3" json:"similarity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Duplicate) Reset()         { *m = Duplicate{} }
+func (m *Duplicate) String() string { return proto.CompactTextString(m) }
+func (*Duplicate) ProtoMessage()    {}
+func (*Duplicate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{14}
+}
+
+func (m *Duplicate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Duplicate.Unmarshal(m, b)
+}
+func (m *Duplicate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Duplicate.Marshal(b, m, deterministic)
+}
+func (m *Duplicate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Duplicate.Merge(m, src)
+}
+func (m *Duplicate) XXX_Size() int {
+	return xxx_messageInfo_Duplicate.Size(m)
+}
+func (m *Duplicate) XXX_DiscardUnknown() {
+	xxx_messageInfo_Duplicate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Duplicate proto.InternalMessageInfo
+
+func (m *Duplicate) GetFileId() uint64 {
+	if m != nil {
+		return m.FileId
+	}
+	return 0
+}
+
+func (m *Duplicate) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *Duplicate) GetMatch() string {
+	if m != nil {
+		return m.Match
+	}
+	return ""
+}
+
+func (m *Duplicate) GetSimilarity() float64 {
+	if m != nil {
+		return m.Similarity
+	}
+	return 0
+}
+
+type DuplicatesResp struct {
+	Duplicates           []*Duplicate `protobuf:"bytes,1,rep,name=duplicates,proto3" json:"duplicates,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *DuplicatesResp) Reset()         { *m = DuplicatesResp{} }
+func (m *DuplicatesResp) String() string { return proto.CompactTextString(m) }
+func (*DuplicatesResp) ProtoMessage()    {}
+func (*DuplicatesResp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{15}
+}
+
+func (m *DuplicatesResp) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DuplicatesResp.Unmarshal(m, b)
+}
+func (m *DuplicatesResp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DuplicatesResp.Marshal(b, m, deterministic)
+}
+func (m *DuplicatesResp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DuplicatesResp.Merge(m, src)
+}
+func (m *DuplicatesResp) XXX_Size() int {
+	return xxx_messageInfo_DuplicatesResp.Size(m)
+}
+func (m *DuplicatesResp) XXX_DiscardUnknown() {
+	xxx_messageInfo_DuplicatesResp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DuplicatesResp proto.InternalMessageInfo
+
+func (m *DuplicatesResp) GetDuplicates() []*Duplicate {
+	if m != nil {
+		return m.Duplicates
+	}
+	return nil
+}
+
+// ShredUserReq deletes files of the user and destroys the user key, so every copy of them becomes unreadable
+type ShredUserReq struct {
+	Uid                  string   `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	RequestedBy          string   `protobuf:"bytes,2,opt,name=requestedBy,proto3" json:"requestedBy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShredUserReq) Reset()         { *m = ShredUserReq{} }
+func (m *ShredUserReq) String() string { return proto.CompactTextString(m) }
+func (*ShredUserReq) ProtoMessage()    {}
+func (*ShredUserReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{16}
+}
+
+func (m *ShredUserReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShredUserReq.Unmarshal(m, b)
+}
+func (m *ShredUserReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShredUserReq.Marshal(b, m, deterministic)
+}
+func (m *ShredUserReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShredUserReq.Merge(m, src)
+}
+func (m *ShredUserReq) XXX_Size() int {
+	return xxx_messageInfo_ShredUserReq.Size(m)
+}
+func (m *ShredUserReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShredUserReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShredUserReq proto.InternalMessageInfo
+
+func (m *ShredUserReq) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *ShredUserReq) GetRequestedBy() string {
+	if m != nil {
+		return m.RequestedBy
+	}
+	return ""
+}
+
+// ShredEvent is the compliance record of shredding, createdAt is RFC 3339
+type ShredEvent struct {
+	Id                   uint64   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Uid                  string   `protobuf:"bytes,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	RequestedBy          string   `protobuf:"bytes,3,opt,name=requestedBy,proto3" json:"requestedBy,omitempty"`
+	FilesCount           int64    `protobuf:"varint,4,opt,name=filesCount,proto3" json:"filesCount,omitempty"`
+	FailedCount          int64    `protobuf:"varint,5,opt,name=failedCount,proto3" json:"failedCount,omitempty"`
+	CreatedAt            string   `protobuf:"bytes,6,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShredEvent) Reset()         { *m = ShredEvent{} }
+func (m *ShredEvent) String() string { return proto.CompactTextString(m) }
+func (*ShredEvent) ProtoMessage()    {}
+func (*ShredEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_09a996b583fbc301, []int{17}
+}
+
+func (m *ShredEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShredEvent.Unmarshal(m, b)
+}
+func (m *ShredEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShredEvent.Marshal(b, m, deterministic)
+}
+func (m *ShredEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShredEvent.Merge(m, src)
+}
+func (m *ShredEvent) XXX_Size() int {
+	return xxx_messageInfo_ShredEvent.Size(m)
+}
+func (m *ShredEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShredEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShredEvent proto.InternalMessageInfo
+
+func (m *ShredEvent) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *ShredEvent) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *ShredEvent) GetRequestedBy() string {
+	if m != nil {
+		return m.RequestedBy
+	}
+	return ""
+}
+
+func (m *ShredEvent) GetFilesCount() int64 {
+	if m != nil {
+		return m.FilesCount
+	}
+	return 0
+}
+
+func (m *ShredEvent) GetFailedCount() int64 {
+	if m != nil {
+		return m.FailedCount
+	}
+	return 0
+}
+
+func (m *ShredEvent) GetCreatedAt() string {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*FileReq)(nil), "velmie.wallet.files.FileReq")
 	proto.RegisterType((*FileResp)(nil), "velmie.wallet.files.FileResp")
@@ -381,6 +1049,17 @@ func init() {
 	proto.RegisterType((*UserHasFilesResp)(nil), "velmie.wallet.files.UserHasFilesResp")
 	proto.RegisterType((*UploadFileReq)(nil), "velmie.wallet.files.UploadFileReq")
 	proto.RegisterType((*UploadFileResp)(nil), "velmie.wallet.files.UploadFileResp")
+	proto.RegisterType((*EraseUserFilesReq)(nil), "velmie.wallet.files.EraseUserFilesReq")
+	proto.RegisterType((*ErasedFile)(nil), "velmie.wallet.files.ErasedFile")
+	proto.RegisterType((*EraseUserFilesResp)(nil), "velmie.wallet.files.EraseUserFilesResp")
+	proto.RegisterType((*ExportUserFilesReq)(nil), "velmie.wallet.files.ExportUserFilesReq")
+	proto.RegisterType((*UserFilesExportReq)(nil), "velmie.wallet.files.UserFilesExportReq")
+	proto.RegisterType((*UserFilesExport)(nil), "velmie.wallet.files.UserFilesExport")
+	proto.RegisterType((*CombineImagesReq)(nil), "velmie.wallet.files.CombineImagesReq")
+	proto.RegisterType((*Duplicate)(nil), "velmie.wallet.files.Duplicate")
+	proto.RegisterType((*DuplicatesResp)(nil), "velmie.wallet.files.DuplicatesResp")
+	proto.RegisterType((*ShredUserReq)(nil), "velmie.wallet.files.ShredUserReq")
+	proto.RegisterType((*ShredEvent)(nil), "velmie.wallet.files.ShredEvent")
 }
 
 func init() {
@@ -388,32 +1067,64 @@ func init() {
 }
 
 var fileDescriptor_09a996b583fbc301 = []byte{
-	// 432 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x94, 0x53, 0x4d, 0x8f, 0xd3, 0x30,
-	0x10, 0x55, 0x9b, 0x76, 0xd3, 0x0e, 0xa1, 0x2c, 0x06, 0xa4, 0x50, 0x2d, 0x68, 0x65, 0x40, 0xe2,
-	0x80, 0x82, 0xb4, 0x48, 0x9c, 0x38, 0x2d, 0xb0, 0x70, 0x02, 0x35, 0xd0, 0x4b, 0x39, 0xb9, 0xc9,
-	0x80, 0x2c, 0xb9, 0x71, 0x88, 0xdd, 0x8f, 0xf0, 0x87, 0xf8, 0x55, 0xfc, 0x17, 0x6c, 0xa7, 0x69,
-	0xd3, 0x12, 0xbe, 0x2e, 0xd5, 0xcc, 0xbc, 0xce, 0x9b, 0xe7, 0x37, 0x13, 0xb8, 0x53, 0xe4, 0xc9,
-	0xd3, 0xcf, 0x5c, 0xa0, 0xaa, 0x7e, 0xa3, 0xbc, 0x90, 0x5a, 0x92, 0x5b, 0x2b, 0x14, 0x0b, 0x8e,
-	0xd1, 0x9a, 0x09, 0x81, 0x3a, 0x72, 0x10, 0xbd, 0x0b, 0xfe, 0x95, 0x09, 0x62, 0xfc, 0x4a, 0x46,
-	0xd0, 0xe5, 0x69, 0xd8, 0x39, 0xef, 0x3c, 0xee, 0xc5, 0x26, 0xa2, 0xcf, 0x61, 0x50, 0x41, 0x2a,
-	0x3f, 0xc6, 0xc8, 0x18, 0x06, 0x42, 0x26, 0x4c, 0x73, 0x99, 0x85, 0x5d, 0x53, 0x1d, 0xc6, 0xbb,
-	0x9c, 0xce, 0x60, 0x74, 0xc9, 0x33, 0x56, 0x94, 0xbb, 0x6e, 0x02, 0xbd, 0x94, 0x69, 0xe6, 0xfa,
-	0x83, 0xd8, 0xc5, 0xb6, 0xa6, 0xf8, 0x37, 0x74, 0xdd, 0x5e, 0xec, 0x62, 0x72, 0x0e, 0xd7, 0x12,
-	0x99, 0x69, 0xcc, 0xf4, 0xc7, 0x32, 0xc7, 0xd0, 0x73, 0xc4, 0xcd, 0x12, 0x9d, 0xc0, 0x8d, 0xa9,
-	0xc2, 0xe2, 0x2d, 0x53, 0x96, 0x5c, 0x59, 0xd9, 0xa7, 0xe0, 0x2d, 0xb7, 0xda, 0x86, 0xb1, 0x0d,
-	0xc9, 0x13, 0xb8, 0x89, 0x9b, 0x44, 0x2c, 0x53, 0x7c, 0xc9, 0x34, 0x7e, 0x91, 0x05, 0x47, 0x65,
-	0xe6, 0x78, 0x06, 0xff, 0x15, 0xa0, 0x17, 0x70, 0x7a, 0x48, 0x69, 0x04, 0xdf, 0x07, 0x70, 0xf6,
-	0xbc, 0xde, 0x70, 0xa5, 0x1d, 0xf5, 0x20, 0x6e, 0x54, 0xe8, 0xf7, 0x0e, 0x5c, 0x9f, 0xe6, 0x42,
-	0xb2, 0xb4, 0x36, 0xef, 0x36, 0xf4, 0xe7, 0xa5, 0x36, 0x73, 0xaa, 0x37, 0x56, 0x89, 0xb5, 0xc9,
-	0x76, 0xbd, 0x63, 0x0b, 0xac, 0x6d, 0xaa, 0xf3, 0x5a, 0xb7, 0xb7, 0xd7, 0x7d, 0x06, 0x43, 0x96,
-	0x2e, 0x78, 0xf6, 0x3e, 0x13, 0x65, 0xd8, 0x73, 0x43, 0xf7, 0x05, 0x12, 0x82, 0x9f, 0x17, 0x7c,
-	0x65, 0x84, 0x87, 0x7d, 0x87, 0xd5, 0xa9, 0x9d, 0x92, 0x54, 0xef, 0x29, 0xc3, 0x93, 0x6a, 0x4a,
-	0x9d, 0xd3, 0x17, 0x30, 0x6a, 0x0a, 0xfd, 0xbf, 0x55, 0x5e, 0xfc, 0xe8, 0x42, 0xf0, 0x01, 0x8b,
-	0x15, 0x4f, 0xd0, 0x99, 0x43, 0xae, 0xc0, 0x7f, 0x83, 0xda, 0xc6, 0xe4, 0x2c, 0x6a, 0xb9, 0xa7,
-	0x68, 0xeb, 0xc7, 0xf8, 0xde, 0x1f, 0x50, 0x23, 0x62, 0x02, 0xc1, 0x2b, 0xb9, 0xce, 0x6a, 0x61,
-	0x7f, 0x21, 0x7b, 0xd0, 0x8a, 0x1e, 0x1d, 0xd9, 0x27, 0x08, 0x9a, 0x7b, 0x24, 0x0f, 0x5b, 0x9b,
-	0x8e, 0xae, 0x67, 0xfc, 0xe8, 0x1f, 0xfe, 0x65, 0xc8, 0xa7, 0x00, 0x7b, 0x1b, 0x09, 0x6d, 0x6f,
-	0x6a, 0x1e, 0xc4, 0x6f, 0x34, 0x1f, 0xee, 0xe2, 0xd2, 0x9f, 0xf5, 0x5d, 0x7d, 0x7e, 0xe2, 0x3e,
-	0xd1, 0x67, 0x3f, 0x03, 0x00, 0x00, 0xff, 0xff, 0x8e, 0x4c, 0x83, 0x63, 0xbb, 0x03, 0x00, 0x00,
+	// 938 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x56, 0x4f, 0x73, 0xdb, 0x44,
+	0x14, 0x1f, 0x59, 0x8e, 0x1d, 0xbf, 0x38, 0x4e, 0xba, 0x94, 0x20, 0x3c, 0xa5, 0x35, 0x6a, 0xa0,
+	0x39, 0x30, 0x66, 0x26, 0x9d, 0x61, 0x86, 0x19, 0x86, 0x19, 0x92, 0x26, 0x6d, 0x2f, 0x94, 0x2a,
+	0xe4, 0x52, 0x2e, 0x6c, 0xa4, 0xd7, 0x74, 0x27, 0xb2, 0xa4, 0xee, 0xae, 0x9d, 0x98, 0x13, 0x1f,
+	0x04, 0xce, 0x5c, 0xf9, 0x38, 0x7c, 0x09, 0xbe, 0x03, 0xb3, 0xab, 0x95, 0xb4, 0x96, 0x65, 0x62,
+	0x32, 0x5c, 0x3c, 0x7a, 0x6f, 0xdf, 0xdf, 0xdf, 0xfe, 0xde, 0x5b, 0xc3, 0x87, 0x3c, 0x0b, 0xbf,
+	0x7c, 0xcb, 0x62, 0x14, 0xf9, 0xef, 0x38, 0xe3, 0xa9, 0x4c, 0xc9, 0x07, 0x33, 0x8c, 0x27, 0x0c,
+	0xc7, 0xd7, 0x34, 0x8e, 0x51, 0x8e, 0xf5, 0x91, 0xff, 0x31, 0x74, 0x4f, 0x59, 0x8c, 0x01, 0xbe,
+	0x27, 0x03, 0x68, 0xb1, 0xc8, 0x73, 0x46, 0xce, 0x41, 0x3b, 0x68, 0xb1, 0xc8, 0xff, 0x0a, 0x36,
+	0xf3, 0x23, 0x91, 0xd5, 0xcf, 0xc8, 0x10, 0x36, 0xe3, 0x34, 0xa4, 0x92, 0xa5, 0x89, 0xd7, 0x1a,
+	0x39, 0x07, 0xbd, 0xa0, 0x94, 0xfd, 0x37, 0x30, 0x38, 0x62, 0x09, 0xe5, 0xf3, 0xd2, 0x9b, 0x40,
+	0x3b, 0xa2, 0x92, 0x6a, 0xff, 0x7e, 0xa0, 0xbf, 0x95, 0x4e, 0xb0, 0x5f, 0x50, 0x7b, 0xbb, 0x81,
+	0xfe, 0x26, 0x23, 0xd8, 0x0a, 0xd3, 0x44, 0x62, 0x22, 0x7f, 0x9c, 0x67, 0xe8, 0xb9, 0x3a, 0xb0,
+	0xad, 0xf2, 0x5f, 0xc3, 0xce, 0xb9, 0x40, 0xfe, 0x82, 0x0a, 0x15, 0x5c, 0xa8, 0xb2, 0x77, 0xc1,
+	0x9d, 0x9a, 0xda, 0x7a, 0x81, 0xfa, 0x24, 0x5f, 0xc0, 0x3d, 0xbc, 0x09, 0xe3, 0x69, 0x84, 0xc7,
+	0x54, 0xe2, 0x65, 0xca, 0x19, 0x0a, 0xaf, 0x35, 0x72, 0x0f, 0x7a, 0xc1, 0xf2, 0x81, 0x7f, 0x08,
+	0xbb, 0x8b, 0x21, 0x45, 0x46, 0x1e, 0x02, 0x68, 0x78, 0x4e, 0x6e, 0x98, 0x90, 0x3a, 0xf4, 0x66,
+	0x60, 0x69, 0xfc, 0x3f, 0x1c, 0xd8, 0x3e, 0xcf, 0xe2, 0x94, 0x46, 0x05, 0x78, 0xf7, 0x61, 0xe3,
+	0x62, 0x2e, 0x51, 0x98, 0x1e, 0x73, 0x41, 0xc1, 0xa4, 0xbc, 0xbe, 0xa7, 0x13, 0x2c, 0x60, 0x2a,
+	0xe4, 0xa2, 0x6e, 0xb7, 0xaa, 0xfb, 0x01, 0xf4, 0x68, 0x34, 0x61, 0xc9, 0xab, 0x24, 0x9e, 0x7b,
+	0x6d, 0x9d, 0xb4, 0x52, 0x10, 0x0f, 0xba, 0x19, 0x67, 0x33, 0x2a, 0xd1, 0xdb, 0xd0, 0x67, 0x85,
+	0xa8, 0xb2, 0x84, 0x79, 0x3f, 0x73, 0xaf, 0x93, 0x67, 0x29, 0x64, 0xff, 0x1b, 0x18, 0xd8, 0x85,
+	0xfe, 0xc7, 0xab, 0xbc, 0x82, 0x7b, 0x27, 0x9c, 0x0a, 0x54, 0x00, 0xfd, 0x5f, 0x80, 0x93, 0x3d,
+	0xe8, 0x44, 0x7c, 0x1e, 0x4c, 0x13, 0xdd, 0xfb, 0x66, 0x60, 0x24, 0xff, 0x57, 0x07, 0x40, 0x67,
+	0xd3, 0xb5, 0x36, 0xd5, 0xa9, 0xb0, 0x4b, 0x6a, 0x58, 0x2a, 0x79, 0x01, 0x01, 0x77, 0x11, 0x81,
+	0x92, 0x68, 0x6d, 0x8b, 0x68, 0x7b, 0xd0, 0xe1, 0x48, 0x45, 0x9a, 0x68, 0x28, 0x7b, 0x81, 0x91,
+	0xfc, 0xbf, 0x1c, 0x20, 0xf5, 0x86, 0x45, 0x66, 0x55, 0xec, 0xd8, 0x15, 0x93, 0xaf, 0xa1, 0xcb,
+	0x71, 0x92, 0xce, 0x30, 0xd2, 0xdd, 0x6e, 0x1d, 0x3e, 0x1a, 0x37, 0xcc, 0xd8, 0xb8, 0x6a, 0x2a,
+	0x28, 0xec, 0xc9, 0x53, 0x68, 0x5f, 0x61, 0x26, 0x3d, 0x77, 0x3d, 0x3f, 0x6d, 0xac, 0x28, 0x80,
+	0x37, 0x59, 0xca, 0xa5, 0x30, 0xdd, 0x14, 0x62, 0x3e, 0x39, 0x71, 0x8c, 0xa1, 0xba, 0x36, 0xa1,
+	0xbb, 0x72, 0x03, 0x5b, 0xe5, 0xbf, 0x00, 0x72, 0xa2, 0x8d, 0x6f, 0xb9, 0xcb, 0x11, 0x6c, 0x71,
+	0x7c, 0x3f, 0x45, 0x21, 0x31, 0x3a, 0x9a, 0x1b, 0xa4, 0x6d, 0x95, 0xbf, 0x0f, 0xa4, 0x8c, 0x91,
+	0x87, 0x6c, 0xda, 0x1e, 0xbf, 0x3b, 0xb0, 0x53, 0x33, 0x5b, 0xba, 0x52, 0x93, 0xbd, 0x55, 0x65,
+	0xdf, 0x83, 0x8e, 0x90, 0x54, 0x4e, 0x85, 0xb9, 0x46, 0x23, 0x35, 0x5e, 0x62, 0x31, 0xa4, 0xc7,
+	0xe9, 0x34, 0x91, 0xa6, 0x65, 0x4b, 0xa3, 0xc6, 0x09, 0x6f, 0x32, 0xc6, 0x51, 0x7c, 0x27, 0xcd,
+	0x5c, 0x54, 0x0a, 0xff, 0x37, 0x07, 0x76, 0x8f, 0xd3, 0xc9, 0x05, 0x4b, 0xf0, 0xe5, 0x84, 0x5e,
+	0xae, 0x82, 0xc3, 0x83, 0xae, 0x0a, 0xf9, 0x32, 0xca, 0x09, 0xdd, 0x0e, 0x0a, 0x71, 0x61, 0xb6,
+	0xdd, 0xda, 0x6c, 0xdb, 0x7c, 0x6c, 0xd7, 0xf8, 0xb8, 0x0f, 0xdb, 0x11, 0xc6, 0x28, 0xf1, 0x2c,
+	0x9d, 0xf2, 0x10, 0x85, 0x99, 0xe6, 0x45, 0xa5, 0x7f, 0x05, 0xbd, 0x67, 0xd3, 0x2c, 0x66, 0xca,
+	0x4d, 0xa1, 0x92, 0x67, 0x35, 0xd8, 0x19, 0xa9, 0x01, 0xbf, 0xfb, 0xb0, 0x31, 0xa1, 0x32, 0x7c,
+	0x67, 0x2a, 0xca, 0x05, 0x85, 0x94, 0x60, 0x13, 0x16, 0x53, 0xce, 0x64, 0x5e, 0x90, 0x13, 0x58,
+	0x1a, 0xff, 0x07, 0x18, 0x94, 0xc9, 0x72, 0xc6, 0x7f, 0x0b, 0x10, 0x95, 0x1a, 0xcf, 0xd1, 0x24,
+	0x7d, 0xd8, 0x48, 0xd2, 0xd2, 0x31, 0xb0, 0x3c, 0xfc, 0x23, 0xe8, 0x9f, 0xbd, 0xe3, 0x18, 0x29,
+	0x06, 0xdc, 0x95, 0x67, 0x7f, 0x3a, 0x00, 0x3a, 0xc8, 0xc9, 0x0c, 0x93, 0x75, 0xc8, 0x53, 0x0b,
+	0xe9, 0x2e, 0x85, 0xac, 0x51, 0xa6, 0xbd, 0x44, 0x99, 0x11, 0x6c, 0xbd, 0xa5, 0x2c, 0xc6, 0xc8,
+	0xe6, 0x94, 0xad, 0x52, 0xa4, 0x0a, 0x39, 0x52, 0x89, 0x51, 0x45, 0xaa, 0x52, 0x71, 0xf8, 0x77,
+	0x17, 0xfa, 0x67, 0xc8, 0x67, 0x2c, 0x44, 0xcd, 0x7b, 0x72, 0x0a, 0xdd, 0xe7, 0x28, 0xd5, 0x37,
+	0x79, 0xd0, 0x08, 0x9f, 0x79, 0x3f, 0x86, 0x9f, 0xfc, 0xcb, 0xa9, 0xc8, 0xc8, 0x6b, 0xe8, 0x3f,
+	0x4b, 0xaf, 0x93, 0x62, 0x91, 0xdf, 0x12, 0xec, 0x71, 0xe3, 0x69, 0xed, 0x51, 0xfe, 0x09, 0xfa,
+	0xf6, 0xbb, 0x47, 0xf6, 0x1b, 0x9d, 0x6a, 0xaf, 0xed, 0xf0, 0xb3, 0x35, 0xac, 0x44, 0x46, 0xce,
+	0x01, 0xaa, 0x67, 0x87, 0xf8, 0xcd, 0x4e, 0xf6, 0x03, 0x3a, 0x7c, 0x7c, 0xab, 0x8d, 0xc8, 0x08,
+	0x85, 0xc1, 0xe2, 0x7a, 0x26, 0x9f, 0xaf, 0xde, 0x9c, 0xf6, 0xa2, 0x1b, 0x3e, 0x59, 0xcb, 0x4e,
+	0x64, 0xe4, 0x67, 0xd8, 0xa9, 0xed, 0x49, 0xb2, 0xc2, 0x77, 0x69, 0x9b, 0x0e, 0x57, 0x43, 0x68,
+	0x6f, 0xc1, 0x10, 0xc8, 0x73, 0x94, 0x75, 0xed, 0x93, 0x75, 0x7c, 0xd7, 0x4f, 0x72, 0x09, 0x1f,
+	0x15, 0x84, 0xb9, 0x73, 0xa6, 0xb5, 0x68, 0x74, 0x0e, 0xdb, 0x0b, 0x6b, 0x94, 0x34, 0x33, 0xa4,
+	0xbe, 0x6a, 0x6f, 0x23, 0xfc, 0x19, 0x0c, 0x4e, 0x59, 0x12, 0x55, 0x6b, 0xe9, 0x4e, 0x94, 0xaf,
+	0x6d, 0xb5, 0x57, 0xd0, 0x2b, 0xb7, 0x12, 0xf9, 0xb4, 0xd1, 0xc3, 0xde, 0x5a, 0xc3, 0x47, 0xab,
+	0x4d, 0xf4, 0x4e, 0x3a, 0xea, 0xbe, 0xd9, 0xd0, 0xba, 0x8b, 0x8e, 0xfe, 0x8b, 0xfd, 0xf4, 0x9f,
+	0x01, 0x00, 0x27, 0x52, 0xd9, 0x9d, 0x7b, 0x0b, 0x00, 0x00,
 }
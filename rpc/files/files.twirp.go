@@ -40,6 +40,20 @@ type ServiceFiles interface {
 	UserHasFiles(context.Context, *UserHasFilesReq) (*UserHasFilesResp, error)
 
 	UploadFile(context.Context, *UploadFileReq) (*UploadFileResp, error)
+
+	EraseUserFiles(context.Context, *EraseUserFilesReq) (*EraseUserFilesResp, error)
+
+	ExportUserFiles(context.Context, *ExportUserFilesReq) (*UserFilesExport, error)
+
+	GetUserFilesExport(context.Context, *UserFilesExportReq) (*UserFilesExport, error)
+
+	DownloadUserFilesExport(context.Context, *UserFilesExportReq) (*BinaryFileResp, error)
+
+	CombineImages(context.Context, *CombineImagesReq) (*FileResp, error)
+
+	FindDuplicates(context.Context, *FileReq) (*DuplicatesResp, error)
+
+	ShredUser(context.Context, *ShredUserReq) (*ShredEvent, error)
 }
 
 // ============================
@@ -48,18 +62,25 @@ type ServiceFiles interface {
 
 type serviceFilesProtobufClient struct {
 	client HTTPClient
-	urls   [4]string
+	urls   [11]string
 }
 
 // NewServiceFilesProtobufClient creates a Protobuf client that implements the ServiceFiles interface.
 // It communicates using Protobuf and can be configured with a custom HTTPClient.
 func NewServiceFilesProtobufClient(addr string, client HTTPClient) ServiceFiles {
 	prefix := urlBase(addr) + ServiceFilesPathPrefix
-	urls := [4]string{
+	urls := [11]string{
 		prefix + "GetFile",
 		prefix + "DownloadFile",
 		prefix + "UserHasFiles",
 		prefix + "UploadFile",
+		prefix + "EraseUserFiles",
+		prefix + "ExportUserFiles",
+		prefix + "GetUserFilesExport",
+		prefix + "DownloadUserFilesExport",
+		prefix + "CombineImages",
+		prefix + "FindDuplicates",
+		prefix + "ShredUser",
 	}
 	if httpClient, ok := client.(*http.Client); ok {
 		return &serviceFilesProtobufClient{
@@ -121,24 +142,115 @@ func (c *serviceFilesProtobufClient) UploadFile(ctx context.Context, in *UploadF
 	return out, nil
 }
 
+func (c *serviceFilesProtobufClient) EraseUserFiles(ctx context.Context, in *EraseUserFilesReq) (*EraseUserFilesResp, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "EraseUserFiles")
+	out := new(EraseUserFilesResp)
+	err := doProtobufRequest(ctx, c.client, c.urls[4], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesProtobufClient) ExportUserFiles(ctx context.Context, in *ExportUserFilesReq) (*UserFilesExport, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "ExportUserFiles")
+	out := new(UserFilesExport)
+	err := doProtobufRequest(ctx, c.client, c.urls[5], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesProtobufClient) GetUserFilesExport(ctx context.Context, in *UserFilesExportReq) (*UserFilesExport, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "GetUserFilesExport")
+	out := new(UserFilesExport)
+	err := doProtobufRequest(ctx, c.client, c.urls[6], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesProtobufClient) DownloadUserFilesExport(ctx context.Context, in *UserFilesExportReq) (*BinaryFileResp, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "DownloadUserFilesExport")
+	out := new(BinaryFileResp)
+	err := doProtobufRequest(ctx, c.client, c.urls[7], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesProtobufClient) CombineImages(ctx context.Context, in *CombineImagesReq) (*FileResp, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "CombineImages")
+	out := new(FileResp)
+	err := doProtobufRequest(ctx, c.client, c.urls[8], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesProtobufClient) FindDuplicates(ctx context.Context, in *FileReq) (*DuplicatesResp, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "FindDuplicates")
+	out := new(DuplicatesResp)
+	err := doProtobufRequest(ctx, c.client, c.urls[9], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesProtobufClient) ShredUser(ctx context.Context, in *ShredUserReq) (*ShredEvent, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "ShredUser")
+	out := new(ShredEvent)
+	err := doProtobufRequest(ctx, c.client, c.urls[10], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ========================
 // ServiceFiles JSON Client
 // ========================
 
 type serviceFilesJSONClient struct {
 	client HTTPClient
-	urls   [4]string
+	urls   [11]string
 }
 
 // NewServiceFilesJSONClient creates a JSON client that implements the ServiceFiles interface.
 // It communicates using JSON and can be configured with a custom HTTPClient.
 func NewServiceFilesJSONClient(addr string, client HTTPClient) ServiceFiles {
 	prefix := urlBase(addr) + ServiceFilesPathPrefix
-	urls := [4]string{
+	urls := [11]string{
 		prefix + "GetFile",
 		prefix + "DownloadFile",
 		prefix + "UserHasFiles",
 		prefix + "UploadFile",
+		prefix + "EraseUserFiles",
+		prefix + "ExportUserFiles",
+		prefix + "GetUserFilesExport",
+		prefix + "DownloadUserFilesExport",
+		prefix + "CombineImages",
+		prefix + "FindDuplicates",
+		prefix + "ShredUser",
 	}
 	if httpClient, ok := client.(*http.Client); ok {
 		return &serviceFilesJSONClient{
@@ -200,6 +312,90 @@ func (c *serviceFilesJSONClient) UploadFile(ctx context.Context, in *UploadFileR
 	return out, nil
 }
 
+func (c *serviceFilesJSONClient) EraseUserFiles(ctx context.Context, in *EraseUserFilesReq) (*EraseUserFilesResp, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "EraseUserFiles")
+	out := new(EraseUserFilesResp)
+	err := doJSONRequest(ctx, c.client, c.urls[4], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesJSONClient) ExportUserFiles(ctx context.Context, in *ExportUserFilesReq) (*UserFilesExport, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "ExportUserFiles")
+	out := new(UserFilesExport)
+	err := doJSONRequest(ctx, c.client, c.urls[5], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesJSONClient) GetUserFilesExport(ctx context.Context, in *UserFilesExportReq) (*UserFilesExport, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "GetUserFilesExport")
+	out := new(UserFilesExport)
+	err := doJSONRequest(ctx, c.client, c.urls[6], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesJSONClient) DownloadUserFilesExport(ctx context.Context, in *UserFilesExportReq) (*BinaryFileResp, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "DownloadUserFilesExport")
+	out := new(BinaryFileResp)
+	err := doJSONRequest(ctx, c.client, c.urls[7], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesJSONClient) CombineImages(ctx context.Context, in *CombineImagesReq) (*FileResp, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "CombineImages")
+	out := new(FileResp)
+	err := doJSONRequest(ctx, c.client, c.urls[8], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesJSONClient) FindDuplicates(ctx context.Context, in *FileReq) (*DuplicatesResp, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "FindDuplicates")
+	out := new(DuplicatesResp)
+	err := doJSONRequest(ctx, c.client, c.urls[9], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serviceFilesJSONClient) ShredUser(ctx context.Context, in *ShredUserReq) (*ShredEvent, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "velmie.wallet.files")
+	ctx = ctxsetters.WithServiceName(ctx, "ServiceFiles")
+	ctx = ctxsetters.WithMethodName(ctx, "ShredUser")
+	out := new(ShredEvent)
+	err := doJSONRequest(ctx, c.client, c.urls[10], in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ===========================
 // ServiceFiles Server Handler
 // ===========================
@@ -260,6 +456,27 @@ func (s *serviceFilesServer) ServeHTTP(resp http.ResponseWriter, req *http.Reque
 	case "/twirp/velmie.wallet.files.ServiceFiles/UploadFile":
 		s.serveUploadFile(ctx, resp, req)
 		return
+	case "/twirp/velmie.wallet.files.ServiceFiles/EraseUserFiles":
+		s.serveEraseUserFiles(ctx, resp, req)
+		return
+	case "/twirp/velmie.wallet.files.ServiceFiles/ExportUserFiles":
+		s.serveExportUserFiles(ctx, resp, req)
+		return
+	case "/twirp/velmie.wallet.files.ServiceFiles/GetUserFilesExport":
+		s.serveGetUserFilesExport(ctx, resp, req)
+		return
+	case "/twirp/velmie.wallet.files.ServiceFiles/DownloadUserFilesExport":
+		s.serveDownloadUserFilesExport(ctx, resp, req)
+		return
+	case "/twirp/velmie.wallet.files.ServiceFiles/CombineImages":
+		s.serveCombineImages(ctx, resp, req)
+		return
+	case "/twirp/velmie.wallet.files.ServiceFiles/FindDuplicates":
+		s.serveFindDuplicates(ctx, resp, req)
+		return
+	case "/twirp/velmie.wallet.files.ServiceFiles/ShredUser":
+		s.serveShredUser(ctx, resp, req)
+		return
 	default:
 		msg := fmt.Sprintf("no handler for path %q", req.URL.Path)
 		err = badRouteError(msg, req.Method, req.URL.Path)
@@ -844,6 +1061,1014 @@ func (s *serviceFilesServer) serveUploadFileProtobuf(ctx context.Context, resp h
 	callResponseSent(ctx, s.hooks)
 }
 
+func (s *serviceFilesServer) serveEraseUserFiles(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveEraseUserFilesJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveEraseUserFilesProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceFilesServer) serveEraseUserFilesJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "EraseUserFiles")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	reqContent := new(EraseUserFilesReq)
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err = unmarshaler.Unmarshal(req.Body, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request json")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *EraseUserFilesResp
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.EraseUserFiles(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *EraseUserFilesResp and nil error while calling EraseUserFiles. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	var buf bytes.Buffer
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	if err = marshaler.Marshal(&buf, respContent); err != nil {
+		err = wrapErr(err, "failed to marshal json response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+
+	respBytes := buf.Bytes()
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveEraseUserFilesProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "EraseUserFiles")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		err = wrapErr(err, "failed to read request body")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+	reqContent := new(EraseUserFilesReq)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request proto")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *EraseUserFilesResp
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.EraseUserFiles(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *EraseUserFilesResp and nil error while calling EraseUserFiles. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		err = wrapErr(err, "failed to marshal proto response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveExportUserFiles(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveExportUserFilesJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveExportUserFilesProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceFilesServer) serveExportUserFilesJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "ExportUserFiles")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	reqContent := new(ExportUserFilesReq)
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err = unmarshaler.Unmarshal(req.Body, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request json")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *UserFilesExport
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.ExportUserFiles(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *UserFilesExport and nil error while calling ExportUserFiles. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	var buf bytes.Buffer
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	if err = marshaler.Marshal(&buf, respContent); err != nil {
+		err = wrapErr(err, "failed to marshal json response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+
+	respBytes := buf.Bytes()
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveExportUserFilesProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "ExportUserFiles")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		err = wrapErr(err, "failed to read request body")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+	reqContent := new(ExportUserFilesReq)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request proto")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *UserFilesExport
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.ExportUserFiles(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *UserFilesExport and nil error while calling ExportUserFiles. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		err = wrapErr(err, "failed to marshal proto response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveGetUserFilesExport(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveGetUserFilesExportJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveGetUserFilesExportProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceFilesServer) serveGetUserFilesExportJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "GetUserFilesExport")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	reqContent := new(UserFilesExportReq)
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err = unmarshaler.Unmarshal(req.Body, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request json")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *UserFilesExport
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.GetUserFilesExport(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *UserFilesExport and nil error while calling GetUserFilesExport. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	var buf bytes.Buffer
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	if err = marshaler.Marshal(&buf, respContent); err != nil {
+		err = wrapErr(err, "failed to marshal json response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+
+	respBytes := buf.Bytes()
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveGetUserFilesExportProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "GetUserFilesExport")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		err = wrapErr(err, "failed to read request body")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+	reqContent := new(UserFilesExportReq)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request proto")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *UserFilesExport
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.GetUserFilesExport(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *UserFilesExport and nil error while calling GetUserFilesExport. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		err = wrapErr(err, "failed to marshal proto response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveDownloadUserFilesExport(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveDownloadUserFilesExportJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveDownloadUserFilesExportProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceFilesServer) serveDownloadUserFilesExportJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "DownloadUserFilesExport")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	reqContent := new(UserFilesExportReq)
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err = unmarshaler.Unmarshal(req.Body, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request json")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *BinaryFileResp
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.DownloadUserFilesExport(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *BinaryFileResp and nil error while calling DownloadUserFilesExport. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	var buf bytes.Buffer
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	if err = marshaler.Marshal(&buf, respContent); err != nil {
+		err = wrapErr(err, "failed to marshal json response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+
+	respBytes := buf.Bytes()
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveDownloadUserFilesExportProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "DownloadUserFilesExport")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		err = wrapErr(err, "failed to read request body")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+	reqContent := new(UserFilesExportReq)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request proto")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *BinaryFileResp
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.DownloadUserFilesExport(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *BinaryFileResp and nil error while calling DownloadUserFilesExport. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		err = wrapErr(err, "failed to marshal proto response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveCombineImages(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveCombineImagesJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveCombineImagesProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceFilesServer) serveCombineImagesJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "CombineImages")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	reqContent := new(CombineImagesReq)
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err = unmarshaler.Unmarshal(req.Body, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request json")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *FileResp
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.CombineImages(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *FileResp and nil error while calling CombineImages. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	var buf bytes.Buffer
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	if err = marshaler.Marshal(&buf, respContent); err != nil {
+		err = wrapErr(err, "failed to marshal json response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+
+	respBytes := buf.Bytes()
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveCombineImagesProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "CombineImages")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		err = wrapErr(err, "failed to read request body")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+	reqContent := new(CombineImagesReq)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request proto")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *FileResp
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.CombineImages(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *FileResp and nil error while calling CombineImages. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		err = wrapErr(err, "failed to marshal proto response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveFindDuplicates(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveFindDuplicatesJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveFindDuplicatesProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceFilesServer) serveFindDuplicatesJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "FindDuplicates")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	reqContent := new(FileReq)
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err = unmarshaler.Unmarshal(req.Body, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request json")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *DuplicatesResp
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.FindDuplicates(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *DuplicatesResp and nil error while calling FindDuplicates. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	var buf bytes.Buffer
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	if err = marshaler.Marshal(&buf, respContent); err != nil {
+		err = wrapErr(err, "failed to marshal json response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+
+	respBytes := buf.Bytes()
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveFindDuplicatesProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "FindDuplicates")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		err = wrapErr(err, "failed to read request body")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+	reqContent := new(FileReq)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request proto")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *DuplicatesResp
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.FindDuplicates(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *DuplicatesResp and nil error while calling FindDuplicates. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		err = wrapErr(err, "failed to marshal proto response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveShredUser(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveShredUserJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveShredUserProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *serviceFilesServer) serveShredUserJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "ShredUser")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	reqContent := new(ShredUserReq)
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err = unmarshaler.Unmarshal(req.Body, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request json")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *ShredEvent
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.ShredUser(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *ShredEvent and nil error while calling ShredUser. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	var buf bytes.Buffer
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	if err = marshaler.Marshal(&buf, respContent); err != nil {
+		err = wrapErr(err, "failed to marshal json response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+
+	respBytes := buf.Bytes()
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *serviceFilesServer) serveShredUserProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "ShredUser")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		err = wrapErr(err, "failed to read request body")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+	reqContent := new(ShredUserReq)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		err = wrapErr(err, "failed to parse request proto")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	// Call service method
+	var respContent *ShredEvent
+	func() {
+		defer func() {
+			// In case of a panic, serve a 500 error and then panic.
+			if r := recover(); r != nil {
+				s.writeError(ctx, resp, twirp.InternalError("Internal service panic"))
+				panic(r)
+			}
+		}()
+		respContent, err = s.ShredUser(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *ShredEvent and nil error while calling ShredUser. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		err = wrapErr(err, "failed to marshal proto response")
+		s.writeError(ctx, resp, twirp.InternalErrorWith(err))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
 func (s *serviceFilesServer) ServiceDescriptor() ([]byte, int) {
 	return twirpFileDescriptor0, 0
 }
@@ -1273,33 +2498,64 @@ func callError(ctx context.Context, h *twirp.ServerHooks, err twirp.Error) conte
 }
 
 var twirpFileDescriptor0 = []byte{
-	// 436 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x53, 0x4d, 0x6f, 0xd3, 0x40,
-	0x10, 0x55, 0xec, 0xa4, 0x49, 0x86, 0x10, 0xca, 0x02, 0xd2, 0x12, 0x15, 0x64, 0x2d, 0x20, 0xe5,
-	0x80, 0x8c, 0x54, 0x24, 0x4e, 0x9c, 0x0a, 0x14, 0x4e, 0xa0, 0x2e, 0xe4, 0x52, 0x4e, 0x5b, 0x7b,
-	0x40, 0x2b, 0x6d, 0xbc, 0x8b, 0x77, 0x9b, 0xd6, 0xfc, 0x21, 0x7e, 0x15, 0xff, 0x05, 0xed, 0x3a,
-	0x6e, 0x9c, 0x60, 0x3e, 0x7a, 0x89, 0x66, 0xe6, 0xe5, 0xcd, 0xbc, 0x7d, 0x33, 0x86, 0x7b, 0xa5,
-	0xc9, 0x9e, 0x7d, 0x91, 0x0a, 0x6d, 0xfd, 0x9b, 0x9a, 0x52, 0x3b, 0x4d, 0xee, 0xac, 0x50, 0x2d,
-	0x25, 0xa6, 0x17, 0x42, 0x29, 0x74, 0x69, 0x80, 0xd8, 0x7d, 0x18, 0x1e, 0x4b, 0x85, 0x1c, 0xbf,
-	0x91, 0x29, 0x44, 0x32, 0xa7, 0xbd, 0xa4, 0x37, 0xef, 0xf3, 0x48, 0xe6, 0xec, 0x05, 0x8c, 0x6a,
-	0xc8, 0x9a, 0x5d, 0x8c, 0xcc, 0x60, 0xa4, 0x74, 0x26, 0x9c, 0xd4, 0x05, 0x8d, 0x92, 0xde, 0x7c,
-	0xcc, 0xaf, 0x72, 0x76, 0x0a, 0xd3, 0x23, 0x59, 0x88, 0xb2, 0xba, 0x62, 0x13, 0xe8, 0xe7, 0xc2,
-	0x89, 0xc0, 0x9f, 0xf0, 0x10, 0xfb, 0x9a, 0x95, 0xdf, 0x31, 0xb0, 0x63, 0x1e, 0x62, 0x92, 0xc0,
-	0x8d, 0x4c, 0x17, 0x0e, 0x0b, 0xf7, 0xa9, 0x32, 0x48, 0xe3, 0xd0, 0xb8, 0x5d, 0x62, 0x27, 0x70,
-	0x6b, 0x61, 0xb1, 0x7c, 0x27, 0xac, 0x6f, 0x6e, 0xbd, 0xec, 0x7d, 0x88, 0xcf, 0xd7, 0xda, 0xc6,
-	0xdc, 0x87, 0xe4, 0x29, 0xdc, 0xc6, 0xcb, 0x4c, 0x9d, 0xe7, 0xf8, 0x4a, 0x38, 0xfc, 0xaa, 0x4b,
-	0x89, 0x96, 0x46, 0x49, 0x3c, 0x1f, 0xf3, 0xdf, 0x01, 0x76, 0x08, 0xfb, 0xdb, 0x2d, 0xad, 0x21,
-	0x0f, 0x01, 0x82, 0x3d, 0x6f, 0x2e, 0xa5, 0x75, 0xa1, 0xf5, 0x88, 0xb7, 0x2a, 0xec, 0x47, 0x0f,
-	0x6e, 0x2e, 0x8c, 0xd2, 0x22, 0x6f, 0xcc, 0xbb, 0x0b, 0x83, 0xb3, 0xca, 0xa1, 0x5d, 0xbf, 0xb1,
-	0x4e, 0xbc, 0x4d, 0x9e, 0xf5, 0x5e, 0x2c, 0xb1, 0xb1, 0xa9, 0xc9, 0x1b, 0xdd, 0xf1, 0x46, 0xf7,
-	0x01, 0x8c, 0x45, 0xbe, 0x94, 0xc5, 0x87, 0x42, 0x55, 0xb4, 0x1f, 0x86, 0x6e, 0x0a, 0x84, 0xc2,
-	0xd0, 0x94, 0x72, 0x25, 0x1c, 0xd2, 0x41, 0xc0, 0x9a, 0xd4, 0x4f, 0xc9, 0xea, 0xf7, 0x54, 0x74,
-	0xaf, 0x9e, 0xd2, 0xe4, 0xec, 0x25, 0x4c, 0xdb, 0x42, 0xaf, 0xb7, 0xca, 0xc3, 0x9f, 0x11, 0x4c,
-	0x3e, 0x62, 0xb9, 0x92, 0x19, 0x06, 0x73, 0xc8, 0x31, 0x0c, 0xdf, 0xa2, 0xf3, 0x31, 0x39, 0x48,
-	0x3b, 0xee, 0x29, 0x5d, 0xfb, 0x31, 0x7b, 0xf0, 0x17, 0xd4, 0x1a, 0x72, 0x02, 0x93, 0xd7, 0xfa,
-	0xa2, 0x68, 0x84, 0xfd, 0xa3, 0xd9, 0xa3, 0x4e, 0x74, 0xe7, 0xc8, 0x3e, 0xc3, 0xa4, 0xbd, 0x47,
-	0xf2, 0xb8, 0x93, 0xb4, 0x73, 0x3d, 0xb3, 0x27, 0xff, 0xf1, 0x2f, 0x6b, 0xc8, 0x02, 0x60, 0x63,
-	0x23, 0x61, 0xdd, 0xa4, 0xf6, 0x41, 0xfc, 0x41, 0xf3, 0xf6, 0x2e, 0x8e, 0x86, 0xa7, 0x83, 0x50,
-	0x3f, 0xdb, 0x0b, 0x9f, 0xe8, 0xf3, 0x5f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x8e, 0x4c, 0x83, 0x63,
-	0xbb, 0x03, 0x00, 0x00,
+	// 938 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x4f, 0x73, 0xdb, 0x44,
+	0x14, 0x1f, 0x59, 0x8e, 0x1d, 0xbf, 0x38, 0x4e, 0xba, 0x94, 0x20, 0x3c, 0xa5, 0x35, 0x6a, 0xa0,
+	0x39, 0x30, 0x66, 0x26, 0x9d, 0x61, 0x86, 0x19, 0x86, 0x19, 0x92, 0x26, 0x6d, 0x2f, 0x94, 0x2a,
+	0xe4, 0x52, 0x2e, 0x6c, 0xa4, 0xd7, 0x74, 0x27, 0xb2, 0xa4, 0xee, 0xae, 0x9d, 0x98, 0x13, 0x1f,
+	0x04, 0xce, 0x5c, 0xf9, 0x38, 0x7c, 0x09, 0xbe, 0x03, 0xb3, 0xab, 0x95, 0xb4, 0x96, 0x65, 0x62,
+	0x32, 0x5c, 0x3c, 0x7a, 0x6f, 0xdf, 0xdf, 0xdf, 0xfe, 0xde, 0x5b, 0xc3, 0x87, 0x3c, 0x0b, 0xbf,
+	0x7c, 0xcb, 0x62, 0x14, 0xf9, 0xef, 0x38, 0xe3, 0xa9, 0x4c, 0xc9, 0x07, 0x33, 0x8c, 0x27, 0x0c,
+	0xc7, 0xd7, 0x34, 0x8e, 0x51, 0x8e, 0xf5, 0x91, 0xff, 0x31, 0x74, 0x4f, 0x59, 0x8c, 0x01, 0xbe,
+	0x27, 0x03, 0x68, 0xb1, 0xc8, 0x73, 0x46, 0xce, 0x41, 0x3b, 0x68, 0xb1, 0xc8, 0xff, 0x0a, 0x36,
+	0xf3, 0x23, 0x91, 0xd5, 0xcf, 0xc8, 0x10, 0x36, 0xe3, 0x34, 0xa4, 0x92, 0xa5, 0x89, 0xd7, 0x1a,
+	0x39, 0x07, 0xbd, 0xa0, 0x94, 0xfd, 0x37, 0x30, 0x38, 0x62, 0x09, 0xe5, 0xf3, 0xd2, 0x9b, 0x40,
+	0x3b, 0xa2, 0x92, 0x6a, 0xff, 0x7e, 0xa0, 0xbf, 0x95, 0x4e, 0xb0, 0x5f, 0x50, 0x7b, 0xbb, 0x81,
+	0xfe, 0x26, 0x23, 0xd8, 0x0a, 0xd3, 0x44, 0x62, 0x22, 0x7f, 0x9c, 0x67, 0xe8, 0xb9, 0x3a, 0xb0,
+	0xad, 0xf2, 0x5f, 0xc3, 0xce, 0xb9, 0x40, 0xfe, 0x82, 0x0a, 0x15, 0x5c, 0xa8, 0xb2, 0x77, 0xc1,
+	0x9d, 0x9a, 0xda, 0x7a, 0x81, 0xfa, 0x24, 0x5f, 0xc0, 0x3d, 0xbc, 0x09, 0xe3, 0x69, 0x84, 0xc7,
+	0x54, 0xe2, 0x65, 0xca, 0x19, 0x0a, 0xaf, 0x35, 0x72, 0x0f, 0x7a, 0xc1, 0xf2, 0x81, 0x7f, 0x08,
+	0xbb, 0x8b, 0x21, 0x45, 0x46, 0x1e, 0x02, 0x68, 0x78, 0x4e, 0x6e, 0x98, 0x90, 0x3a, 0xf4, 0x66,
+	0x60, 0x69, 0xfc, 0x3f, 0x1c, 0xd8, 0x3e, 0xcf, 0xe2, 0x94, 0x46, 0x05, 0x78, 0xf7, 0x61, 0xe3,
+	0x62, 0x2e, 0x51, 0x98, 0x1e, 0x73, 0x41, 0xc1, 0xa4, 0xbc, 0xbe, 0xa7, 0x13, 0x2c, 0x60, 0x2a,
+	0xe4, 0xa2, 0x6e, 0xb7, 0xaa, 0xfb, 0x01, 0xf4, 0x68, 0x34, 0x61, 0xc9, 0xab, 0x24, 0x9e, 0x7b,
+	0x6d, 0x9d, 0xb4, 0x52, 0x10, 0x0f, 0xba, 0x19, 0x67, 0x33, 0x2a, 0xd1, 0xdb, 0xd0, 0x67, 0x85,
+	0xa8, 0xb2, 0x84, 0x79, 0x3f, 0x73, 0xaf, 0x93, 0x67, 0x29, 0x64, 0xff, 0x1b, 0x18, 0xd8, 0x85,
+	0xfe, 0xc7, 0xab, 0xbc, 0x82, 0x7b, 0x27, 0x9c, 0x0a, 0x54, 0x00, 0xfd, 0x5f, 0x80, 0x93, 0x3d,
+	0xe8, 0x44, 0x7c, 0x1e, 0x4c, 0x13, 0xdd, 0xfb, 0x66, 0x60, 0x24, 0xff, 0x57, 0x07, 0x40, 0x67,
+	0xd3, 0xb5, 0x36, 0xd5, 0xa9, 0xb0, 0x4b, 0x6a, 0x58, 0x2a, 0x79, 0x01, 0x01, 0x77, 0x11, 0x81,
+	0x92, 0x68, 0x6d, 0x8b, 0x68, 0x7b, 0xd0, 0xe1, 0x48, 0x45, 0x9a, 0x68, 0x28, 0x7b, 0x81, 0x91,
+	0xfc, 0xbf, 0x1c, 0x20, 0xf5, 0x86, 0x45, 0x66, 0x55, 0xec, 0xd8, 0x15, 0x93, 0xaf, 0xa1, 0xcb,
+	0x71, 0x92, 0xce, 0x30, 0xd2, 0xdd, 0x6e, 0x1d, 0x3e, 0x1a, 0x37, 0xcc, 0xd8, 0xb8, 0x6a, 0x2a,
+	0x28, 0xec, 0xc9, 0x53, 0x68, 0x5f, 0x61, 0x26, 0x3d, 0x77, 0x3d, 0x3f, 0x6d, 0xac, 0x28, 0x80,
+	0x37, 0x59, 0xca, 0xa5, 0x30, 0xdd, 0x14, 0x62, 0x3e, 0x39, 0x71, 0x8c, 0xa1, 0xba, 0x36, 0xa1,
+	0xbb, 0x72, 0x03, 0x5b, 0xe5, 0xbf, 0x00, 0x72, 0xa2, 0x8d, 0x6f, 0xb9, 0xcb, 0x11, 0x6c, 0x71,
+	0x7c, 0x3f, 0x45, 0x21, 0x31, 0x3a, 0x9a, 0x1b, 0xa4, 0x6d, 0x95, 0xbf, 0x0f, 0xa4, 0x8c, 0x91,
+	0x87, 0x6c, 0xda, 0x1e, 0xbf, 0x3b, 0xb0, 0x53, 0x33, 0x5b, 0xba, 0x52, 0x93, 0xbd, 0x55, 0x65,
+	0xdf, 0x83, 0x8e, 0x90, 0x54, 0x4e, 0x85, 0xb9, 0x46, 0x23, 0x35, 0x5e, 0x62, 0x31, 0xa4, 0xc7,
+	0xe9, 0x34, 0x91, 0xa6, 0x65, 0x4b, 0xa3, 0xc6, 0x09, 0x6f, 0x32, 0xc6, 0x51, 0x7c, 0x27, 0xcd,
+	0x5c, 0x54, 0x0a, 0xff, 0x37, 0x07, 0x76, 0x8f, 0xd3, 0xc9, 0x05, 0x4b, 0xf0, 0xe5, 0x84, 0x5e,
+	0xae, 0x82, 0xc3, 0x83, 0xae, 0x0a, 0xf9, 0x32, 0xca, 0x09, 0xdd, 0x0e, 0x0a, 0x71, 0x61, 0xb6,
+	0xdd, 0xda, 0x6c, 0xdb, 0x7c, 0x6c, 0xd7, 0xf8, 0xb8, 0x0f, 0xdb, 0x11, 0xc6, 0x28, 0xf1, 0x2c,
+	0x9d, 0xf2, 0x10, 0x85, 0x99, 0xe6, 0x45, 0xa5, 0x7f, 0x05, 0xbd, 0x67, 0xd3, 0x2c, 0x66, 0xca,
+	0x4d, 0xa1, 0x92, 0x67, 0x35, 0xd8, 0x19, 0xa9, 0x01, 0xbf, 0xfb, 0xb0, 0x31, 0xa1, 0x32, 0x7c,
+	0x67, 0x2a, 0xca, 0x05, 0x85, 0x94, 0x60, 0x13, 0x16, 0x53, 0xce, 0x64, 0x5e, 0x90, 0x13, 0x58,
+	0x1a, 0xff, 0x07, 0x18, 0x94, 0xc9, 0x72, 0xc6, 0x7f, 0x0b, 0x10, 0x95, 0x1a, 0xcf, 0xd1, 0x24,
+	0x7d, 0xd8, 0x48, 0xd2, 0xd2, 0x31, 0xb0, 0x3c, 0xfc, 0x23, 0xe8, 0x9f, 0xbd, 0xe3, 0x18, 0x29,
+	0x06, 0xdc, 0x95, 0x67, 0x7f, 0x3a, 0x00, 0x3a, 0xc8, 0xc9, 0x0c, 0x93, 0x75, 0xc8, 0x53, 0x0b,
+	0xe9, 0x2e, 0x85, 0xac, 0x51, 0xa6, 0xbd, 0x44, 0x99, 0x11, 0x6c, 0xbd, 0xa5, 0x2c, 0xc6, 0xc8,
+	0xe6, 0x94, 0xad, 0x52, 0xa4, 0x0a, 0x39, 0x52, 0x89, 0x51, 0x45, 0xaa, 0x52, 0x71, 0xf8, 0x77,
+	0x17, 0xfa, 0x67, 0xc8, 0x67, 0x2c, 0x44, 0xcd, 0x7b, 0x72, 0x0a, 0xdd, 0xe7, 0x28, 0xd5, 0x37,
+	0x79, 0xd0, 0x08, 0x9f, 0x79, 0x3f, 0x86, 0x9f, 0xfc, 0xcb, 0xa9, 0xc8, 0xc8, 0x6b, 0xe8, 0x3f,
+	0x4b, 0xaf, 0x93, 0x62, 0x91, 0xdf, 0x12, 0xec, 0x71, 0xe3, 0x69, 0xed, 0x51, 0xfe, 0x09, 0xfa,
+	0xf6, 0xbb, 0x47, 0xf6, 0x1b, 0x9d, 0x6a, 0xaf, 0xed, 0xf0, 0xb3, 0x35, 0xac, 0x44, 0x46, 0xce,
+	0x01, 0xaa, 0x67, 0x87, 0xf8, 0xcd, 0x4e, 0xf6, 0x03, 0x3a, 0x7c, 0x7c, 0xab, 0x8d, 0xc8, 0x08,
+	0x85, 0xc1, 0xe2, 0x7a, 0x26, 0x9f, 0xaf, 0xde, 0x9c, 0xf6, 0xa2, 0x1b, 0x3e, 0x59, 0xcb, 0x4e,
+	0x64, 0xe4, 0x67, 0xd8, 0xa9, 0xed, 0x49, 0xb2, 0xc2, 0x77, 0x69, 0x9b, 0x0e, 0x57, 0x43, 0x68,
+	0x6f, 0xc1, 0x10, 0xc8, 0x73, 0x94, 0x75, 0xed, 0x93, 0x75, 0x7c, 0xd7, 0x4f, 0x72, 0x09, 0x1f,
+	0x15, 0x84, 0xb9, 0x73, 0xa6, 0xb5, 0x68, 0x74, 0x0e, 0xdb, 0x0b, 0x6b, 0x94, 0x34, 0x33, 0xa4,
+	0xbe, 0x6a, 0x6f, 0x23, 0xfc, 0x19, 0x0c, 0x4e, 0x59, 0x12, 0x55, 0x6b, 0xe9, 0x4e, 0x94, 0xaf,
+	0x6d, 0xb5, 0x57, 0xd0, 0x2b, 0xb7, 0x12, 0xf9, 0xb4, 0xd1, 0xc3, 0xde, 0x5a, 0xc3, 0x47, 0xab,
+	0x4d, 0xf4, 0x4e, 0x3a, 0xea, 0xbe, 0xd9, 0xd0, 0xba, 0x8b, 0x8e, 0xfe, 0x8b, 0xfd, 0xf4, 0x9f,
+	0x01, 0x00, 0x27, 0x52, 0xd9, 0x9d, 0x7b, 0x0b, 0x00, 0x00,
 }
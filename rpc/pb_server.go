@@ -4,11 +4,14 @@ import (
 	"github.com/Confialink/wallet-files/internal/service"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/Confialink/wallet-files/internal/config"
 	"github.com/Confialink/wallet-files/internal/database"
 	pb "github.com/Confialink/wallet-files/rpc/files"
+	"github.com/twitchtv/twirp"
 )
 
 type PbServerInterface interface {
@@ -17,13 +20,25 @@ type PbServerInterface interface {
 }
 
 type pbServer struct {
-	repo    *database.Repository
-	config  *config.Config
-	storage *service.StorageService
+	repo      *database.Repository
+	config    *config.Config
+	storage   *service.StorageService
+	erasure   *service.ErasureService
+	export    *service.ExportService
+	combine   *service.CombineService
+	duplicate *service.DuplicateService
 }
 
-func NewPbServer(repo *database.Repository, config *config.Config, storage *service.StorageService) *pbServer {
-	return &pbServer{repo, config, storage}
+func NewPbServer(
+	repo *database.Repository,
+	config *config.Config,
+	storage *service.StorageService,
+	erasure *service.ErasureService,
+	export *service.ExportService,
+	combine *service.CombineService,
+	duplicate *service.DuplicateService,
+) *pbServer {
+	return &pbServer{repo, config, storage, erasure, export, combine, duplicate}
 }
 
 func (s *pbServer) Start() {
@@ -61,7 +76,7 @@ func (s *pbServer) UploadFile(ctx context.Context, req *pb.UploadFileReq) (resp
 	if req.Category != "" {
 		cat = &req.Category
 	}
-	_, err = s.storage.UploadBytes(req.Bytes, req.FileName, req.Uid, req.AdminOnly, req.Private, cat)
+	_, err = s.storage.UploadBytes(req.Bytes, req.FileName, req.Uid, req.AdminOnly, req.Private, cat, service.UploaderRoleService)
 	if err != nil {
 		return
 	}
@@ -73,9 +88,189 @@ func (s *pbServer) DownloadFile(_ context.Context, req *pb.FileReq) (resp *pb.Bi
 	if err != nil {
 		return nil, err
 	}
+	if file.IsQuarantined() {
+		return nil, twirp.NewError(twirp.FailedPrecondition, "file is quarantined")
+	}
+	if s.storage.Unscanned(file) {
+		return nil, twirp.NewError(twirp.FailedPrecondition, "file is not scanned yet")
+	}
+	b, err := s.storage.Download(file)
+	if err == service.ErrKeyShredded {
+		return nil, twirp.NewError(twirp.NotFound, "key of the file owner is shredded")
+	}
+	if err != nil {
+		return nil, twirp.InternalErrorWith(err)
+	}
 	return &pb.BinaryFileResp{
-		Data:        s.storage.Download(file),
+		Data:        b,
 		Size:        file.Size,
 		ContentType: file.ContentType,
 	}, nil
 }
+
+func (s *pbServer) EraseUserFiles(_ context.Context, req *pb.EraseUserFilesReq) (*pb.EraseUserFilesResp, error) {
+	report, err := s.erasure.EraseUserFiles(req.Uid, req.ExcludeCategories, req.DryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.EraseUserFilesResp{
+		DryRun:      report.DryRun,
+		Removed:     erasedFilesToPb(report.Removed),
+		Kept:        erasedFilesToPb(report.Kept),
+		Exports:     int64(report.Exports),
+		Collections: int64(report.Collections),
+	}, nil
+}
+
+func erasedFilesToPb(items []*service.ErasureItem) []*pb.ErasedFile {
+	result := make([]*pb.ErasedFile, len(items))
+	for i, item := range items {
+		result[i] = &pb.ErasedFile{
+			Id:       item.ID,
+			Filename: item.Filename,
+			Size:     item.Size,
+			Reason:   item.Reason,
+		}
+		if item.Category != nil {
+			result[i].Category = *item.Category
+		}
+	}
+	return result
+}
+
+func (s *pbServer) ExportUserFiles(_ context.Context, req *pb.ExportUserFilesReq) (*pb.UserFilesExport, error) {
+	export, err := s.export.CreateExport(req.Uid, req.RequestedBy, "")
+	if err != nil {
+		return nil, err
+	}
+	return exportToPb(export), nil
+}
+
+func (s *pbServer) GetUserFilesExport(_ context.Context, req *pb.UserFilesExportReq) (*pb.UserFilesExport, error) {
+	export, err := s.export.FindExport(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return exportToPb(export), nil
+}
+
+// DownloadUserFilesExport returns the archive of a finished export, the whole archive is sent in the response
+func (s *pbServer) DownloadUserFilesExport(_ context.Context, req *pb.UserFilesExportReq) (*pb.BinaryFileResp, error) {
+	export, err := s.export.FindExport(req.Id)
+	if err != nil {
+		return nil, twirp.NotFoundError(fmt.Sprintf("export %d is not found", req.Id))
+	}
+
+	content, err := s.export.OpenArchive(export)
+	if err != nil {
+		return nil, twirp.NewError(twirp.FailedPrecondition, err.Error())
+	}
+	defer content.Close()
+
+	b, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil, twirp.InternalErrorWith(err)
+	}
+	return &pb.BinaryFileResp{
+		Data:        b,
+		Size:        int64(len(b)),
+		ContentType: "application/zip",
+	}, nil
+}
+
+// CombineImages combines images into a pdf document, the caller is responsible for permission checks
+func (s *pbServer) CombineImages(_ context.Context, req *pb.CombineImagesReq) (*pb.FileResp, error) {
+	if len(req.FileIds) == 0 || len(req.FileIds) > service.MaxCombinedImages {
+		return nil, twirp.InvalidArgumentError("fileIds", fmt.Sprintf("must contain from 1 to %d ids", service.MaxCombinedImages))
+	}
+
+	found, err := s.repo.FindByIDs(req.FileIds)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint64]*database.FileModel, len(found))
+	for _, file := range found {
+		byID[file.ID] = file
+	}
+
+	files := make([]*database.FileModel, 0, len(req.FileIds))
+	for _, id := range req.FileIds {
+		file, ok := byID[id]
+		if !ok {
+			return nil, twirp.NotFoundError(fmt.Sprintf("file %d is not found", id))
+		}
+		files = append(files, file)
+	}
+
+	var category *string
+	if req.Category != "" {
+		category = &req.Category
+	}
+	document, tErr := s.combine.Combine(req.Uid, files, req.FileName, category, req.DeleteSources, service.UploaderRoleService)
+	if tErr != nil {
+		return nil, tErr
+	}
+	return &pb.FileResp{Id: document.ID}, nil
+}
+
+func (s *pbServer) FindDuplicates(_ context.Context, req *pb.FileReq) (*pb.DuplicatesResp, error) {
+	file, err := s.repo.FindByID(req.Id)
+	if err != nil {
+		return nil, twirp.NotFoundError(fmt.Sprintf("file %d is not found", req.Id))
+	}
+
+	duplicates, err := s.duplicate.Find(file)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.DuplicatesResp{Duplicates: make([]*pb.Duplicate, len(duplicates))}
+	for i, duplicate := range duplicates {
+		resp.Duplicates[i] = &pb.Duplicate{
+			FileId:     duplicate.FileId,
+			Uid:        duplicate.UserId,
+			Match:      duplicate.Match,
+			Similarity: duplicate.Similarity,
+		}
+	}
+	return resp, nil
+}
+
+// ShredUser fails with FailedPrecondition if the user has files under a legal hold or retention
+func (s *pbServer) ShredUser(_ context.Context, req *pb.ShredUserReq) (*pb.ShredEvent, error) {
+	if req.Uid == "" {
+		return nil, twirp.RequiredArgumentError("uid")
+	}
+
+	event, err := s.erasure.ShredUser(req.Uid, req.RequestedBy)
+	if err == service.ErrShredProtected {
+		return nil, twirp.NewError(twirp.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ShredEvent{
+		Id:          event.ID,
+		Uid:         event.UserId,
+		RequestedBy: event.RequestedBy,
+		FilesCount:  int64(event.FilesCount),
+		FailedCount: int64(event.FailedCount),
+		CreatedAt:   event.CreatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+func exportToPb(export *database.ExportModel) *pb.UserFilesExport {
+	result := &pb.UserFilesExport{
+		Id:         export.ID,
+		Uid:        export.UserId,
+		Status:     export.Status,
+		Size:       export.Size,
+		FilesCount: int64(export.FilesCount),
+	}
+	if export.ExpiresAt != nil {
+		result.ExpiresAt = export.ExpiresAt.Format(time.RFC3339)
+	}
+	return result
+}